@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"kube-proxless/internal/cluster/kube"
+	"kube-proxless/internal/config"
+	"os"
+)
+
+// e.g. in an init container or a CI step - the errors are printed and the exit code is 1
+func validateConfig(opts *options) int {
+	if _, err := config.Load(opts.configFile, opts.flags); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Println("config is valid")
+
+	return 0
+}
+
+// detect the misconfigured roles at deploy time instead of in the logs of the engines
+func checkCluster(opts *options) int {
+	cfg, err := config.Load(opts.configFile, opts.flags)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	errs := kube.CheckPermissions(
+		kube.NewKubeClient(cfg.KubeConfigPath),
+		cfg.NamespaceScope,
+		cfg.ProxlessRoutes,
+		cfg.Discovery,
+		cfg.NamespaceOptIn)
+
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	if len(errs) > 0 {
+		return 1
+	}
+
+	fmt.Println("all the permissions are granted")
+
+	return 0
+}
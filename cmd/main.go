@@ -1,35 +1,94 @@
 package main
 
 import (
-	"kube-proxless/internal/cluster/kube"
+	"flag"
+	"fmt"
 	"kube-proxless/internal/config"
-	ctrl "kube-proxless/internal/controller"
 	"kube-proxless/internal/logger"
-	"kube-proxless/internal/memory"
-	"kube-proxless/internal/pubsub"
-	"kube-proxless/internal/pubsub/redis"
-	"kube-proxless/internal/server/http"
+	"os"
+	"strings"
 )
 
+// set at build time with `-ldflags "-X main.version=..."`
+var version = "dev"
+
+const usage = `Usage: proxless [command] [flags]
+
+Commands:
+  serve            run proxless (default)
+  validate-config  validate the config file, the env vars and the flags
+  check-cluster    check the RBAC permissions of proxless in its namespace scope
+  version          print the version of proxless
+
+Run 'proxless [command] --help' to list the flags - they mirror the keys of the config file,
+e.g. '--serverless-ttl-seconds' for 'serverlessTTLSeconds', and have priority over the env vars.
+`
+
+type options struct {
+	configFile string
+	flags      config.Flags
+	version    bool
+}
+
 func main() {
 	logger.InitLogger()
 
-	config.LoadEnvVars()
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	commands := map[string]func(opts *options) int{
+		"serve":           serve,
+		"validate-config": validateConfig,
+		"check-cluster":   checkCluster,
+		"version":         printVersion,
+	}
+
+	cmd, ok := commands[command]
 
-	memoryMap := memory.NewMemoryMap()
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		return 2
+	}
 
-	c := kube.NewCluster(kube.NewKubeClient(config.KubeConfigPath), config.ServicesInformerResyncIntervalSeconds)
+	opts, err := parseFlags(command, args)
+
+	if err == flag.ErrHelp {
+		return 0
+	} else if err != nil {
+		return 2
+	}
 
-	var ps pubsub.Interface
-	if config.RedisURL != "" {
-		ps = redis.NewRedisPubSub(config.RedisURL)
+	if opts.version {
+		return printVersion(opts)
 	}
 
-	controller := ctrl.NewController(memoryMap, c, ps)
+	return cmd(opts)
+}
+
+func parseFlags(command string, args []string) (*options, error) {
+	opts := &options{}
 
-	go controller.RunDownScaler(config.ScaleDownCheckIntervalSeconds)
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.StringVar(&opts.configFile, "config", os.Getenv("CONFIG_FILE"), "path of the config file - env var CONFIG_FILE")
+	fs.BoolVar(&opts.version, "version", false, "print the version of proxless")
+	opts.flags = config.RegisterFlags(fs)
 
-	go controller.RunServicesEngine()
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fmt.Fprintf(fs.Output(), "\nFlags of %s:\n", command)
+		fs.PrintDefaults()
+	}
+
+	return opts, fs.Parse(args)
+}
 
-	http.NewHTTPServer(controller).Run()
+func printVersion(opts *options) int {
+	fmt.Println(version)
+	return 0
 }
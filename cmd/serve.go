@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"k8s.io/client-go/dynamic"
+	"kube-proxless/internal/cluster/kube"
+	"kube-proxless/internal/config"
+	ctrl "kube-proxless/internal/controller"
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/memory"
+	"kube-proxless/internal/pubsub"
+	"kube-proxless/internal/pubsub/redis"
+	"kube-proxless/internal/server/admin"
+	"kube-proxless/internal/server/http"
+	"kube-proxless/internal/server/webhook"
+	"kube-proxless/internal/store"
+	redisstore "kube-proxless/internal/store/redis"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// run proxless - the default command
+func serve(opts *options) int {
+	cfg, err := config.Load(opts.configFile, opts.flags)
+
+	if err != nil {
+		logger.Errorf(err, "Could not load the config")
+		return 1
+	}
+
+	logger.SetLevel(cfg.LogLevel)
+
+	configProvider := config.NewProvider(cfg)
+
+	if opts.configFile != "" {
+		go configProvider.RunWatcher(opts.configFile, opts.flags, cfg.ConfigWatchIntervalSeconds)
+	}
+
+	memoryMap := memory.NewMemoryMap(configProvider)
+
+	var dynamicClient dynamic.Interface
+	if cfg.ProxlessRoutes || cfg.Discovery {
+		dynamicClient = kube.NewDynamicClient(cfg.KubeConfigPath)
+	}
+
+	c := kube.NewCluster(
+		kube.NewKubeClient(cfg.KubeConfigPath),
+		dynamicClient,
+		cfg.ServicesInformerResyncIntervalSeconds,
+		cfg.DeploymentReadinessPollIntervalSeconds,
+		cfg.ProxyToEndpoints,
+		cfg.NamespaceOptIn,
+		cfg.DryRun)
+
+	var ps pubsub.Interface
+	if cfg.RedisURL != "" {
+		ps = redis.NewRedisPubSub(cfg.RedisURL)
+	}
+
+	var st store.Interface
+	if cfg.RedisURL != "" && cfg.RedisStateStore {
+		st = redisstore.NewRedisStore(cfg.RedisURL, cfg.StateStoreTTLSeconds)
+	}
+
+	controller := ctrl.NewController(memoryMap, c, ps, st, configProvider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the store and the pubsub are closed once the runners have stopped
+	var runners sync.WaitGroup
+	goRunner := func(run func()) {
+		runners.Add(1)
+		go func() {
+			defer runners.Done()
+			run()
+		}()
+	}
+
+	goRunner(func() { controller.RunDownScaler(ctx, cfg.ScaleDownCheckIntervalSeconds) })
+
+	if st != nil {
+		goRunner(func() { controller.RunStateReconciler(ctx, cfg.StateStoreReconcileIntervalSeconds) })
+	}
+
+	if cfg.PersistLastUsed {
+		goRunner(func() {
+			controller.RunLastUsedPersister(ctx, cfg.PersistLastUsedIntervalSeconds, cfg.PersistLastUsedThresholdSeconds)
+		})
+	}
+
+	go controller.RunServicesEngine(ctx)
+
+	goRunner(func() { controller.RunProxlessServicesCollector(ctx, cfg.ProxlessServicesGCIntervalSeconds) })
+
+	if cfg.ProxlessRoutes {
+		go controller.RunProxlessRoutesEngine(ctx)
+	}
+
+	if cfg.Discovery {
+		go controller.RunDiscoveryEngine(ctx)
+	}
+
+	goRunner(func() { controller.RunSavingsCollector(ctx, cfg.SavingsCollectIntervalSeconds) })
+
+	if cfg.PreWarm {
+		goRunner(func() { controller.RunPreWarmer(ctx, cfg.PreWarmCheckIntervalSeconds) })
+	}
+
+	go admin.NewAdminServer(controller, configProvider).Run()
+
+	if cfg.WebhookEnabled {
+		go webhook.NewWebhookServer(controller, configProvider).Run()
+	}
+
+	httpServer := http.NewHTTPServer(controller, configProvider)
+	go httpServer.Run()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	logger.Infof("Received %s - shutting down...", <-sigCh)
+
+	// the readiness fails first so the replica is removed from the endpoints while it still serves the requests
+	controller.Drain()
+	time.Sleep(time.Duration(cfg.ShutdownDelaySeconds) * time.Second)
+
+	exitCode := 0
+	if err := httpServer.Shutdown(cfg.ShutdownGracePeriodSeconds); err != nil {
+		logger.Errorf(err, "Could not drain the in-flight requests")
+		exitCode = 1
+	}
+
+	// after the drain - the requests waiting for a scale up rely on the endpoint slices informer
+	// the runners stop at the end of their current iteration
+	cancel()
+	runners.Wait()
+
+	if ps != nil {
+		ps.Close()
+	}
+
+	if st != nil {
+		st.Close()
+	}
+
+	logger.Infof("Proxless stopped")
+
+	return exitCode
+}
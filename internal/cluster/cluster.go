@@ -1,14 +1,62 @@
 package cluster
 
+import (
+	"context"
+	"kube-proxless/internal/model"
+	"time"
+)
+
+// a write on the cluster skipped in dry-run mode
+type DryRunActionReport struct {
+	Resource string `json:"resource"` // e.g. `deployments`
+	Action   string `json:"action"`   // e.g. `patch`
+	Count    int    `json:"count"`
+}
+
 type Interface interface {
-	ScaleUpDeployment(name, namespace string, timeout int) error
+	// the events are recorded on the deployment and on the service `serviceName`
+	ScaleUpDeployment(deploymentName, serviceName, namespace string, timeout int) error
+
+	ScaleDownDeployment(deploymentName, serviceName, namespace string, lastUsed time.Time) error
+
+	PersistLastUsed(deploymentName, namespace string, lastUsed time.Time) error
+
+	DeploymentExists(name, namespace string) (bool, error)
+
+	// cpu (cores) and memory (GB) requested by the deployment once scaled up
+	GetDeploymentResourceRequests(name, namespace string) (cpu, memory float64, err error)
+
+	DeleteOrphanProxlessServices(namespaceScope string) []error
 
-	ScaleDownDeployment(deploymentName, namespace string) error
+	// empty if dry-run is disabled
+	GetDryRunActions() []DryRunActionReport
 
+	// the engines stop when `ctx` is done
+	// `onSynced` is called once the routes of the cluster are in memory
 	RunServicesEngine(
+		ctx context.Context,
+		namespaceScope, proxlessService, proxlessNamespace string,
+		upsertMemory func(route *model.Route) error,
+		deleteRouteFromMemory func(id string) error,
+		updateReplicasInMemory func(deployName, namespace string, replicas, availableReplicas int) error,
+		updateEndpointsInMemory func(id string, endpoints []string) error,
+		onSynced func(),
+	)
+
+	RunProxlessRoutesEngine(
+		ctx context.Context,
+		namespaceScope, proxlessService, proxlessNamespace string,
+		upsertMemory func(route *model.Route) error,
+		deleteRouteFromMemory func(id string) error,
+		getRouteFromMemory func(id string) (*model.Route, error),
+		onSynced func(),
+	)
+
+	RunDiscoveryEngine(
+		ctx context.Context,
 		namespaceScope, proxlessService, proxlessNamespace string,
-		upsertMemory func(
-			id, name, port, deployName, namespace string, domains []string, isRunning bool, ttlSeconds, readinessTimeoutSeconds *int) error,
+		upsertMemory func(route *model.Route) error,
 		deleteRouteFromMemory func(id string) error,
+		onSynced func(),
 	)
 }
@@ -1,10 +1,13 @@
 package fake
 
 import (
+	"context"
 	"errors"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"kube-proxless/internal/cluster"
 	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+	"time"
 )
 
 const (
@@ -24,27 +27,128 @@ func NewCluster() cluster.Interface {
 	return &fakeCluster{}
 }
 
-func (*fakeCluster) ScaleUpDeployment(name, namespace string, timeout int) error {
-	if name != deployName || namespace != namespaceName {
+func (*fakeCluster) ScaleUpDeployment(deploymentName, serviceName, namespace string, timeout int) error {
+	if deploymentName != deployName || namespace != namespaceName {
 		return errors.New("error scaling up deployment")
 	}
 	return nil
 }
 
-func (*fakeCluster) ScaleDownDeployment(deploymentName, namespace string) error {
+func (*fakeCluster) ScaleDownDeployment(deploymentName, serviceName, namespace string, lastUsed time.Time) error {
+	return nil
+}
+
+func (*fakeCluster) PersistLastUsed(deploymentName, namespace string, lastUsed time.Time) error {
+	if deploymentName != deployName || namespace != namespaceName {
+		return errors.New("error persisting lastUsed")
+	}
+	return nil
+}
+
+func (*fakeCluster) DeploymentExists(name, namespace string) (bool, error) {
+	return name == deployName && namespace == namespaceName, nil
+}
+
+func (*fakeCluster) GetDeploymentResourceRequests(name, namespace string) (float64, float64, error) {
+	if name != deployName || namespace != namespaceName {
+		return 0, 0, errors.New("error getting the resource requests")
+	}
+	return 0.5, 2, nil
+}
+
+func (*fakeCluster) DeleteOrphanProxlessServices(namespaceScope string) []error {
+	if namespaceScope != namespaceName {
+		return []error{errors.New("error deleting orphan proxless services")}
+	}
 	return nil
 }
 
+func (*fakeCluster) GetDryRunActions() []cluster.DryRunActionReport {
+	return []cluster.DryRunActionReport{}
+}
+
 func (*fakeCluster) RunServicesEngine(
+	ctx context.Context,
 	namespaceScope, proxlessService, proxlessNamespace string,
-	upsertMemory func(
-		id, name, port, deployName, namespace string, domains []string, isRunning bool, ttlSeconds, readinessTimeoutSeconds *int) error,
+	upsertMemory func(route *model.Route) error,
 	deleteRouteFromMemory func(id string) error,
+	updateReplicasInMemory func(deployName, namespace string, replicas, availableReplicas int) error,
+	updateEndpointsInMemory func(id string, endpoints []string) error,
+	onSynced func(),
 ) {
+	defer onSynced()
+
 	if namespaceScope == "upsert" { // TODO this is too hacky, see how others are doing
-		err := upsertMemory(
+		route, err := model.NewRoute(
+			serviceId, serviceName, "", deployName, namespaceName, domains, true, nil, nil)
+
+		if err == nil {
+			err = upsertMemory(route)
+		}
+
+		if err != nil {
+			logger.Errorf(err, "Error upserting in fake package")
+		}
+	} else {
+		err := deleteRouteFromMemory(serviceId)
+
+		if err != nil {
+			logger.Errorf(err, "Error deleting route in fake package")
+		}
+	}
+}
+
+func (*fakeCluster) RunProxlessRoutesEngine(
+	ctx context.Context,
+	namespaceScope, proxlessService, proxlessNamespace string,
+	upsertMemory func(route *model.Route) error,
+	deleteRouteFromMemory func(id string) error,
+	getRouteFromMemory func(id string) (*model.Route, error),
+	onSynced func(),
+) {
+	defer onSynced()
+
+	if namespaceScope == "upsert" {
+		route, err := model.NewRoute(
 			serviceId, serviceName, "", deployName, namespaceName, domains, true, nil, nil)
 
+		if err == nil {
+			err = upsertMemory(route)
+		}
+
+		if err == nil {
+			_, err = getRouteFromMemory(serviceId)
+		}
+
+		if err != nil {
+			logger.Errorf(err, "Error upserting in fake package")
+		}
+	} else {
+		err := deleteRouteFromMemory(serviceId)
+
+		if err != nil {
+			logger.Errorf(err, "Error deleting route in fake package")
+		}
+	}
+}
+
+func (*fakeCluster) RunDiscoveryEngine(
+	ctx context.Context,
+	namespaceScope, proxlessService, proxlessNamespace string,
+	upsertMemory func(route *model.Route) error,
+	deleteRouteFromMemory func(id string) error,
+	onSynced func(),
+) {
+	defer onSynced()
+
+	if namespaceScope == "upsert" {
+		route, err := model.NewRoute(
+			serviceId, serviceName, "", deployName, namespaceName, domains, true, nil, nil)
+
+		if err == nil {
+			err = upsertMemory(route)
+		}
+
 		if err != nil {
 			logger.Errorf(err, "Error upserting in fake package")
 		}
@@ -3,12 +3,21 @@ package kube
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	clusterutils "kube-proxless/internal/cluster/utils"
 	"kube-proxless/internal/logger"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -22,6 +31,100 @@ func getDeployment(clientSet kubernetes.Interface, name, namespace string) (*app
 	return clientSet.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 }
 
+func deploymentExists(clientSet kubernetes.Interface, name, namespace string) (bool, error) {
+	_, err := getDeployment(clientSet, name, namespace)
+
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// return the names of the deployments whose pod template matches the selector of the service
+func getDeploymentsForService(clientSet kubernetes.Interface, svc *corev1.Service) ([]string, error) {
+	if len(svc.Spec.Selector) == 0 {
+		return nil, nil
+	}
+
+	deploys, err := clientSet.AppsV1().Deployments(svc.Namespace).List(context.TODO(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	selector := labels.SelectorFromSet(svc.Spec.Selector)
+	var names []string
+
+	for _, deploy := range deploys.Items {
+		if selector.Matches(labels.Set(deploy.Spec.Template.Labels)) {
+			names = append(names, deploy.Name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// return the deployments of `deployName` if set, otherwise the deployment matching the selector of the service
+// all the matching deployments are returned if `wakeAll` is true
+func resolveServiceDeployment(
+	clientSet kubernetes.Interface, svc *corev1.Service, deployName string, wakeAll bool) ([]string, error) {
+	if deployNames := clusterutils.SplitDeployments(deployName); len(deployNames) > 0 {
+		return deployNames, nil
+	}
+
+	deployNames, err := getDeploymentsForService(clientSet, svc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deployNames) == 0 {
+		return nil, errors.New(fmt.Sprintf("no deployment matches the selector of service %s - set %s",
+			svc.Name, clusterutils.AnnotationServiceDeployKey))
+	}
+
+	if len(deployNames) > 1 && !wakeAll {
+		return nil, errors.New(fmt.Sprintf("the selector of service %s matches multiple deployments (%s) - set %s or %s",
+			svc.Name, strings.Join(deployNames, ","), clusterutils.AnnotationServiceDeployKey,
+			clusterutils.AnnotationServiceWakeAllDeployments))
+	}
+
+	if len(deployNames) > 1 {
+		logger.Warnf(nil, "The selector of service %s.%s matches multiple deployments (%s) - they are woken up together",
+			svc.Name, svc.Namespace, strings.Join(deployNames, ","))
+	}
+
+	return deployNames, nil
+}
+
+// run `f` concurrently for each deployment of the route and return the first error
+func forEachDeployment(deployName string, f func(name string) error) error {
+	deployNames := clusterutils.SplitDeployments(deployName)
+
+	if len(deployNames) == 1 {
+		return f(deployName)
+	}
+
+	errCh := make(chan error, len(deployNames))
+	for _, name := range deployNames {
+		go func(name string) {
+			errCh <- f(name)
+		}(name)
+	}
+
+	var firstErr error
+	for range deployNames {
+		if err := <-errCh; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
 func patchDeploymentReplicas(
 	clientSet kubernetes.Interface, name, namespace string, replicas int) (*appsv1.Deployment, error) {
 
@@ -39,40 +142,148 @@ func patchDeploymentReplicas(
 		context.TODO(), name, k8stypes.JSONPatchType, payloadBytes, metav1.PatchOptions{})
 }
 
-func scaleUpDeployment(clientSet kubernetes.Interface, name, namespace string, timeout int) error {
-	_, err := patchDeploymentReplicas(clientSet, name, namespace, 1)
+type patchAnnotations struct {
+	Metadata patchAnnotationsMetadata `json:"metadata"`
+}
+
+type patchAnnotationsMetadata struct {
+	Annotations map[string]string `json:"annotations"`
+}
+
+func patchDeploymentAnnotations(
+	clientSet kubernetes.Interface, name, namespace string, annotations map[string]string) (*appsv1.Deployment, error) {
+
+	payloadBytes, err := json.Marshal(patchAnnotations{
+		Metadata: patchAnnotationsMetadata{Annotations: annotations},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return clientSet.AppsV1().Deployments(namespace).Patch(
+		context.TODO(), name, k8stypes.MergePatchType, payloadBytes, metav1.PatchOptions{})
+}
+
+func scaleUpDeployment(
+	clientSet kubernetes.Interface, watcher *endpointsWatcher, recorder record.EventRecorder,
+	name, serviceName, namespace string, timeout, pollInterval int) error {
+	now := time.Now()
+	replicas, err := resumeHPA(clientSet, name, namespace)
+
+	if err != nil {
+		logger.Errorf(err, "Could not resume the HPA of deployment %s.%s", name, namespace)
+		// do not return here - scaling up the deployment is more important
+	}
+
+	deploy, err := patchDeploymentReplicas(clientSet, name, namespace, replicas)
 
 	if err != nil {
 		logger.Errorf(err, "Could not scale up the deployment %s.%s", name, namespace)
 		return err
 	}
 
-	return waitForDeploymentAvailable(clientSet, name, namespace, timeout)
+	err = waitForDeploymentAvailable(clientSet, watcher, name, serviceName, namespace, timeout, pollInterval)
+
+	if err == wait.ErrWaitTimeout {
+		recordScaleEventf(clientSet, recorder, deploy, serviceName, corev1.EventTypeWarning, eventReasonWakeTimeout,
+			"Deployment %s not available after %d seconds", name, timeout)
+	} else if err == nil {
+		recordScaleEventf(clientSet, recorder, deploy, serviceName, corev1.EventTypeNormal, eventReasonScaledUp,
+			"Deployment %s scaled up to %d replicas - cold start %s", name, replicas, time.Since(now).Round(time.Millisecond))
+	}
+
+	return err
 }
 
-func waitForDeploymentAvailable(clientSet kubernetes.Interface, name, namespace string, timeout int) error {
+// the endpoint slices informer releases the request as soon as the service has a ready endpoint
+// the deployment can be available before its pods are added to the endpoints of the service
+// polling is only a fallback in case the informer is not running or missed the event
+func waitForDeploymentAvailable(
+	clientSet kubernetes.Interface, watcher *endpointsWatcher,
+	name, serviceName, namespace string, timeout, pollInterval int) error {
 	now := time.Now()
-	intervalSeconds := 5 * time.Second // TODO make this configurable
-	err := wait.PollImmediate(intervalSeconds, time.Duration(timeout)*time.Second, func() (bool, error) {
-		if deploy, err := getDeployment(clientSet, name, namespace); err != nil {
+
+	id := clusterutils.GenRouteId(serviceName, namespace)
+	ready := watcher.register(id)
+	defer watcher.unregister(id, ready)
+
+	ticker := time.NewTicker(time.Duration(pollInterval) * time.Second)
+	defer ticker.Stop()
+
+	timeoutCh := time.After(time.Duration(timeout) * time.Second)
+
+	for {
+		if isReady, err := isServiceReady(clientSet, name, serviceName, namespace); err != nil {
 			logger.Errorf(err, "Could not get the deployment %s.%s", name, namespace)
-			return true, err
+			return err
+		} else if isReady {
+			logger.Debugf("Deployment %s.%s scaled up successfully after %s", name, namespace, time.Now().Sub(now))
+			return nil
 		} else {
-			if deploy.Status.AvailableReplicas >= 1 { // TODO make this configurable
-				logger.Debugf("Deployment %s.%s scaled up successfully after %s",
-					name, namespace, time.Now().Sub(now))
-				return true, nil
-			} else {
-				logger.Debugf("Deployment %s.%s not ready yet", name, namespace)
-			}
-			return false, nil
+			logger.Debugf("Deployment %s.%s not ready yet", name, namespace)
 		}
-	})
-	return err
+
+		select {
+		case <-ready:
+			logger.Debugf("Deployment %s.%s scaled up successfully after %s", name, namespace, time.Now().Sub(now))
+			return nil
+		case <-ticker.C:
+		case <-timeoutCh:
+			return wait.ErrWaitTimeout
+		}
+	}
 }
 
-func scaleDownDeployment(kubeClient kubernetes.Interface, deploymentName, namespace string) error {
-	_, err := patchDeploymentReplicas(kubeClient, deploymentName, namespace, 0)
+// the endpoint slices of the service are the source of truth
+// the deployment is only checked if the service has none - e.g. no selector or endpoint slices not served
+func isServiceReady(clientSet kubernetes.Interface, deployName, serviceName, namespace string) (bool, error) {
+	if serviceName != "" {
+		endpointSlices, err := listServiceEndpointSlices(clientSet, serviceName, namespace)
+
+		if err != nil {
+			logger.Warnf(err, "Could not list the endpoint slices of service %s.%s - checking the deployment %s",
+				serviceName, namespace, deployName)
+		} else if len(endpointSlices) > 0 {
+			return len(getReadyEndpoints(endpointSlices)) > 0, nil
+		}
+	}
+
+	deploy, err := getDeployment(clientSet, deployName, namespace)
+
+	if err != nil {
+		return false, err
+	}
+
+	return isDeploymentAvailable(deploy), nil
+}
+
+func isDeploymentAvailable(deploy *appsv1.Deployment) bool {
+	return deploy.Status.AvailableReplicas >= 1 // TODO make this configurable
+}
+
+func scaleDownDeployment(
+	kubeClient kubernetes.Interface, recorder record.EventRecorder,
+	deploymentName, serviceName, namespace string, lastUsed time.Time) error {
+	deploy, err := getDeployment(kubeClient, deploymentName, namespace)
+
+	if err != nil {
+		logger.Errorf(err, "Could not get the deployment %s.%s", deploymentName, namespace)
+		return err
+	}
+
+	// e.g. scaled down by another replica of proxless or by hand - nothing to patch and no event
+	if !isDeploymentRunning(deploy) {
+		logger.Debugf("Deployment %s.%s already scaled down", deploymentName, namespace)
+		return nil
+	}
+
+	if err := pauseHPA(kubeClient, deploymentName, namespace); err != nil {
+		logger.Errorf(err, "Could not pause the HPA of deployment %s.%s", deploymentName, namespace)
+		// do not return here - the HPA is disabled anyway while the deployment has 0 replicas
+	}
+
+	deploy, err = patchDeploymentReplicas(kubeClient, deploymentName, namespace, 0)
 
 	if err != nil {
 		logger.Errorf(err, "Could not scale down deployment %s.%s", deploymentName, namespace)
@@ -81,21 +292,44 @@ func scaleDownDeployment(kubeClient kubernetes.Interface, deploymentName, namesp
 		logger.Debugf("Deployment %s.%s scaled down", deploymentName, namespace)
 	}
 
+	recordScaleEventf(kubeClient, recorder, deploy, serviceName, corev1.EventTypeNormal, eventReasonScaledDown,
+		"Deployment %s scaled down to 0 replicas after %s of inactivity",
+		deploymentName, time.Since(lastUsed).Round(time.Second))
+
 	return nil
 }
 
 // return true if deployment `replicas` > 0
-func isDeploymentRunning(kubeClient kubernetes.Interface, deployment, namespace string) bool {
-	deploy, err := getDeployment(kubeClient, deployment, namespace)
+func isDeploymentRunning(deploy *appsv1.Deployment) bool {
+	return getDeploymentReplicas(deploy) > 0
+}
 
-	if err != nil {
-		logger.Errorf(err, "Error retrieving deployment %s.%s", deployment, namespace)
-		return false
+// `spec.replicas` defaults to 1 when not set
+func getDeploymentReplicas(deploy *appsv1.Deployment) int {
+	if deploy.Spec.Replicas == nil {
+		return 1
 	}
 
-	if *deploy.Spec.Replicas > 0 {
-		return true
+	return int(*deploy.Spec.Replicas)
+}
+
+func persistLastUsedInDeployment(
+	clientSet kubernetes.Interface, deploymentName, namespace string, lastUsed time.Time) error {
+	_, err := patchDeploymentAnnotations(clientSet, deploymentName, namespace, map[string]string{
+		clusterutils.AnnotationDeploymentLastUsed: lastUsed.UTC().Format(time.RFC3339),
+	})
+
+	if err != nil {
+		logger.Errorf(err, "Could not persist lastUsed in deployment %s.%s", deploymentName, namespace)
+		return err
 	}
 
-	return false
+	logger.Debugf("Deployment %s.%s lastUsed persisted - %s", deploymentName, namespace, lastUsed)
+
+	return nil
+}
+
+// return nil if the annotation is missing or invalid
+func getLastUsedFromDeployment(deploy *appsv1.Deployment) *time.Time {
+	return clusterutils.ParseStringToTimePointer(deploy.Annotations[clusterutils.AnnotationDeploymentLastUsed])
 }
@@ -1,28 +1,274 @@
 package kube
 
 import (
+	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func Test_waitForDeploymentAvailable(t *testing.T) {
 	clientSet := fake.NewSimpleClientset()
+	watcher := newEndpointsWatcher()
 
 	timeout := 1
+	pollInterval := 1
 
 	// error - deployment is not in kubernetes
-	assert.Error(t, waitForDeploymentAvailable(clientSet, dummyProxlessName, dummyNamespaceName, timeout))
+	assert.Error(t, waitForDeploymentAvailable(clientSet, watcher, dummyProxlessName, dummyProxlessName, dummyNamespaceName, timeout, pollInterval))
 
 	helper_createNamespace(t, clientSet)
 	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
 
 	// error - deployment in kubernetes but not available
-	assert.Error(t, waitForDeploymentAvailable(clientSet, dummyProxlessName, dummyNamespaceName, timeout))
+	assert.Error(t, waitForDeploymentAvailable(clientSet, watcher, dummyProxlessName, dummyProxlessName, dummyNamespaceName, timeout, pollInterval))
 
 	deploy.Status.AvailableReplicas = 1
 	helper_updateDeployment(t, clientSet, deploy)
 
 	// no error - deployment in kubernetes and available
-	assert.NoError(t, waitForDeploymentAvailable(clientSet, dummyProxlessName, dummyNamespaceName, timeout))
+	assert.NoError(t, waitForDeploymentAvailable(clientSet, watcher, dummyProxlessName, dummyProxlessName, dummyNamespaceName, timeout, pollInterval))
+
+	// nobody must still be waiting
+	assert.Len(t, watcher.waiters, 0)
+}
+
+func Test_waitForDeploymentAvailable_Watcher(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	watcher := newEndpointsWatcher()
+
+	helper_createNamespace(t, clientSet)
+	helper_createProxlessCompatibleDeployment(t, clientSet)
+
+	// the poll interval is longer than the timeout - only the watcher can release the request
+	timeout := 2
+	pollInterval := 10
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		watcher.notify(clusterutils.GenRouteId(dummyProxlessName, dummyNamespaceName), []string{"10.0.0.1"})
+	}()
+
+	now := time.Now()
+	assert.NoError(t, waitForDeploymentAvailable(clientSet, watcher, dummyProxlessName, dummyProxlessName, dummyNamespaceName, timeout, pollInterval))
+	assert.True(t, time.Now().Sub(now) < time.Second)
+}
+
+func Test_waitForDeploymentAvailable_EndpointSlices(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	watcher := newEndpointsWatcher()
+
+	timeout := 1
+	pollInterval := 1
+
+	helper_createNamespace(t, clientSet)
+	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
+	deploy.Status.AvailableReplicas = 1
+	helper_updateDeployment(t, clientSet, deploy)
+
+	endpointSlice := &discoveryv1beta1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dummyProxlessName,
+			Namespace: dummyNamespaceName,
+			Labels:    map[string]string{discoveryv1beta1.LabelServiceName: dummyProxlessName},
+		},
+		AddressType: discoveryv1beta1.AddressTypeIPv4,
+		Endpoints: []discoveryv1beta1.Endpoint{
+			{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1beta1.EndpointConditions{Ready: pointer.BoolPtr(false)}},
+		},
+	}
+	endpointSlice, err := clientSet.DiscoveryV1beta1().EndpointSlices(dummyNamespaceName).Create(
+		context.TODO(), endpointSlice, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// error - deployment available but the pod is not a ready endpoint of the service yet
+	assert.Error(t, waitForDeploymentAvailable(
+		clientSet, watcher, dummyProxlessName, dummyProxlessName, dummyNamespaceName, timeout, pollInterval))
+
+	endpointSlice.Endpoints[0].Conditions.Ready = pointer.BoolPtr(true)
+	_, err = clientSet.DiscoveryV1beta1().EndpointSlices(dummyNamespaceName).Update(
+		context.TODO(), endpointSlice, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	// no error - the service has a ready endpoint
+	assert.NoError(t, waitForDeploymentAvailable(
+		clientSet, watcher, dummyProxlessName, dummyProxlessName, dummyNamespaceName, timeout, pollInterval))
+}
+
+func Test_scaleDeployment_Events(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	watcher := newEndpointsWatcher()
+	recorder := record.NewFakeRecorder(10)
+
+	helper_createNamespace(t, clientSet)
+	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
+	svc := helper_createProxlessCompatibleService(t, clientSet)
+
+	// the events are recorded on the deployment and on the service
+	assert.Error(t, scaleUpDeployment(
+		clientSet, watcher, recorder, dummyProxlessName, svc.Name, dummyNamespaceName, 1, 1))
+	assert.Contains(t, <-recorder.Events, eventReasonWakeTimeout)
+	assert.Contains(t, <-recorder.Events, eventReasonWakeTimeout)
+
+	deploy.Status.AvailableReplicas = 1
+	helper_updateDeployment(t, clientSet, deploy)
+
+	assert.NoError(t, scaleUpDeployment(
+		clientSet, watcher, recorder, dummyProxlessName, svc.Name, dummyNamespaceName, 1, 1))
+	assert.Contains(t, <-recorder.Events, eventReasonScaledUp)
+	assert.Contains(t, <-recorder.Events, eventReasonScaledUp)
+
+	assert.NoError(t, scaleDownDeployment(
+		clientSet, recorder, dummyProxlessName, svc.Name, dummyNamespaceName, time.Now().Add(-time.Minute)))
+	assert.Contains(t, <-recorder.Events, "after 1m0s of inactivity")
+	assert.Contains(t, <-recorder.Events, "after 1m0s of inactivity")
+
+	// already scaled down - no event
+	assert.NoError(t, scaleDownDeployment(
+		clientSet, recorder, dummyProxlessName, svc.Name, dummyNamespaceName, time.Now().Add(-time.Minute)))
+	assert.Len(t, recorder.Events, 0)
+}
+
+func Test_scaleDownDeployment_AlreadyScaledDown(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	recorder := record.NewFakeRecorder(10)
+
+	helper_createNamespace(t, clientSet)
+	helper_createProxlessCompatibleDeployment(t, clientSet)
+	helper_createHPA(t, clientSet, dummyProxlessName, 2)
+
+	clientSet.ClearActions()
+
+	assert.NoError(t, scaleDownDeployment(clientSet, recorder, dummyProxlessName, "", dummyNamespaceName, time.Now()))
+
+	// only the deployment is read - the HPA and the deployment must not be patched
+	if assert.Len(t, clientSet.Actions(), 1) {
+		assert.Equal(t, "get", clientSet.Actions()[0].GetVerb())
+	}
+	assert.Equal(t, int32(2), *helper_getHPA(t, clientSet).Spec.MinReplicas)
+	assert.Len(t, recorder.Events, 0)
+
+	// error - deployment is not in kubernetes
+	assert.Error(t, scaleDownDeployment(clientSet, recorder, dummyNonProxlessName, "", dummyNamespaceName, time.Now()))
+}
+
+func Test_persistLastUsedInDeployment(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+
+	lastUsed := time.Now()
+
+	// error - deployment is not in kubernetes
+	assert.Error(t, persistLastUsedInDeployment(clientSet, dummyProxlessName, dummyNamespaceName, lastUsed))
+
+	helper_createNamespace(t, clientSet)
+	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
+
+	// no annotation yet
+	assert.Nil(t, getLastUsedFromDeployment(deploy))
+
+	assert.NoError(t, persistLastUsedInDeployment(clientSet, dummyProxlessName, dummyNamespaceName, lastUsed))
+
+	// the annotation is stored with a precision of 1 second
+	deploy, err := getDeployment(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	got := getLastUsedFromDeployment(deploy)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, lastUsed.Unix(), got.Unix())
+	}
+}
+
+func Test_getDeploymentReplicas(t *testing.T) {
+	testCases := []struct {
+		replicas      *int32
+		want          int
+		wantIsRunning bool
+	}{
+		{nil, 1, true},
+		{pointer.Int32Ptr(0), 0, false},
+		{pointer.Int32Ptr(3), 3, true},
+	}
+
+	for _, tc := range testCases {
+		deploy := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: tc.replicas}}
+
+		assert.Equal(t, tc.want, getDeploymentReplicas(deploy))
+		assert.Equal(t, tc.wantIsRunning, isDeploymentRunning(deploy))
+	}
+}
+
+func Test_deploymentExists(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+
+	exists, err := deploymentExists(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	helper_createNamespace(t, clientSet)
+	helper_createProxlessCompatibleDeployment(t, clientSet)
+
+	exists, err = deploymentExists(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func Test_resolveServiceDeployment(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	helper_createDeploymentWithTemplateLabels(t, clientSet, "deploy-a", map[string]string{"app": "a"})
+	helper_createDeploymentWithTemplateLabels(t, clientSet, "deploy-b1", map[string]string{"app": "b", "v": "1"})
+	helper_createDeploymentWithTemplateLabels(t, clientSet, "deploy-b2", map[string]string{"app": "b", "v": "2"})
+
+	testCases := []struct {
+		selector   map[string]string
+		deployName string
+		wakeAll    bool
+		want       []string
+		errWanted  bool
+	}{
+		{map[string]string{"app": "a"}, "", false, []string{"deploy-a"}, false},
+		{map[string]string{"app": "a"}, "deploy-z", false, []string{"deploy-z"}, false},
+		{map[string]string{"app": "a"}, "deploy-y, deploy-z", false, []string{"deploy-y", "deploy-z"}, false},
+		{map[string]string{"app": "b", "v": "2"}, "", false, []string{"deploy-b2"}, false},
+		{map[string]string{"app": "b"}, "", false, nil, true},
+		{map[string]string{"app": "b"}, "", true, []string{"deploy-b1", "deploy-b2"}, false},
+		{map[string]string{"app": "c"}, "", false, nil, true},
+		{nil, "", false, nil, true},
+	}
+
+	for i, tc := range testCases {
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: dummyProxlessName, Namespace: dummyNamespaceName},
+			Spec:       corev1.ServiceSpec{Selector: tc.selector},
+		}
+
+		got, err := resolveServiceDeployment(clientSet, svc, tc.deployName, tc.wakeAll)
+		assert.Equal(t, tc.errWanted, err != nil, i)
+		assert.Equal(t, tc.want, got, i)
+	}
+}
+
+func Test_forEachDeployment(t *testing.T) {
+	var called int32
+
+	err := forEachDeployment("deploy-a,deploy-b,deploy-c", func(name string) error {
+		atomic.AddInt32(&called, 1)
+		if name == "deploy-b" {
+			return errors.New("scale failed")
+		}
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), called)
+	assert.NoError(t, forEachDeployment("deploy-a", func(name string) error { return nil }))
 }
@@ -0,0 +1,92 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"kube-proxless/internal/logger"
+	"time"
+)
+
+func parseDeployment(obj interface{}) (*appsv1.Deployment, error) {
+	deploy, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("event for invalid object; got %T want *apps.Deployment", obj))
+	}
+	return deploy, nil
+}
+
+// the informer sends the last known state of the deployment if it missed the deletion - e.g. after a disconnection
+func parseDeletedDeployment(obj interface{}) (*appsv1.Deployment, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	return parseDeployment(obj)
+}
+
+func updateDeploymentReplicas(
+	deploy *appsv1.Deployment, replicas int,
+	updateReplicas func(deployName, namespace string, replicas, availableReplicas int) error) {
+	err := updateReplicas(deploy.Name, deploy.Namespace, replicas, int(deploy.Status.AvailableReplicas))
+
+	if err != nil {
+		logger.Errorf(err, "Error updating replicas of deployment %s.%s in memory", deploy.Name, deploy.Namespace)
+	}
+}
+
+func runDeploymentsInformer(
+	clientSet kubernetes.Interface,
+	namespaceScope string,
+	informerResyncInterval int,
+	updateReplicas func(deployName, namespace string, replicas, availableReplicas int) error,
+	stopCh <-chan struct{},
+) {
+	opts := make([]informers.SharedInformerOption, 0)
+	if namespaceScope != "" {
+		opts = append(opts, informers.WithNamespace(namespaceScope))
+	}
+	informer := informers.
+		NewSharedInformerFactoryWithOptions(clientSet, time.Duration(informerResyncInterval)*time.Second, opts...).
+		Apps().V1().Deployments().Informer()
+
+	eventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			deploy, err := parseDeployment(obj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process deployment in AddFunc handler")
+				return
+			}
+
+			updateDeploymentReplicas(deploy, getDeploymentReplicas(deploy), updateReplicas)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			deploy, err := parseDeployment(newObj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process deployment in UpdateFunc handler")
+				return
+			}
+
+			updateDeploymentReplicas(deploy, getDeploymentReplicas(deploy), updateReplicas)
+		},
+		DeleteFunc: func(obj interface{}) {
+			deploy, err := parseDeletedDeployment(obj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process deployment in DeleteFunc handler")
+				return
+			}
+
+			// a deleted deployment is not running anymore
+			updateDeploymentReplicas(deploy, 0, updateReplicas)
+		},
+	}
+	informer.AddEventHandler(eventHandler)
+
+	informer.Run(stopCh)
+}
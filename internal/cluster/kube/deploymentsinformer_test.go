@@ -0,0 +1,83 @@
+package kube
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"testing"
+	"time"
+)
+
+func Test_parseDeployment(t *testing.T) {
+	testCases := []struct {
+		deploy    interface{}
+		errWanted bool
+	}{
+		{&appsv1.Deployment{}, false},
+		{&corev1.Service{}, true},
+	}
+
+	for _, tc := range testCases {
+		_, errGot := parseDeployment(tc.deploy)
+
+		if tc.errWanted != (errGot != nil) {
+			t.Errorf("parseDeployment(%v) = %v; error wanted = %t",
+				tc.deploy, errGot, tc.errWanted)
+		}
+	}
+}
+
+func Test_parseDeletedDeployment(t *testing.T) {
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: dummyProxlessName}}
+
+	testCases := []struct {
+		obj       interface{}
+		errWanted bool
+	}{
+		{deploy, false},
+		{cache.DeletedFinalStateUnknown{Key: dummyProxlessName, Obj: deploy}, false},
+		{cache.DeletedFinalStateUnknown{Key: dummyProxlessName, Obj: &corev1.Service{}}, true},
+		{&corev1.Service{}, true},
+	}
+
+	for _, tc := range testCases {
+		got, err := parseDeletedDeployment(tc.obj)
+
+		assert.Equal(t, tc.errWanted, err != nil, tc.obj)
+		if !tc.errWanted {
+			assert.Equal(t, deploy, got)
+		}
+	}
+}
+
+func Test_runDeploymentsInformer(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+
+	helper_createNamespace(t, clientSet)
+	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	memory := fakeMemory{m: map[string]string{}, replicas: map[string]int{}, endpoints: map[string][]string{}}
+	go runDeploymentsInformer(clientSet, dummyNamespaceName, 60, memory.helper_updateReplicasInMemory, stopCh)
+
+	deploy.Spec.Replicas = pointer.Int32Ptr(1)
+	deploy.Status.AvailableReplicas = 1
+	helper_updateDeployment(t, clientSet, deploy)
+
+	// the replicas of the deployments are sent to the memory
+	time.Sleep(100 * time.Millisecond)
+	id := clusterutils.GenRouteId(dummyProxlessName, dummyNamespaceName)
+	assert.Equal(t, 1, memory.helper_getReplicas(id))
+
+	assert.NoError(t, clientSet.AppsV1().Deployments(dummyNamespaceName).Delete(
+		context.TODO(), dummyProxlessName, metav1.DeleteOptions{}))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, memory.helper_getReplicas(id))
+}
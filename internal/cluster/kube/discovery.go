@@ -0,0 +1,156 @@
+package kube
+
+import (
+	"context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+	"strings"
+)
+
+// a service targeted by an ingress or an http route annotated with `proxless/enabled`
+type discoveredBackend struct {
+	service string
+	port    string
+	domains []string
+}
+
+// the backend can already target the proxless service if the deployment is asleep
+// the suffix is not enough - the application service can also end with `-proxless`
+func getBackendServiceName(clientSet kubernetes.Interface, namespace, name string) string {
+	suffix := clusterutils.GenServiceToAppName("")
+
+	if !strings.HasSuffix(name, suffix) {
+		return name
+	}
+
+	svc, err := clientSet.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+
+	if err != nil || !isProxlessService(svc) {
+		return name
+	}
+
+	return strings.TrimSuffix(name, suffix)
+}
+
+func addDiscoveredBackend(backends map[string]*discoveredBackend, service, port, domain string) {
+	backend, ok := backends[service]
+	if !ok {
+		backend = &discoveredBackend{service: service, port: port}
+		backends[service] = backend
+	}
+
+	if domain != "" {
+		for _, d := range backend.domains {
+			if d == domain {
+				return
+			}
+		}
+		backend.domains = append(backend.domains, domain)
+	}
+}
+
+// the `proxless/deployment` annotation of the ingress is only used if it targets a single service
+// the deployment is inferred from the selector of the service otherwise
+func getDiscoveredDeployment(annotations map[string]string, backends map[string]*discoveredBackend) string {
+	if len(backends) != 1 {
+		return ""
+	}
+
+	return annotations[clusterutils.AnnotationServiceDeployKey]
+}
+
+func addDiscoveredBackendsToMemory(
+	clientSet kubernetes.Interface, kind, name, namespace string, annotations map[string]string,
+	backends map[string]*discoveredBackend, namespaceScoped bool, proxlessSvc, proxlessNamespace string,
+	upsertMemory func(route *model.Route) error,
+) {
+	// the deployment of each service is inferred from its selector if empty
+	deployName := getDiscoveredDeployment(annotations, backends)
+	ttlSeconds := clusterutils.ParseStringToIntPointer(annotations[clusterutils.AnnotationServiceTTLSeconds])
+	readinessTimeoutSeconds := clusterutils.ParseStringToIntPointer(annotations[clusterutils.AnnotationServiceReadinessTimeoutSeconds])
+	minUptimeSeconds := clusterutils.ParseStringToIntPointer(annotations[clusterutils.AnnotationServiceMinUptimeSeconds])
+	pinnedUntil := clusterutils.ParseStringToTime(annotations[clusterutils.AnnotationServicePinnedUntil])
+
+	for _, backend := range backends {
+		// the annotated services are managed by the services engine
+		if svc, err := clientSet.CoreV1().Services(namespace).Get(
+			context.TODO(), backend.service, metav1.GetOptions{}); err == nil &&
+			clusterutils.IsAnnotationsProxlessCompatible(svc.ObjectMeta) {
+			logger.Debugf("Service %s.%s from %s %s already annotated - skipping", backend.service, namespace, kind, name)
+			continue
+		}
+
+		route, errs := genRouteFromService(
+			clientSet, backend.service, namespace, deployName, backend.port, backend.domains, nil,
+			ttlSeconds, readinessTimeoutSeconds, namespaceScoped, proxlessSvc, proxlessNamespace)
+
+		for _, err := range errs {
+			logger.Errorf(err, "Error discovering service %s.%s from %s %s", backend.service, namespace, kind, name)
+		}
+
+		if route == nil {
+			continue
+		}
+
+		route.SetMinUptimeSeconds(minUptimeSeconds)
+		route.SetPinnedUntil(pinnedUntil)
+
+		if err := upsertMemory(route); err != nil {
+			logger.Errorf(err, "Error adding service %s.%s from %s %s into memory", backend.service, namespace, kind, name)
+		} else {
+			logger.Debugf("Service %s.%s from %s %s added into memory", backend.service, namespace, kind, name)
+		}
+	}
+}
+
+// remove the routes of the services that are not targeted anymore
+func removeDiscoveredBackendsFromMemory(
+	clientSet kubernetes.Interface, namespace string, oldBackends, newBackends map[string]*discoveredBackend,
+	deleteRouteFromMemory func(id string) error,
+) {
+	for service := range oldBackends {
+		if _, ok := newBackends[service]; ok {
+			continue
+		}
+
+		_ = deleteProxlessService(clientSet, service, namespace)
+
+		if err := deleteRouteFromMemory(clusterutils.GenRouteId(service, namespace)); err != nil {
+			logger.Errorf(err, "Error removing service %s.%s from memory", service, namespace)
+		}
+	}
+}
+
+// the backends always target the proxless service so that proxless sees every request and keeps the route in use
+// return false if the deployment is unknown - the backend must not be changed
+func getDesiredBackendService(
+	clientSet kubernetes.Interface, annotations map[string]string, namespace string,
+	backends map[string]*discoveredBackend, service string,
+) (string, bool) {
+	deployName := getDiscoveredDeployment(annotations, backends)
+
+	if deployName == "" {
+		svc, err := clientSet.CoreV1().Services(namespace).Get(context.TODO(), service, metav1.GetOptions{})
+
+		if err != nil {
+			return "", false
+		}
+
+		deployNames, err := resolveServiceDeployment(clientSet, svc, "", false)
+
+		if err != nil {
+			return "", false
+		}
+
+		deployName = deployNames[0]
+	}
+
+	if _, err := getDeployment(clientSet, deployName, namespace); err != nil {
+		return "", false
+	}
+
+	return clusterutils.GenServiceToAppName(service), true
+}
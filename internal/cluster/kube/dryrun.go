@@ -0,0 +1,271 @@
+package kube
+
+import (
+	"context"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	typedautoscalingv1 "k8s.io/client-go/kubernetes/typed/autoscaling/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typednetworkingv1beta1 "k8s.io/client-go/kubernetes/typed/networking/v1beta1"
+	"kube-proxless/internal/cluster"
+	"kube-proxless/internal/logger"
+	"sort"
+	"sync"
+)
+
+// number of writes skipped in dry-run mode per resource and action - exported on /metrics
+type dryRunActions struct {
+	counts map[cluster.DryRunActionReport]int
+	lock   sync.Mutex
+}
+
+func newDryRunActions() *dryRunActions {
+	return &dryRunActions{
+		counts: map[cluster.DryRunActionReport]int{},
+		lock:   sync.Mutex{},
+	}
+}
+
+func (a *dryRunActions) record(resource, action string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.counts[cluster.DryRunActionReport{Resource: resource, Action: action}]++
+}
+
+func (a *dryRunActions) get() []cluster.DryRunActionReport {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	reports := make([]cluster.DryRunActionReport, 0, len(a.counts))
+	for report, count := range a.counts {
+		report.Count = count
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Resource != reports[j].Resource {
+			return reports[i].Resource < reports[j].Resource
+		}
+		return reports[i].Action < reports[j].Action
+	})
+
+	return reports
+}
+
+// in dry-run mode, the writes of proxless on the services, the deployments, the HPAs, the ingresses
+// and the http routes are only logged and counted
+// the scale up/down of the deployments are skipped by the controller
+// the other methods are delegated to the embedded clients
+type dryRunClientSet struct {
+	kubernetes.Interface
+	actions *dryRunActions
+}
+
+func newDryRunClientSet(clientSet kubernetes.Interface, actions *dryRunActions) kubernetes.Interface {
+	return &dryRunClientSet{Interface: clientSet, actions: actions}
+}
+
+func (c *dryRunClientSet) CoreV1() typedcorev1.CoreV1Interface {
+	return &dryRunCoreV1{CoreV1Interface: c.Interface.CoreV1(), actions: c.actions}
+}
+
+func (c *dryRunClientSet) AppsV1() typedappsv1.AppsV1Interface {
+	return &dryRunAppsV1{AppsV1Interface: c.Interface.AppsV1(), actions: c.actions}
+}
+
+func (c *dryRunClientSet) AutoscalingV1() typedautoscalingv1.AutoscalingV1Interface {
+	return &dryRunAutoscalingV1{AutoscalingV1Interface: c.Interface.AutoscalingV1(), actions: c.actions}
+}
+
+func (c *dryRunClientSet) NetworkingV1beta1() typednetworkingv1beta1.NetworkingV1beta1Interface {
+	return &dryRunNetworkingV1beta1{NetworkingV1beta1Interface: c.Interface.NetworkingV1beta1(), actions: c.actions}
+}
+
+type dryRunCoreV1 struct {
+	typedcorev1.CoreV1Interface
+	actions *dryRunActions
+}
+
+func (c *dryRunCoreV1) Services(namespace string) typedcorev1.ServiceInterface {
+	return &dryRunServices{
+		ServiceInterface: c.CoreV1Interface.Services(namespace), namespace: namespace, actions: c.actions}
+}
+
+type dryRunServices struct {
+	typedcorev1.ServiceInterface
+	namespace string
+	actions   *dryRunActions
+}
+
+func (s *dryRunServices) Create(
+	ctx context.Context, svc *corev1.Service, opts metav1.CreateOptions) (*corev1.Service, error) {
+	logger.Infof("[dry-run] Would create service %s.%s", svc.Name, s.namespace)
+	s.actions.record("services", "create")
+	return svc, nil
+}
+
+func (s *dryRunServices) Update(
+	ctx context.Context, svc *corev1.Service, opts metav1.UpdateOptions) (*corev1.Service, error) {
+	logger.Infof("[dry-run] Would update service %s.%s", svc.Name, s.namespace)
+	s.actions.record("services", "update")
+	return svc, nil
+}
+
+func (s *dryRunServices) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	logger.Infof("[dry-run] Would delete service %s.%s", name, s.namespace)
+	s.actions.record("services", "delete")
+	return nil
+}
+
+func (s *dryRunServices) Patch(
+	ctx context.Context, name string, pt k8stypes.PatchType, data []byte, opts metav1.PatchOptions,
+	subresources ...string) (*corev1.Service, error) {
+	logger.Infof("[dry-run] Would patch service %s.%s - %s", name, s.namespace, string(data))
+	s.actions.record("services", "patch")
+	return s.ServiceInterface.Get(ctx, name, metav1.GetOptions{})
+}
+
+type dryRunAppsV1 struct {
+	typedappsv1.AppsV1Interface
+	actions *dryRunActions
+}
+
+func (c *dryRunAppsV1) Deployments(namespace string) typedappsv1.DeploymentInterface {
+	return &dryRunDeployments{
+		DeploymentInterface: c.AppsV1Interface.Deployments(namespace), namespace: namespace, actions: c.actions}
+}
+
+// e.g. the `lastUsed` annotation written by the lastUsed persister
+type dryRunDeployments struct {
+	typedappsv1.DeploymentInterface
+	namespace string
+	actions   *dryRunActions
+}
+
+func (d *dryRunDeployments) Update(
+	ctx context.Context, deploy *appsv1.Deployment, opts metav1.UpdateOptions) (*appsv1.Deployment, error) {
+	logger.Infof("[dry-run] Would update deployment %s.%s", deploy.Name, d.namespace)
+	d.actions.record("deployments", "update")
+	return deploy, nil
+}
+
+func (d *dryRunDeployments) Patch(
+	ctx context.Context, name string, pt k8stypes.PatchType, data []byte, opts metav1.PatchOptions,
+	subresources ...string) (*appsv1.Deployment, error) {
+	logger.Infof("[dry-run] Would patch deployment %s.%s - %s", name, d.namespace, string(data))
+	d.actions.record("deployments", "patch")
+	return d.DeploymentInterface.Get(ctx, name, metav1.GetOptions{})
+}
+
+type dryRunAutoscalingV1 struct {
+	typedautoscalingv1.AutoscalingV1Interface
+	actions *dryRunActions
+}
+
+func (c *dryRunAutoscalingV1) HorizontalPodAutoscalers(
+	namespace string) typedautoscalingv1.HorizontalPodAutoscalerInterface {
+	return &dryRunHPAs{
+		HorizontalPodAutoscalerInterface: c.AutoscalingV1Interface.HorizontalPodAutoscalers(namespace),
+		namespace:                        namespace,
+		actions:                          c.actions,
+	}
+}
+
+type dryRunHPAs struct {
+	typedautoscalingv1.HorizontalPodAutoscalerInterface
+	namespace string
+	actions   *dryRunActions
+}
+
+func (h *dryRunHPAs) Update(
+	ctx context.Context, hpa *autoscalingv1.HorizontalPodAutoscaler,
+	opts metav1.UpdateOptions) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	logger.Infof("[dry-run] Would update HPA %s.%s", hpa.Name, h.namespace)
+	h.actions.record("horizontalpodautoscalers", "update")
+	return hpa, nil
+}
+
+func (h *dryRunHPAs) Patch(
+	ctx context.Context, name string, pt k8stypes.PatchType, data []byte, opts metav1.PatchOptions,
+	subresources ...string) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	logger.Infof("[dry-run] Would patch HPA %s.%s - %s", name, h.namespace, string(data))
+	h.actions.record("horizontalpodautoscalers", "patch")
+	return h.HorizontalPodAutoscalerInterface.Get(ctx, name, metav1.GetOptions{})
+}
+
+type dryRunNetworkingV1beta1 struct {
+	typednetworkingv1beta1.NetworkingV1beta1Interface
+	actions *dryRunActions
+}
+
+func (c *dryRunNetworkingV1beta1) Ingresses(namespace string) typednetworkingv1beta1.IngressInterface {
+	return &dryRunIngresses{
+		IngressInterface: c.NetworkingV1beta1Interface.Ingresses(namespace), namespace: namespace, actions: c.actions}
+}
+
+type dryRunIngresses struct {
+	typednetworkingv1beta1.IngressInterface
+	namespace string
+	actions   *dryRunActions
+}
+
+func (i *dryRunIngresses) Update(
+	ctx context.Context, ing *networkingv1beta1.Ingress, opts metav1.UpdateOptions) (*networkingv1beta1.Ingress, error) {
+	logger.Infof("[dry-run] Would update the backends of ingress %s.%s", ing.Name, i.namespace)
+	i.actions.record("ingresses", "update")
+	return ing, nil
+}
+
+type dryRunDynamicClient struct {
+	dynamic.Interface
+	actions *dryRunActions
+}
+
+func newDryRunDynamicClient(dynamicClient dynamic.Interface, actions *dryRunActions) dynamic.Interface {
+	return &dryRunDynamicClient{Interface: dynamicClient, actions: actions}
+}
+
+// only the http routes are modified by proxless - the status of the proxless routes is still written
+func (c *dryRunDynamicClient) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	if resource != httpRouteResource {
+		return c.Interface.Resource(resource)
+	}
+
+	return &dryRunResource{
+		NamespaceableResourceInterface: c.Interface.Resource(resource), resource: resource.Resource, actions: c.actions}
+}
+
+type dryRunResource struct {
+	dynamic.NamespaceableResourceInterface
+	resource string
+	actions  *dryRunActions
+}
+
+func (r *dryRunResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &dryRunNamespacedResource{
+		ResourceInterface: r.NamespaceableResourceInterface.Namespace(namespace), resource: r.resource, actions: r.actions}
+}
+
+type dryRunNamespacedResource struct {
+	dynamic.ResourceInterface
+	resource string
+	actions  *dryRunActions
+}
+
+func (r *dryRunNamespacedResource) Update(
+	ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions,
+	subresources ...string) (*unstructured.Unstructured, error) {
+	logger.Infof("[dry-run] Would update the backends of %s %s.%s", obj.GetKind(), obj.GetName(), obj.GetNamespace())
+	r.actions.record(r.resource, "update")
+	return obj, nil
+}
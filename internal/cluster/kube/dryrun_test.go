@@ -0,0 +1,52 @@
+package kube
+
+import (
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"kube-proxless/internal/cluster"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"testing"
+	"time"
+)
+
+func Test_newDryRunClientSet(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	actions := newDryRunActions()
+	dryRunClientSet := newDryRunClientSet(clientSet, actions)
+	name := clusterutils.GenServiceToAppName(dummyNonProxlessName)
+
+	// the proxless service is not created
+	_, err := createProxlessService(dryRunClientSet, helper_newService(dummyNonProxlessName), dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	helper_assertServiceExists(t, clientSet, name, false)
+
+	// nor deleted
+	_, err = createProxlessService(clientSet, helper_newService(dummyNonProxlessName), dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.NoError(t, deleteProxlessService(dryRunClientSet, dummyNonProxlessName, dummyNamespaceName))
+	helper_assertServiceExists(t, clientSet, name, true)
+
+	// the reads are delegated
+	helper_assertServiceExists(t, dryRunClientSet, name, true)
+
+	// the lastUsed is not written in the deployment
+	helper_createProxlessCompatibleDeployment(t, clientSet)
+	assert.NoError(t, persistLastUsedInDeployment(dryRunClientSet, dummyProxlessName, dummyNamespaceName, time.Now()))
+	deploy, err := getDeployment(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.False(t, metav1.HasAnnotation(deploy.ObjectMeta, clusterutils.AnnotationDeploymentLastUsed))
+
+	// nor the HPA paused
+	helper_createHPA(t, clientSet, dummyProxlessName, 2)
+	assert.NoError(t, pauseHPA(dryRunClientSet, dummyProxlessName, dummyNamespaceName))
+	assert.Equal(t, int32(2), *helper_getHPA(t, clientSet).Spec.MinReplicas)
+
+	assert.Equal(t, []cluster.DryRunActionReport{
+		{Resource: "deployments", Action: "patch", Count: 1},
+		{Resource: "horizontalpodautoscalers", Action: "patch", Count: 1},
+		{Resource: "services", Action: "create", Count: 1},
+		{Resource: "services", Action: "delete", Count: 1},
+	}, actions.get())
+}
@@ -0,0 +1,194 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listersv1beta1 "k8s.io/client-go/listers/discovery/v1beta1"
+	"k8s.io/client-go/tools/cache"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+	"sync"
+	"time"
+)
+
+// release the requests waiting for a service to be ready as soon as the informer sees its first ready endpoint
+type endpointsWatcher struct {
+	waiters map[string][]chan struct{}
+	lock    sync.Mutex
+}
+
+func newEndpointsWatcher() *endpointsWatcher {
+	return &endpointsWatcher{
+		waiters: make(map[string][]chan struct{}),
+		lock:    sync.Mutex{},
+	}
+}
+
+// return a channel closed when the service `id` has a ready endpoint
+func (w *endpointsWatcher) register(id string) chan struct{} {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	ch := make(chan struct{})
+	w.waiters[id] = append(w.waiters[id], ch)
+
+	return ch
+}
+
+func (w *endpointsWatcher) unregister(id string, ch chan struct{}) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for i, c := range w.waiters[id] {
+		if c == ch {
+			w.waiters[id] = append(w.waiters[id][:i], w.waiters[id][i+1:]...)
+			break
+		}
+	}
+
+	if len(w.waiters[id]) == 0 {
+		delete(w.waiters, id)
+	}
+}
+
+func (w *endpointsWatcher) notify(id string, endpoints []string) {
+	if len(endpoints) == 0 {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for _, ch := range w.waiters[id] {
+		close(ch)
+	}
+	delete(w.waiters, id)
+}
+
+func parseEndpointSlice(obj interface{}) (*discoveryv1beta1.EndpointSlice, error) {
+	endpointSlice, ok := obj.(*discoveryv1beta1.EndpointSlice)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("event for invalid object; got %T want *discovery.EndpointSlice", obj))
+	}
+	return endpointSlice, nil
+}
+
+// return the addresses of the ready endpoints of all the slices
+func getReadyEndpoints(endpointSlices []*discoveryv1beta1.EndpointSlice) []string {
+	endpoints := []string{}
+
+	for _, endpointSlice := range endpointSlices {
+		if endpointSlice.AddressType == discoveryv1beta1.AddressTypeFQDN {
+			continue
+		}
+
+		for _, endpoint := range endpointSlice.Endpoints {
+			// `ready` nil must be interpreted as ready
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+
+			endpoints = append(endpoints, endpoint.Addresses...)
+		}
+	}
+
+	return endpoints
+}
+
+func listServiceEndpointSlices(
+	clientSet kubernetes.Interface, serviceName, namespace string) ([]*discoveryv1beta1.EndpointSlice, error) {
+	list, err := clientSet.DiscoveryV1beta1().EndpointSlices(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{discoveryv1beta1.LabelServiceName: serviceName}).String(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	endpointSlices := make([]*discoveryv1beta1.EndpointSlice, 0, len(list.Items))
+	for i := range list.Items {
+		endpointSlices = append(endpointSlices, &list.Items[i])
+	}
+
+	return endpointSlices, nil
+}
+
+// a service can have multiple slices - the endpoints of all of them are sent to the watcher and to the memory
+func updateServiceEndpoints(
+	lister listersv1beta1.EndpointSliceLister, watcher *endpointsWatcher, endpointSlice *discoveryv1beta1.EndpointSlice,
+	updateEndpoints func(id string, endpoints []string) error) {
+	serviceName, ok := endpointSlice.Labels[discoveryv1beta1.LabelServiceName]
+
+	if !ok {
+		return
+	}
+
+	endpointSlices, err := lister.EndpointSlices(endpointSlice.Namespace).List(
+		labels.SelectorFromSet(labels.Set{discoveryv1beta1.LabelServiceName: serviceName}))
+
+	if err != nil {
+		logger.Errorf(err, "Cannot list endpoint slices of service %s.%s", serviceName, endpointSlice.Namespace)
+		return
+	}
+
+	id := clusterutils.GenRouteId(serviceName, endpointSlice.Namespace)
+	endpoints := getReadyEndpoints(endpointSlices)
+
+	watcher.notify(id, endpoints)
+	err = updateEndpoints(id, endpoints)
+
+	if err != nil {
+		logger.Errorf(err, "Error updating endpoints of service %s.%s in memory", serviceName, endpointSlice.Namespace)
+	}
+}
+
+func runEndpointSlicesInformer(
+	clientSet kubernetes.Interface,
+	namespaceScope string,
+	informerResyncInterval int,
+	watcher *endpointsWatcher,
+	updateEndpoints func(id string, endpoints []string) error,
+	stopCh <-chan struct{},
+) {
+	opts := make([]informers.SharedInformerOption, 0)
+	if namespaceScope != "" {
+		opts = append(opts, informers.WithNamespace(namespaceScope))
+	}
+	endpointSlicesInformer := informers.
+		NewSharedInformerFactoryWithOptions(clientSet, time.Duration(informerResyncInterval)*time.Second, opts...).
+		Discovery().V1beta1().EndpointSlices()
+	informer := endpointSlicesInformer.Informer()
+	lister := endpointSlicesInformer.Lister()
+
+	handle := func(handler string, obj interface{}) {
+		endpointSlice, err := parseEndpointSlice(obj)
+
+		if err != nil {
+			logger.Errorf(err, "Cannot process endpoint slice in %s handler", handler)
+			return
+		}
+
+		updateServiceEndpoints(lister, watcher, endpointSlice, updateEndpoints)
+	}
+
+	eventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			handle("AddFunc", obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			handle("UpdateFunc", newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			handle("DeleteFunc", obj)
+		},
+	}
+	informer.AddEventHandler(eventHandler)
+
+	informer.Run(stopCh)
+}
@@ -0,0 +1,138 @@
+package kube
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"testing"
+	"time"
+)
+
+func Test_parseEndpointSlice(t *testing.T) {
+	testCases := []struct {
+		endpointSlice interface{}
+		errWanted     bool
+	}{
+		{&discoveryv1beta1.EndpointSlice{}, false},
+		{&corev1.Service{}, true},
+	}
+
+	for _, tc := range testCases {
+		_, errGot := parseEndpointSlice(tc.endpointSlice)
+
+		if tc.errWanted != (errGot != nil) {
+			t.Errorf("parseEndpointSlice(%v) = %v; error wanted = %t",
+				tc.endpointSlice, errGot, tc.errWanted)
+		}
+	}
+}
+
+func Test_getReadyEndpoints(t *testing.T) {
+	testCases := []struct {
+		endpointSlices []*discoveryv1beta1.EndpointSlice
+		want           []string
+	}{
+		{[]*discoveryv1beta1.EndpointSlice{}, []string{}},
+		{
+			[]*discoveryv1beta1.EndpointSlice{
+				{
+					AddressType: discoveryv1beta1.AddressTypeIPv4,
+					Endpoints: []discoveryv1beta1.Endpoint{
+						{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1beta1.EndpointConditions{Ready: pointer.BoolPtr(true)}},
+						{Addresses: []string{"10.0.0.2"}, Conditions: discoveryv1beta1.EndpointConditions{Ready: pointer.BoolPtr(false)}},
+						{Addresses: []string{"10.0.0.3"}}, // ready nil
+					},
+				},
+				{
+					AddressType: discoveryv1beta1.AddressTypeIPv4,
+					Endpoints:   []discoveryv1beta1.Endpoint{{Addresses: []string{"10.0.0.4"}}},
+				},
+				{
+					AddressType: discoveryv1beta1.AddressTypeFQDN,
+					Endpoints:   []discoveryv1beta1.Endpoint{{Addresses: []string{"example.com"}}},
+				},
+			},
+			[]string{"10.0.0.1", "10.0.0.3", "10.0.0.4"},
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, getReadyEndpoints(tc.endpointSlices))
+	}
+}
+
+func TestEndpointsWatcher_notify(t *testing.T) {
+	watcher := newEndpointsWatcher()
+
+	id := clusterutils.GenRouteId(dummyProxlessName, dummyNamespaceName)
+	otherId := clusterutils.GenRouteId(dummyNonProxlessName, dummyNamespaceName)
+	ch := watcher.register(id)
+	otherCh := watcher.register(otherId)
+
+	// no ready endpoint - must not release
+	watcher.notify(id, []string{})
+	assert.False(t, helper_isClosed(ch))
+
+	watcher.notify(id, []string{"10.0.0.1"})
+	assert.True(t, helper_isClosed(ch))
+	assert.False(t, helper_isClosed(otherCh))
+
+	// must not panic when unregistering a channel already released
+	watcher.unregister(id, ch)
+	watcher.unregister(otherId, otherCh)
+	assert.Len(t, watcher.waiters, 0)
+}
+
+func Test_runEndpointSlicesInformer(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	watcher := newEndpointsWatcher()
+	helper_createNamespace(t, clientSet)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	memory := fakeMemory{m: map[string]string{}, replicas: map[string]int{}, endpoints: map[string][]string{}}
+	go runEndpointSlicesInformer(
+		clientSet, dummyNamespaceName, 60, watcher, memory.helper_updateEndpointsInMemory, stopCh)
+
+	id := clusterutils.GenRouteId(dummyProxlessName, dummyNamespaceName)
+	ch := watcher.register(id)
+
+	newEndpointSlice := func(name, address string) *discoveryv1beta1.EndpointSlice {
+		return &discoveryv1beta1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: dummyNamespaceName,
+				Labels:    map[string]string{discoveryv1beta1.LabelServiceName: dummyProxlessName},
+			},
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1beta1.Endpoint{{Addresses: []string{address}}},
+		}
+	}
+
+	for _, endpointSlice := range []*discoveryv1beta1.EndpointSlice{
+		newEndpointSlice("slice-a", "10.0.0.1"), newEndpointSlice("slice-b", "10.0.0.2")} {
+		_, err := clientSet.DiscoveryV1beta1().EndpointSlices(dummyNamespaceName).Create(
+			context.TODO(), endpointSlice, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Errorf("runEndpointSlicesInformer(); the waiter has not been released")
+	}
+
+	// the endpoints of all the slices of the service are sent to the memory
+	time.Sleep(100 * time.Millisecond)
+	assert.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.2"}, memory.helper_getEndpoints(id))
+
+	assert.NoError(t, clientSet.DiscoveryV1beta1().EndpointSlices(dummyNamespaceName).Delete(
+		context.TODO(), "slice-a", metav1.DeleteOptions{}))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []string{"10.0.0.2"}, memory.helper_getEndpoints(id))
+}
@@ -0,0 +1,48 @@
+package kube
+
+import (
+	"context"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"kube-proxless/internal/logger"
+)
+
+const (
+	eventReasonScaledUp          = "ScaledUp"
+	eventReasonScaledDown        = "ScaledDown"
+	eventReasonWakeTimeout       = "WakeTimeout"
+	eventReasonDomainConflict    = "DomainConflict"
+	eventReasonAnnotationInvalid = "AnnotationInvalid"
+	eventReasonDeploymentUnknown = "DeploymentUnknown"
+)
+
+// the events are visible with `kubectl describe` on the deployments and services
+func newEventRecorder(clientSet kubernetes.Interface) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(logger.Debugf)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientSet.CoreV1().Events("")})
+
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "proxless"})
+}
+
+// the scaling events are recorded on the deployment and on the service of the route
+// the application teams usually describe the service - the deployment is an implementation detail
+func recordScaleEventf(
+	clientSet kubernetes.Interface, recorder record.EventRecorder,
+	deploy *appsv1.Deployment, serviceName, eventType, reason, messageFmt string, args ...interface{}) {
+	recorder.Eventf(deploy, eventType, reason, messageFmt, args...)
+
+	svc, err := clientSet.CoreV1().Services(deploy.Namespace).Get(context.TODO(), serviceName, metav1.GetOptions{})
+
+	if err != nil {
+		logger.Errorf(err, "Could not record the event %s on service %s.%s", reason, serviceName, deploy.Namespace)
+		return
+	}
+
+	recorder.Eventf(svc, eventType, reason, messageFmt, args...)
+}
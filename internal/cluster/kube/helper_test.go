@@ -5,12 +5,19 @@ import (
 	"errors"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/utils/pointer"
 	"kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/model"
+	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -115,6 +122,12 @@ func helper_createRandomService(t *testing.T, clientSet kubernetes.Interface) *c
 	return deploy
 }
 
+func helper_getService(t *testing.T, clientSet kubernetes.Interface, name string) *corev1.Service {
+	svc, err := clientSet.CoreV1().Services(dummyNamespaceName).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	return svc
+}
+
 func helper_updateService(t *testing.T, clientSet kubernetes.Interface, svc *corev1.Service) *corev1.Service {
 	svcUpdated, err := clientSet.CoreV1().Services(dummyNamespaceName).Update(
 		context.TODO(), svc, metav1.UpdateOptions{})
@@ -122,23 +135,224 @@ func helper_updateService(t *testing.T, clientSet kubernetes.Interface, svc *cor
 	return svcUpdated
 }
 
+// the informers call the memory from their own goroutine - read it with the getters
 type fakeMemory struct {
-	m map[string]string
+	m         map[string]string
+	replicas  map[string]int
+	endpoints map[string][]string
+	lock      sync.Mutex
 }
 
-func (s *fakeMemory) helper_upsertMemory(
-	id, name, port, deployName, namespace string, domains []string, isRunning bool, ttlSeconds, readinessTimeoutSeconds *int) error {
-	if deployName == "" {
+func (s *fakeMemory) helper_upsertMemory(route *model.Route) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if route.GetDeployment() == "" {
 		return errors.New("error upserting m")
 	}
-	s.m[id] = deployName
+	s.m[route.GetId()] = route.GetDeployment()
+	return nil
+}
+
+func (s *fakeMemory) helper_updateReplicasInMemory(deployName, namespace string, replicas, availableReplicas int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.replicas[utils.GenRouteId(deployName, namespace)] = replicas
+	return nil
+}
+
+func (s *fakeMemory) helper_updateEndpointsInMemory(id string, endpoints []string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.endpoints[id] = endpoints
 	return nil
 }
 
 func (s *fakeMemory) helper_deleteRouteFromMemory(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	if _, ok := s.m[id]; ok {
 		delete(s.m, id)
 		return nil
 	}
 	return errors.New("route not found")
 }
+
+// empty if the route is not in memory
+func (s *fakeMemory) helper_getDeployment(id string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.m[id]
+}
+
+func (s *fakeMemory) helper_countRoutes() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.m)
+}
+
+func (s *fakeMemory) helper_getReplicas(id string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.replicas[id]
+}
+
+func (s *fakeMemory) helper_getEndpoints(id string) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.endpoints[id]
+}
+
+func helper_isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func helper_createHPA(
+	t *testing.T, clientSet kubernetes.Interface, deployName string, minReplicas int32) *autoscalingv1.HorizontalPodAutoscaler {
+	dummyHPA := &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deployName,
+			Namespace: dummyNamespaceName,
+		},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: deployName},
+			MinReplicas:    pointer.Int32Ptr(minReplicas),
+			MaxReplicas:    10,
+		},
+	}
+	hpa, err := clientSet.AutoscalingV1().HorizontalPodAutoscalers(dummyNamespaceName).Create(
+		context.TODO(), dummyHPA, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	return hpa
+}
+
+func helper_getHPA(t *testing.T, clientSet kubernetes.Interface) *autoscalingv1.HorizontalPodAutoscaler {
+	hpa, err := clientSet.AutoscalingV1().HorizontalPodAutoscalers(dummyNamespaceName).Get(
+		context.TODO(), dummyProxlessName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	return hpa
+}
+
+func helper_newProxlessRoute(name, service string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "proxless.io/v1alpha1",
+		"kind":       "ProxlessRoute",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": dummyNamespaceName,
+		},
+		"spec": map[string]interface{}{
+			"domains":    []interface{}{"dummy.io"},
+			"service":    service,
+			"deployment": dummyProxlessName,
+			"port":       "http",
+			"ttlSeconds": int64(30),
+		},
+	}}
+}
+
+func helper_newIngress(name, service string, enabled bool) *networkingv1beta1.Ingress {
+	return &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: dummyNamespaceName,
+			Annotations: map[string]string{
+				utils.AnnotationEnabled:          strconv.FormatBool(enabled),
+				utils.AnnotationServiceDeployKey: dummyProxlessName,
+			},
+		},
+		Spec: networkingv1beta1.IngressSpec{
+			Rules: []networkingv1beta1.IngressRule{{
+				Host: "dummy.io",
+				IngressRuleValue: networkingv1beta1.IngressRuleValue{
+					HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+						Paths: []networkingv1beta1.HTTPIngressPath{{
+							Path: "/",
+							Backend: networkingv1beta1.IngressBackend{
+								ServiceName: service,
+								ServicePort: intstr.FromInt(80),
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func helper_newHTTPRoute(name, service string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": dummyNamespaceName,
+			"annotations": map[string]interface{}{
+				utils.AnnotationEnabled:          "true",
+				utils.AnnotationServiceDeployKey: dummyProxlessName,
+			},
+		},
+		"spec": map[string]interface{}{
+			"hostnames": []interface{}{"dummy.io"},
+			"rules": []interface{}{
+				map[string]interface{}{
+					"backendRefs": []interface{}{
+						map[string]interface{}{"name": service, "port": int64(80)},
+						map[string]interface{}{"kind": "Bucket", "name": "assets"},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func helper_createDeploymentWithTemplateLabels(
+	t *testing.T, clientSet kubernetes.Interface, name string, labels map[string]string) {
+	_, err := clientSet.AppsV1().Deployments(dummyNamespaceName).Create(context.TODO(), &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: dummyNamespaceName},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}},
+		},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+}
+
+func helper_updateNamespaceAnnotations(t *testing.T, clientSet kubernetes.Interface, annotations map[string]string) {
+	ns, err := clientSet.CoreV1().Namespaces().Get(context.TODO(), dummyNamespaceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	ns.Annotations = annotations
+	_, err = clientSet.CoreV1().Namespaces().Update(context.TODO(), ns, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+}
+
+func helper_newService(name string) *corev1.Service {
+	return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: dummyNamespaceName}}
+}
+
+func helper_assertServiceExists(t *testing.T, clientSet kubernetes.Interface, name string, exists bool) {
+	_, err := clientSet.CoreV1().Services(dummyNamespaceName).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.Equal(t, exists, err == nil)
+}
+
+func helper_newContainer(cpu, memory string) corev1.Container {
+	return corev1.Container{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		},
+	}
+}
@@ -0,0 +1,129 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+	"strconv"
+)
+
+// return nil if no HPA is targeting the deployment
+func getHPAForDeployment(
+	clientSet kubernetes.Interface, deployName, namespace string) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	hpas, err := clientSet.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(context.TODO(), metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hpas.Items {
+		ref := hpas.Items[i].Spec.ScaleTargetRef
+		if ref.Kind == "Deployment" && ref.Name == deployName {
+			return &hpas.Items[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func patchHPA(
+	clientSet kubernetes.Interface, name, namespace string, annotationValue interface{}, minReplicas int32) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				clusterutils.AnnotationHPAMinReplicas: annotationValue,
+			},
+		},
+		"spec": map[string]interface{}{
+			"minReplicas": minReplicas,
+		},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = clientSet.AutoscalingV1().HorizontalPodAutoscalers(namespace).Patch(
+		context.TODO(), name, k8stypes.MergePatchType, payloadBytes, metav1.PatchOptions{})
+
+	return err
+}
+
+// The HPA cannot go to zero and would fight proxless when it patches the replicas.
+// Record its `minReplicas` in an annotation and lower it to 1 -
+// the HPA does not do anything while the deployment has 0 replicas.
+func pauseHPA(clientSet kubernetes.Interface, deployName, namespace string) error {
+	hpa, err := getHPAForDeployment(clientSet, deployName, namespace)
+
+	if err != nil || hpa == nil {
+		return err
+	}
+
+	// already paused - don't override the original minReplicas
+	if metav1.HasAnnotation(hpa.ObjectMeta, clusterutils.AnnotationHPAMinReplicas) {
+		return nil
+	}
+
+	minReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+
+	err = patchHPA(clientSet, hpa.Name, namespace, strconv.Itoa(int(minReplicas)), 1)
+
+	if err == nil {
+		logger.Debugf("HPA %s.%s paused - minReplicas %d recorded", hpa.Name, namespace, minReplicas)
+	}
+
+	return err
+}
+
+// Restore the `minReplicas` recorded when the HPA has been paused
+// return the number of replicas the deployment must be scaled up to
+func resumeHPA(clientSet kubernetes.Interface, deployName, namespace string) (int, error) {
+	hpa, err := getHPAForDeployment(clientSet, deployName, namespace)
+
+	if err != nil || hpa == nil {
+		return 1, err
+	}
+
+	minReplicas := clusterutils.ParseStringToIntPointer(hpa.Annotations[clusterutils.AnnotationHPAMinReplicas])
+
+	if minReplicas == nil || *minReplicas < 1 {
+		// not paused by proxless
+		return 1, nil
+	}
+
+	err = patchHPA(clientSet, hpa.Name, namespace, nil, int32(*minReplicas))
+
+	if err != nil {
+		return 1, err
+	}
+
+	logger.Debugf("HPA %s.%s resumed - minReplicas %d restored", hpa.Name, namespace, *minReplicas)
+
+	return *minReplicas, nil
+}
+
+// number of replicas the deployment runs once scaled up - see `resumeHPA`
+// does not modify the HPA
+func getScaleUpReplicas(clientSet kubernetes.Interface, deployName, namespace string) (int, error) {
+	hpa, err := getHPAForDeployment(clientSet, deployName, namespace)
+
+	if err != nil || hpa == nil {
+		return 1, err
+	}
+
+	minReplicas := clusterutils.ParseStringToIntPointer(hpa.Annotations[clusterutils.AnnotationHPAMinReplicas])
+
+	if minReplicas == nil || *minReplicas < 1 {
+		return 1, nil
+	}
+
+	return *minReplicas, nil
+}
@@ -0,0 +1,95 @@
+package kube
+
+import (
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"testing"
+	"time"
+)
+
+func Test_getHPAForDeployment(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+
+	helper_createNamespace(t, clientSet)
+	helper_createHPA(t, clientSet, dummyNonProxlessName, 2)
+
+	hpa, err := getHPAForDeployment(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.Nil(t, hpa)
+
+	helper_createHPA(t, clientSet, dummyProxlessName, 2)
+
+	hpa, err = getHPAForDeployment(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	if assert.NotNil(t, hpa) {
+		assert.Equal(t, dummyProxlessName, hpa.Spec.ScaleTargetRef.Name)
+	}
+}
+
+func Test_pauseAndResumeHPA(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+
+	helper_createNamespace(t, clientSet)
+
+	// no HPA - nothing to do
+	assert.NoError(t, pauseHPA(clientSet, dummyProxlessName, dummyNamespaceName))
+	replicas, err := resumeHPA(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replicas)
+
+	helper_createHPA(t, clientSet, dummyProxlessName, 3)
+
+	// HPA not paused - nothing to restore
+	replicas, err = resumeHPA(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replicas)
+
+	assert.NoError(t, pauseHPA(clientSet, dummyProxlessName, dummyNamespaceName))
+	hpa := helper_getHPA(t, clientSet)
+	assert.Equal(t, int32(1), *hpa.Spec.MinReplicas)
+	assert.Equal(t, "3", hpa.Annotations[clusterutils.AnnotationHPAMinReplicas])
+
+	// pausing twice must not override the original minReplicas
+	assert.NoError(t, pauseHPA(clientSet, dummyProxlessName, dummyNamespaceName))
+	hpa = helper_getHPA(t, clientSet)
+	assert.Equal(t, "3", hpa.Annotations[clusterutils.AnnotationHPAMinReplicas])
+
+	replicas, err = resumeHPA(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, replicas)
+	hpa = helper_getHPA(t, clientSet)
+	assert.Equal(t, int32(3), *hpa.Spec.MinReplicas)
+	assert.False(t, metav1.HasAnnotation(hpa.ObjectMeta, clusterutils.AnnotationHPAMinReplicas))
+}
+
+func Test_scaleDeployment_WithHPA(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	watcher := newEndpointsWatcher()
+
+	helper_createNamespace(t, clientSet)
+	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
+	helper_createHPA(t, clientSet, dummyProxlessName, 2)
+	recorder := record.NewFakeRecorder(10)
+
+	// a deployment with 0 replicas is not scaled down - the HPA would not be paused
+	deploy.Spec.Replicas = pointer.Int32Ptr(1)
+	deploy = helper_updateDeployment(t, clientSet, deploy)
+
+	assert.NoError(t, scaleDownDeployment(clientSet, recorder, dummyProxlessName, "", dummyNamespaceName, time.Now()))
+	assert.Equal(t, int32(1), *helper_getHPA(t, clientSet).Spec.MinReplicas)
+
+	deploy.Status.AvailableReplicas = 1
+	helper_updateDeployment(t, clientSet, deploy)
+
+	// the deployment must be scaled up to the original minReplicas of the HPA
+	assert.NoError(t, scaleUpDeployment(clientSet, watcher, recorder, dummyProxlessName, "", dummyNamespaceName, 1, 1))
+	assert.Equal(t, int32(2), *helper_getHPA(t, clientSet).Spec.MinReplicas)
+
+	deploy, err := getDeployment(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), *deploy.Spec.Replicas)
+}
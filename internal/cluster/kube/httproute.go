@@ -0,0 +1,182 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+	"strconv"
+)
+
+var httpRouteResource = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+// the gateway api CRDs are not installed in every cluster
+func isHTTPRouteServed(clientSet kubernetes.Interface) bool {
+	resources, err := clientSet.Discovery().ServerResourcesForGroupVersion(httpRouteResource.GroupVersion().String())
+
+	if err != nil {
+		return false
+	}
+
+	for _, resource := range resources.APIResources {
+		if resource.Name == httpRouteResource.Resource {
+			return true
+		}
+	}
+
+	return false
+}
+
+// only the fields used by proxless - the gateway api types are not vendored
+type httpRoute struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              httpRouteSpec `json:"spec"`
+}
+
+type httpRouteSpec struct {
+	Hostnames []string        `json:"hostnames,omitempty"`
+	Rules     []httpRouteRule `json:"rules,omitempty"`
+}
+
+type httpRouteRule struct {
+	BackendRefs []httpRouteBackendRef `json:"backendRefs,omitempty"`
+}
+
+type httpRouteBackendRef struct {
+	Kind *string `json:"kind,omitempty"`
+	Name string  `json:"name"`
+	Port *int    `json:"port,omitempty"`
+}
+
+func parseHTTPRoute(obj interface{}) (*unstructured.Unstructured, *httpRoute, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil, errors.New(fmt.Sprintf("event for invalid object; got %T want *unstructured.Unstructured", obj))
+	}
+
+	hr := &httpRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, hr); err != nil {
+		return nil, nil, err
+	}
+
+	return u, hr, nil
+}
+
+// the backend refs can target other kinds than services
+func isServiceBackendRef(ref httpRouteBackendRef) bool {
+	return ref.Kind == nil || *ref.Kind == "Service"
+}
+
+// the hostnames of the route become the domains of all its services
+func getBackendsFromHTTPRoute(clientSet kubernetes.Interface, hr *httpRoute) map[string]*discoveredBackend {
+	backends := map[string]*discoveredBackend{}
+
+	for _, rule := range hr.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			if !isServiceBackendRef(ref) {
+				continue
+			}
+
+			service := getBackendServiceName(clientSet, hr.Namespace, ref.Name)
+			port := ""
+			if ref.Port != nil {
+				port = strconv.Itoa(*ref.Port)
+			}
+
+			addDiscoveredBackend(backends, service, port, "")
+
+			for _, hostname := range hr.Spec.Hostnames {
+				addDiscoveredBackend(backends, service, port, hostname)
+			}
+		}
+	}
+
+	return backends
+}
+
+// same as `syncIngressBackends` - the backend refs are updated in the unstructured object
+// the other fields of the route are kept as is
+func syncHTTPRouteBackends(clientSet kubernetes.Interface, dynamicClient dynamic.Interface, u *unstructured.Unstructured) {
+	u = u.DeepCopy()
+	_, hr, err := parseHTTPRoute(u)
+
+	if err != nil {
+		logger.Errorf(err, "Cannot process http route %s.%s", u.GetName(), u.GetNamespace())
+		return
+	}
+
+	rules, found, err := unstructured.NestedSlice(u.Object, "spec", "rules")
+
+	if err != nil || !found {
+		return
+	}
+
+	enabled := clusterutils.IsProxlessEnabled(hr.ObjectMeta)
+	backends := getBackendsFromHTTPRoute(clientSet, hr)
+	changed := false
+
+	for i, rule := range hr.Spec.Rules {
+		refs, ok := rules[i].(map[string]interface{})["backendRefs"].([]interface{})
+		if !ok {
+			continue
+		}
+
+		for j, ref := range rule.BackendRefs {
+			if !isServiceBackendRef(ref) {
+				continue
+			}
+
+			desired, ok := getBackendServiceName(clientSet, hr.Namespace, ref.Name), true
+
+			if enabled {
+				desired, ok = getDesiredBackendService(clientSet, hr.Annotations, hr.Namespace, backends, desired)
+			}
+
+			if ok && ref.Name != desired {
+				refs[j].(map[string]interface{})["name"] = desired
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	if err := unstructured.SetNestedSlice(u.Object, rules, "spec", "rules"); err != nil {
+		logger.Errorf(err, "Could not update the backends of http route %s.%s", hr.Name, hr.Namespace)
+		return
+	}
+
+	_, err = dynamicClient.Resource(httpRouteResource).Namespace(hr.Namespace).Update(context.TODO(), u, metav1.UpdateOptions{})
+
+	if err != nil {
+		logger.Errorf(err, "Could not update the backends of http route %s.%s", hr.Name, hr.Namespace)
+	} else {
+		logger.Debugf("Backends of http route %s.%s updated", hr.Name, hr.Namespace)
+	}
+}
+
+func syncHTTPRoutesBackends(clientSet kubernetes.Interface, dynamicClient dynamic.Interface, namespace string) {
+	routes, err := dynamicClient.Resource(httpRouteResource).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+
+	if err != nil {
+		logger.Errorf(err, "Could not list the http routes of namespace %s", namespace)
+		return
+	}
+
+	for i := range routes.Items {
+		syncHTTPRouteBackends(clientSet, dynamicClient, &routes.Items[i])
+	}
+}
@@ -0,0 +1,62 @@
+package kube
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"testing"
+)
+
+func Test_getBackendsFromHTTPRoute(t *testing.T) {
+	_, hr, err := parseHTTPRoute(helper_newHTTPRoute(dummyProxlessName, dummyNonProxlessName))
+	assert.NoError(t, err)
+
+	backends := getBackendsFromHTTPRoute(fake.NewSimpleClientset(), hr)
+
+	// the bucket backend is ignored
+	assert.Len(t, backends, 1)
+	assert.Equal(t,
+		&discoveredBackend{service: dummyNonProxlessName, port: "80", domains: []string{"dummy.io"}},
+		backends[dummyNonProxlessName])
+}
+
+func Test_syncHTTPRouteBackends(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	helper_createNamespace(t, clientSet)
+	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
+	_, err := createProxlessService(clientSet, helper_newService(dummyNonProxlessName), dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+
+	resource := dynamicClient.Resource(httpRouteResource).Namespace(dummyNamespaceName)
+	_, err = resource.Create(
+		context.TODO(), helper_newHTTPRoute(dummyProxlessName, dummyNonProxlessName), metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	getBackends := func() []string {
+		u, err := resource.Get(context.TODO(), dummyProxlessName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		rules, _, _ := unstructured.NestedSlice(u.Object, "spec", "rules")
+		var names []string
+		for _, ref := range rules[0].(map[string]interface{})["backendRefs"].([]interface{}) {
+			names = append(names, ref.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+
+	// the deployment is asleep
+	syncHTTPRoutesBackends(clientSet, dynamicClient, dummyNamespaceName)
+	assert.Equal(t, []string{clusterutils.GenServiceToAppName(dummyNonProxlessName), "assets"}, getBackends())
+
+	// the requests still go through proxless when the deployment is running
+	deploy.Spec.Replicas = pointer.Int32Ptr(1)
+	helper_updateDeployment(t, clientSet, deploy)
+	syncHTTPRoutesBackends(clientSet, dynamicClient, dummyNamespaceName)
+	assert.Equal(t, []string{clusterutils.GenServiceToAppName(dummyNonProxlessName), "assets"}, getBackends())
+}
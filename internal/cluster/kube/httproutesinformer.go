@@ -0,0 +1,110 @@
+package kube
+
+import (
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+	"time"
+)
+
+// same as `runIngressesInformer` for the gateway api http routes
+func runHTTPRoutesInformer(
+	clientSet kubernetes.Interface,
+	dynamicClient dynamic.Interface,
+	namespaceScope, proxlessService, proxlessNamespace string,
+	informerResyncInterval int,
+	upsertMemory func(route *model.Route) error,
+	deleteRouteFromMemory func(id string) error,
+	stopCh <-chan struct{},
+) {
+	namespaceScoped := namespaceScope != ""
+	informer := dynamicinformer.NewFilteredDynamicSharedInformerFactory(
+		dynamicClient, time.Duration(informerResyncInterval)*time.Second, namespaceScope, nil).
+		ForResource(httpRouteResource).Informer()
+
+	eventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			u, hr, err := parseHTTPRoute(obj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process http route in AddFunc handler")
+				return
+			}
+
+			if !clusterutils.IsProxlessEnabled(hr.ObjectMeta) {
+				return
+			}
+
+			logger.Debugf("Add http route handler - %s.%s", hr.Name, hr.Namespace)
+			addDiscoveredBackendsToMemory(
+				clientSet, "http route", hr.Name, hr.Namespace, hr.Annotations, getBackendsFromHTTPRoute(clientSet, hr),
+				namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
+			syncHTTPRouteBackends(clientSet, dynamicClient, u)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			_, oldHr, err := parseHTTPRoute(oldObj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process http route in UpdateFunc handler")
+				return
+			}
+
+			u, newHr, err := parseHTTPRoute(newObj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process http route in UpdateFunc handler")
+				return
+			}
+
+			oldEnabled := clusterutils.IsProxlessEnabled(oldHr.ObjectMeta)
+			newEnabled := clusterutils.IsProxlessEnabled(newHr.ObjectMeta)
+
+			if !oldEnabled && !newEnabled {
+				return
+			}
+
+			logger.Debugf("Update http route handler - %s.%s", newHr.Name, newHr.Namespace)
+
+			newBackends := getBackendsFromHTTPRoute(clientSet, newHr)
+			if !newEnabled {
+				newBackends = map[string]*discoveredBackend{}
+			}
+
+			if oldEnabled {
+				removeDiscoveredBackendsFromMemory(
+					clientSet, oldHr.Namespace, getBackendsFromHTTPRoute(clientSet, oldHr), newBackends, deleteRouteFromMemory)
+			}
+
+			if newEnabled {
+				addDiscoveredBackendsToMemory(
+					clientSet, "http route", newHr.Name, newHr.Namespace, newHr.Annotations, newBackends,
+					namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
+			}
+
+			syncHTTPRouteBackends(clientSet, dynamicClient, u)
+		},
+		DeleteFunc: func(obj interface{}) {
+			_, hr, err := parseHTTPRoute(obj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process http route in DeleteFunc handler")
+				return
+			}
+
+			if !clusterutils.IsProxlessEnabled(hr.ObjectMeta) {
+				return
+			}
+
+			logger.Debugf("Remove http route handler - %s.%s", hr.Name, hr.Namespace)
+			removeDiscoveredBackendsFromMemory(
+				clientSet, hr.Namespace, getBackendsFromHTTPRoute(clientSet, hr), map[string]*discoveredBackend{}, deleteRouteFromMemory)
+		},
+	}
+	informer.AddEventHandler(eventHandler)
+
+	informer.Run(stopCh)
+}
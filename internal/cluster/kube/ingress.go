@@ -0,0 +1,113 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+)
+
+func parseIngress(obj interface{}) (*networkingv1beta1.Ingress, error) {
+	ing, ok := obj.(*networkingv1beta1.Ingress)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("event for invalid object; got %T want *networking.Ingress", obj))
+	}
+	return ing, nil
+}
+
+// the hosts of the rules become the domains of the services
+// the paths are out of scope - the routes are per domain
+func getBackendsFromIngress(clientSet kubernetes.Interface, ing *networkingv1beta1.Ingress) map[string]*discoveredBackend {
+	backends := map[string]*discoveredBackend{}
+
+	if ing.Spec.Backend != nil {
+		addDiscoveredBackend(backends, getBackendServiceName(clientSet, ing.Namespace, ing.Spec.Backend.ServiceName), ing.Spec.Backend.ServicePort.String(), "")
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+
+		for _, path := range rule.HTTP.Paths {
+			addDiscoveredBackend(
+				backends, getBackendServiceName(clientSet, ing.Namespace, path.Backend.ServiceName),
+				path.Backend.ServicePort.String(), rule.Host)
+		}
+	}
+
+	return backends
+}
+
+// return all the backends of the ingress so that they can be updated in place
+func getIngressBackendRefs(ing *networkingv1beta1.Ingress) []*networkingv1beta1.IngressBackend {
+	var refs []*networkingv1beta1.IngressBackend
+
+	if ing.Spec.Backend != nil {
+		refs = append(refs, ing.Spec.Backend)
+	}
+
+	for i := range ing.Spec.Rules {
+		if ing.Spec.Rules[i].HTTP == nil {
+			continue
+		}
+
+		for j := range ing.Spec.Rules[i].HTTP.Paths {
+			refs = append(refs, &ing.Spec.Rules[i].HTTP.Paths[j].Backend)
+		}
+	}
+
+	return refs
+}
+
+// point the backends at the proxless service so that the requests always go through proxless
+// the original services are restored if proxless is disabled
+func syncIngressBackends(clientSet kubernetes.Interface, ing *networkingv1beta1.Ingress) {
+	enabled := clusterutils.IsProxlessEnabled(ing.ObjectMeta)
+	backends := getBackendsFromIngress(clientSet, ing)
+	ing = ing.DeepCopy()
+	changed := false
+
+	for _, ref := range getIngressBackendRefs(ing) {
+		service := getBackendServiceName(clientSet, ing.Namespace, ref.ServiceName)
+		desired, ok := service, true
+
+		if enabled {
+			desired, ok = getDesiredBackendService(clientSet, ing.Annotations, ing.Namespace, backends, service)
+		}
+
+		if ok && ref.ServiceName != desired {
+			ref.ServiceName = desired
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	_, err := clientSet.NetworkingV1beta1().Ingresses(ing.Namespace).Update(context.TODO(), ing, metav1.UpdateOptions{})
+
+	if err != nil {
+		logger.Errorf(err, "Could not update the backends of ingress %s.%s", ing.Name, ing.Namespace)
+	} else {
+		logger.Debugf("Backends of ingress %s.%s updated", ing.Name, ing.Namespace)
+	}
+}
+
+func syncIngressesBackends(clientSet kubernetes.Interface, namespace string) {
+	ingresses, err := clientSet.NetworkingV1beta1().Ingresses(namespace).List(context.TODO(), metav1.ListOptions{})
+
+	if err != nil {
+		logger.Errorf(err, "Could not list the ingresses of namespace %s", namespace)
+		return
+	}
+
+	for i := range ingresses.Items {
+		syncIngressBackends(clientSet, &ingresses.Items[i])
+	}
+}
@@ -0,0 +1,108 @@
+package kube
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"testing"
+	"time"
+)
+
+func Test_getBackendsFromIngress(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	_, err := createProxlessService(clientSet, helper_newService("svc-a"), dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+
+	ing := helper_newIngress(dummyProxlessName, "svc-a", true)
+	ing.Spec.Backend = &networkingv1beta1.IngressBackend{ServiceName: "svc-a", ServicePort: intstr.FromInt(80)}
+	ing.Spec.Rules = append(ing.Spec.Rules,
+		networkingv1beta1.IngressRule{Host: "other.io"},
+		networkingv1beta1.IngressRule{
+			Host: "other.io",
+			IngressRuleValue: networkingv1beta1.IngressRuleValue{
+				HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+					Paths: []networkingv1beta1.HTTPIngressPath{
+						{Backend: networkingv1beta1.IngressBackend{ServiceName: "svc-a-proxless", ServicePort: intstr.FromInt(80)}},
+						{Backend: networkingv1beta1.IngressBackend{ServiceName: "svc-b", ServicePort: intstr.FromString("http")}},
+					},
+				},
+			},
+		})
+
+	backends := getBackendsFromIngress(clientSet, ing)
+
+	assert.Len(t, backends, 2)
+	assert.Equal(t, &discoveredBackend{service: "svc-a", port: "80", domains: []string{"dummy.io", "other.io"}}, backends["svc-a"])
+	assert.Equal(t, &discoveredBackend{service: "svc-b", port: "http", domains: []string{"other.io"}}, backends["svc-b"])
+}
+
+func Test_syncIngressBackends(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
+	_, err := createProxlessService(clientSet, helper_newService(dummyNonProxlessName), dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+
+	getBackend := func() string {
+		ing, err := clientSet.NetworkingV1beta1().Ingresses(dummyNamespaceName).Get(
+			context.TODO(), dummyProxlessName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		return ing.Spec.Rules[0].HTTP.Paths[0].Backend.ServiceName
+	}
+
+	ing, err := clientSet.NetworkingV1beta1().Ingresses(dummyNamespaceName).Create(
+		context.TODO(), helper_newIngress(dummyProxlessName, dummyNonProxlessName, true), metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// the deployment is asleep
+	syncIngressBackends(clientSet, ing)
+	assert.Equal(t, clusterutils.GenServiceToAppName(dummyNonProxlessName), getBackend())
+
+	// the requests still go through proxless when the deployment is running
+	deploy.Spec.Replicas = pointer.Int32Ptr(1)
+	helper_updateDeployment(t, clientSet, deploy)
+	syncIngressesBackends(clientSet, dummyNamespaceName)
+	assert.Equal(t, clusterutils.GenServiceToAppName(dummyNonProxlessName), getBackend())
+
+	// the original service is restored when proxless is disabled
+	ing = helper_newIngress(dummyProxlessName, clusterutils.GenServiceToAppName(dummyNonProxlessName), false)
+	_, err = clientSet.NetworkingV1beta1().Ingresses(dummyNamespaceName).Update(context.TODO(), ing, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	syncIngressesBackends(clientSet, dummyNamespaceName)
+	assert.Equal(t, dummyNonProxlessName, getBackend())
+}
+
+func Test_runIngressesInformer(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	helper_createProxlessCompatibleDeployment(t, clientSet)
+	helper_createRandomService(t, clientSet)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	memory := fakeMemory{m: map[string]string{}, replicas: map[string]int{}, endpoints: map[string][]string{}}
+	go runIngressesInformer(
+		clientSet, dummyNamespaceName, dummyProxlessName, dummyNamespaceName, 60,
+		memory.helper_upsertMemory, memory.helper_deleteRouteFromMemory, func() {}, stopCh)
+
+	ingresses := clientSet.NetworkingV1beta1().Ingresses(dummyNamespaceName)
+	_, err := ingresses.Create(
+		context.TODO(), helper_newIngress(dummyProxlessName, dummyNonProxlessName, true), metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	id := clusterutils.GenRouteId(dummyNonProxlessName, dummyNamespaceName)
+	assert.Equal(t, dummyProxlessName, memory.helper_getDeployment(id))
+	helper_assertServiceExists(t, clientSet, clusterutils.GenServiceToAppName(dummyNonProxlessName), true)
+
+	assert.NoError(t, ingresses.Delete(context.TODO(), dummyProxlessName, metav1.DeleteOptions{}))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, memory.helper_countRoutes())
+	helper_assertServiceExists(t, clientSet, clusterutils.GenServiceToAppName(dummyNonProxlessName), false)
+}
@@ -0,0 +1,114 @@
+package kube
+
+import (
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+	"time"
+)
+
+func runIngressesInformer(
+	clientSet kubernetes.Interface,
+	namespaceScope, proxlessService, proxlessNamespace string,
+	informerResyncInterval int,
+	upsertMemory func(route *model.Route) error,
+	deleteRouteFromMemory func(id string) error,
+	onSynced func(),
+	stopCh <-chan struct{},
+) {
+	namespaceScoped := namespaceScope != ""
+	opts := make([]informers.SharedInformerOption, 0)
+	if namespaceScoped {
+		opts = append(opts, informers.WithNamespace(namespaceScope))
+	}
+	informer := informers.
+		NewSharedInformerFactoryWithOptions(clientSet, time.Duration(informerResyncInterval)*time.Second, opts...).
+		Networking().V1beta1().Ingresses().Informer()
+
+	eventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ing, err := parseIngress(obj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process ingress in AddFunc handler")
+				return
+			}
+
+			if !clusterutils.IsProxlessEnabled(ing.ObjectMeta) {
+				return
+			}
+
+			logger.Debugf("Add ingress handler - %s.%s", ing.Name, ing.Namespace)
+			addDiscoveredBackendsToMemory(
+				clientSet, "ingress", ing.Name, ing.Namespace, ing.Annotations, getBackendsFromIngress(clientSet, ing),
+				namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
+			syncIngressBackends(clientSet, ing)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldIng, err := parseIngress(oldObj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process ingress in UpdateFunc handler")
+				return
+			}
+
+			newIng, err := parseIngress(newObj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process ingress in UpdateFunc handler")
+				return
+			}
+
+			oldEnabled := clusterutils.IsProxlessEnabled(oldIng.ObjectMeta)
+			newEnabled := clusterutils.IsProxlessEnabled(newIng.ObjectMeta)
+
+			if !oldEnabled && !newEnabled {
+				return
+			}
+
+			logger.Debugf("Update ingress handler - %s.%s", newIng.Name, newIng.Namespace)
+
+			newBackends := getBackendsFromIngress(clientSet, newIng)
+			if !newEnabled {
+				newBackends = map[string]*discoveredBackend{}
+			}
+
+			if oldEnabled {
+				removeDiscoveredBackendsFromMemory(
+					clientSet, oldIng.Namespace, getBackendsFromIngress(clientSet, oldIng), newBackends, deleteRouteFromMemory)
+			}
+
+			if newEnabled {
+				addDiscoveredBackendsToMemory(
+					clientSet, "ingress", newIng.Name, newIng.Namespace, newIng.Annotations, newBackends,
+					namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
+			}
+
+			syncIngressBackends(clientSet, newIng)
+		},
+		DeleteFunc: func(obj interface{}) {
+			ing, err := parseIngress(obj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process ingress in DeleteFunc handler")
+				return
+			}
+
+			if !clusterutils.IsProxlessEnabled(ing.ObjectMeta) {
+				return
+			}
+
+			logger.Debugf("Remove ingress handler - %s.%s", ing.Name, ing.Namespace)
+			removeDiscoveredBackendsFromMemory(
+				clientSet, ing.Namespace, getBackendsFromIngress(clientSet, ing), map[string]*discoveredBackend{}, deleteRouteFromMemory)
+		},
+	}
+	informer.AddEventHandler(eventHandler)
+
+	go notifySynced(informer, onSynced, stopCh)
+
+	informer.Run(stopCh)
+}
@@ -1,50 +1,231 @@
 package kube
 
 import (
+	"context"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 	"kube-proxless/internal/cluster"
+	clusterutils "kube-proxless/internal/cluster/utils"
 	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+	"time"
 )
 
 type kubeCluster struct {
-	clientSet                      kubernetes.Interface
-	servicesInformerResyncInterval int
+	clientSet                       kubernetes.Interface
+	dynamicClient                   dynamic.Interface // only used by the proxless routes and the http routes
+	servicesInformerResyncInterval  int
+	deploymentReadinessPollInterval int
+	endpointsWatcher                *endpointsWatcher
+	watchEndpoints                  bool
+	namespaceOptIn                  bool // services of the namespaces annotated with `proxless/enabled`
+	eventRecorder                   record.EventRecorder
+	dryRunActions                   *dryRunActions // nil if dry-run is disabled
 }
 
-func NewCluster(clientSet kubernetes.Interface, servicesInformerResyncInterval int) cluster.Interface {
+func NewCluster(
+	clientSet kubernetes.Interface, dynamicClient dynamic.Interface, servicesInformerResyncInterval, deploymentReadinessPollInterval int,
+	watchEndpoints, namespaceOptIn, dryRun bool) cluster.Interface {
+	var actions *dryRunActions
+	if dryRun {
+		actions = newDryRunActions()
+		clientSet = newDryRunClientSet(clientSet, actions)
+
+		if dynamicClient != nil {
+			dynamicClient = newDryRunDynamicClient(dynamicClient, actions)
+		}
+	}
+
 	return &kubeCluster{
-		clientSet:                      clientSet,
-		servicesInformerResyncInterval: servicesInformerResyncInterval,
+		clientSet:                       clientSet,
+		dynamicClient:                   dynamicClient,
+		servicesInformerResyncInterval:  servicesInformerResyncInterval,
+		deploymentReadinessPollInterval: deploymentReadinessPollInterval,
+		endpointsWatcher:                newEndpointsWatcher(),
+		watchEndpoints:                  watchEndpoints,
+		namespaceOptIn:                  namespaceOptIn,
+		eventRecorder:                   newEventRecorder(clientSet),
+		dryRunActions:                   actions,
 	}
 }
 
 func NewKubeClient(kubeConfigPath string) kubernetes.Interface {
+	return kubernetes.NewForConfigOrDie(buildKubeConfig(kubeConfigPath))
+}
+
+func NewDynamicClient(kubeConfigPath string) dynamic.Interface {
+	return dynamic.NewForConfigOrDie(buildKubeConfig(kubeConfigPath))
+}
+
+func buildKubeConfig(kubeConfigPath string) *rest.Config {
 	// use the current context in kubeconfig
 	kubeConf, err := clientcmd.BuildConfigFromFlags("", kubeConfigPath)
 	if err != nil {
 		logger.Panicf(err, "Could not find kubeconfig file at %s", kubeConfigPath)
 	}
 
-	return kubernetes.NewForConfigOrDie(kubeConf)
+	return kubeConf
+}
+
+// the deployment of a route can be a comma separated list of deployments - see `proxless/wake-all-deployments`
+func (k *kubeCluster) ScaleUpDeployment(deploymentName, serviceName, namespace string, timeout int) error {
+	return forEachDeployment(deploymentName, func(name string) error {
+		return scaleUpDeployment(
+			k.clientSet, k.endpointsWatcher, k.eventRecorder,
+			name, serviceName, namespace, timeout, k.deploymentReadinessPollInterval)
+	})
+}
+
+func (k *kubeCluster) ScaleDownDeployment(deploymentName, serviceName, namespace string, lastUsed time.Time) error {
+	return forEachDeployment(deploymentName, func(name string) error {
+		return scaleDownDeployment(k.clientSet, k.eventRecorder, name, serviceName, namespace, lastUsed)
+	})
+}
+
+func (k *kubeCluster) PersistLastUsed(deploymentName, namespace string, lastUsed time.Time) error {
+	return forEachDeployment(deploymentName, func(name string) error {
+		return persistLastUsedInDeployment(k.clientSet, name, namespace, lastUsed)
+	})
+}
+
+func (k *kubeCluster) DeploymentExists(name, namespace string) (bool, error) {
+	for _, deployName := range clusterutils.SplitDeployments(name) {
+		if exists, err := deploymentExists(k.clientSet, deployName, namespace); !exists || err != nil {
+			return exists, err
+		}
+	}
+
+	return true, nil
+}
+
+func (k *kubeCluster) GetDeploymentResourceRequests(name, namespace string) (float64, float64, error) {
+	cpu, memory := 0.0, 0.0
+
+	for _, deployName := range clusterutils.SplitDeployments(name) {
+		deployCPU, deployMemory, err := getDeploymentResourceRequests(k.clientSet, deployName, namespace)
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		cpu += deployCPU
+		memory += deployMemory
+	}
+
+	return cpu, memory, nil
 }
 
-func (k *kubeCluster) ScaleUpDeployment(name, namespace string, timeout int) error {
-	return scaleUpDeployment(k.clientSet, name, namespace, timeout)
+func (k *kubeCluster) DeleteOrphanProxlessServices(namespaceScope string) []error {
+	return deleteOrphanProxlessServices(k.clientSet, namespaceScope)
 }
 
-func (k *kubeCluster) ScaleDownDeployment(deploymentName, namespace string) error {
-	return scaleDownDeployment(k.clientSet, deploymentName, namespace)
+func (k *kubeCluster) GetDryRunActions() []cluster.DryRunActionReport {
+	if k.dryRunActions == nil {
+		return []cluster.DryRunActionReport{}
+	}
+
+	return k.dryRunActions.get()
 }
 
 func (k *kubeCluster) RunServicesEngine(
+	ctx context.Context,
 	namespaceScope, proxlessService, proxlessNamespace string,
-	upsertMemory func(
-		id, name, port, deployName, namespace string, domains []string, isRunning bool, ttlSeconds, readinessTimeoutSeconds *int) error,
+	upsertMemory func(route *model.Route) error,
 	deleteRouteFromMemory func(id string) error,
+	updateReplicasInMemory func(deployName, namespace string, replicas, availableReplicas int) error,
+	updateEndpointsInMemory func(id string, endpoints []string) error,
+	onSynced func(),
 ) {
+	stopCh := ctx.Done()
+
+	go runDeploymentsInformer(
+		k.clientSet, namespaceScope, k.servicesInformerResyncInterval, updateReplicasInMemory, stopCh)
+
+	// the endpoint slices release the requests waiting for a deployment to be scaled up
+	// they are only sent to the memory if the requests are forwarded to the endpoints
+	updateEndpoints := updateEndpointsInMemory
+	if !k.watchEndpoints {
+		updateEndpoints = func(id string, endpoints []string) error { return nil }
+	}
+
+	go runEndpointSlicesInformer(
+		k.clientSet, namespaceScope, k.servicesInformerResyncInterval, k.endpointsWatcher, updateEndpoints, stopCh)
+
+	var namespaceLister listerscorev1.NamespaceLister
+	if k.namespaceOptIn && namespaceScope != "" {
+		logger.Warnf(nil, "The namespace opt-in is not supported when proxless is namespace scoped - ignoring")
+	} else if k.namespaceOptIn {
+		namespacesInformer := informers.NewSharedInformerFactory(
+			k.clientSet, time.Duration(k.servicesInformerResyncInterval)*time.Second).Core().V1().Namespaces()
+		namespaceLister = namespacesInformer.Lister()
+
+		go runNamespacesInformer(
+			k.clientSet, k.eventRecorder, namespacesInformer.Informer(), proxlessService, proxlessNamespace,
+			upsertMemory, deleteRouteFromMemory, stopCh)
+
+		// the services must not be processed before the namespaces are known
+		cache.WaitForCacheSync(stopCh, namespacesInformer.Informer().HasSynced)
+	}
+
 	runServicesInformer(
-		k.clientSet, namespaceScope, proxlessService, proxlessNamespace, k.servicesInformerResyncInterval,
-		upsertMemory, deleteRouteFromMemory)
+		k.clientSet, k.eventRecorder, namespaceLister, namespaceScope, proxlessService, proxlessNamespace,
+		k.servicesInformerResyncInterval, upsertMemory, deleteRouteFromMemory, onSynced, stopCh)
+}
+
+func (k *kubeCluster) RunProxlessRoutesEngine(
+	ctx context.Context,
+	namespaceScope, proxlessService, proxlessNamespace string,
+	upsertMemory func(route *model.Route) error,
+	deleteRouteFromMemory func(id string) error,
+	getRouteFromMemory func(id string) (*model.Route, error),
+	onSynced func(),
+) {
+	if k.dynamicClient == nil {
+		logger.Errorf(nil, "Cannot run the proxless routes engine without dynamic client")
+		return
+	}
+
+	stopCh := ctx.Done()
+
+	runProxlessRoutesInformer(
+		k.clientSet, k.dynamicClient, namespaceScope, proxlessService, proxlessNamespace,
+		k.servicesInformerResyncInterval, upsertMemory, deleteRouteFromMemory, getRouteFromMemory, onSynced, stopCh)
+}
+
+func (k *kubeCluster) RunDiscoveryEngine(
+	ctx context.Context,
+	namespaceScope, proxlessService, proxlessNamespace string,
+	upsertMemory func(route *model.Route) error,
+	deleteRouteFromMemory func(id string) error,
+	onSynced func(),
+) {
+	stopCh := ctx.Done()
+
+	if k.isHTTPRouteEnabled() {
+		go runHTTPRoutesInformer(
+			k.clientSet, k.dynamicClient, namespaceScope, proxlessService, proxlessNamespace,
+			k.servicesInformerResyncInterval, upsertMemory, deleteRouteFromMemory, stopCh)
+	}
+
+	runIngressesInformer(
+		k.clientSet, namespaceScope, proxlessService, proxlessNamespace,
+		k.servicesInformerResyncInterval, upsertMemory, deleteRouteFromMemory, onSynced, stopCh)
+}
+
+// call `onSynced` once the initial list of the informer is processed - the routes are in memory
+func notifySynced(informer cache.SharedIndexInformer, onSynced func(), stopCh <-chan struct{}) {
+	if cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		onSynced()
+	}
+}
+
+func (k *kubeCluster) isHTTPRouteEnabled() bool {
+	return k.dynamicClient != nil && isHTTPRouteServed(k.clientSet)
 }
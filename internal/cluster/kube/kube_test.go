@@ -13,36 +13,36 @@ import (
 
 func TestClusterClient_ScaleUpDeployment(t *testing.T) {
 	clientSet := fake.NewSimpleClientset()
-	client := NewCluster(clientSet, 2)
+	client := NewCluster(clientSet, nil, 2, 1, false, false, false)
 
 	timeout := 1
 
 	// error - deployment is not in kubernetes
-	assert.Error(t, client.ScaleUpDeployment(dummyProxlessName, dummyNamespaceName, timeout))
+	assert.Error(t, client.ScaleUpDeployment(dummyProxlessName, dummyProxlessName, dummyNamespaceName, timeout))
 
 	helper_createNamespace(t, clientSet)
 	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
 
 	// error - deployment in kubernetes but not available
-	assert.Error(t, client.ScaleUpDeployment(dummyProxlessName, dummyNamespaceName, timeout))
+	assert.Error(t, client.ScaleUpDeployment(dummyProxlessName, dummyProxlessName, dummyNamespaceName, timeout))
 
 	deploy.Status.AvailableReplicas = 1
 	helper_updateDeployment(t, clientSet, deploy)
 
 	// no error - deployment in kubernetes and available
-	assert.NoError(t, client.ScaleUpDeployment(dummyProxlessName, dummyNamespaceName, timeout))
+	assert.NoError(t, client.ScaleUpDeployment(dummyProxlessName, dummyProxlessName, dummyNamespaceName, timeout))
 }
 
 func TestClusterClient_ScaleDownDeployments(t *testing.T) {
 	clientSet := fake.NewSimpleClientset()
-	client := NewCluster(clientSet, 2)
+	client := NewCluster(clientSet, nil, 2, 1, false, false, false)
 
 	helper_createNamespace(t, clientSet)
 	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
 	randomDeployCreated := helper_createRandomDeployment(t, clientSet) // this deployment must not be scaled down
 
 	// no error - deployment in kubernetes and scaled down
-	assert.NoError(t, client.ScaleDownDeployment(deploy.Name, deploy.Namespace))
+	assert.NoError(t, client.ScaleDownDeployment(deploy.Name, dummyProxlessName, deploy.Namespace, time.Now()))
 
 	deploy.Spec.Replicas = pointer.Int32Ptr(1)
 	helper_updateDeployment(t, clientSet, deploy)
@@ -59,24 +59,33 @@ func TestClusterClient_ScaleDownDeployments(t *testing.T) {
 func TestClusterClient_RunServicesEngine(t *testing.T) {
 	clientSet := fake.NewSimpleClientset()
 	servicesInformerResyncInterval := 2
-	client := NewCluster(clientSet, servicesInformerResyncInterval)
+	client := NewCluster(clientSet, nil, servicesInformerResyncInterval, 1, false, false, false)
 
-	memory := fakeMemory{m: map[string]string{}}
+	memory := fakeMemory{m: map[string]string{}, replicas: map[string]int{}, endpoints: map[string][]string{}}
 
 	helper_createNamespace(t, clientSet)
 	helper_createProxlessCompatibleDeployment(t, clientSet)
 
-	// TODO check how we wanna deal with closing the channel and stopping the routine
-	// We could use a context https://github.com/kubernetes/client-go/blob/master/examples/fake-client/main_test.go
-	// but not sure if it is worth it
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	synced := make(chan struct{})
+
 	go client.RunServicesEngine(
+		ctx,
 		dummyNamespaceName, dummyProxlessName, dummyProxlessName,
-		memory.helper_upsertMemory, memory.helper_deleteRouteFromMemory)
+		memory.helper_upsertMemory, memory.helper_deleteRouteFromMemory, memory.helper_updateReplicasInMemory,
+		memory.helper_updateEndpointsInMemory, func() { close(synced) })
+
+	select {
+	case <-synced:
+	case <-time.After(1 * time.Second):
+		t.Errorf("RunServicesEngine(); onSynced not called")
+	}
 
 	// don't add random services in memory
 	helper_createRandomService(t, clientSet)
 	time.Sleep(1 * time.Second)
-	if len(memory.m) > 0 {
+	if memory.helper_countRoutes() > 0 {
 		t.Errorf("RunServicesEngine(); must not add random service information into memory")
 	}
 
@@ -84,7 +93,7 @@ func TestClusterClient_RunServicesEngine(t *testing.T) {
 	service := helper_createProxlessCompatibleService(t, clientSet)
 	time.Sleep(1 * time.Second)
 	id := clusterutils.GenRouteId(service.Name, service.Namespace)
-	if _, ok := memory.m[id]; !ok {
+	if memory.helper_getDeployment(id) == "" {
 		t.Errorf("RunServicesEngine(); service not added in memory")
 	}
 	_, err :=
@@ -101,7 +110,7 @@ func TestClusterClient_RunServicesEngine(t *testing.T) {
 	service.Annotations = map[string]string{}
 	helper_updateService(t, clientSet, service)
 	time.Sleep(1 * time.Second)
-	if len(memory.m) > 0 {
+	if memory.helper_countRoutes() > 0 {
 		t.Errorf("RunServicesEngine(); the service must be removed from the memory")
 	}
 	_, err =
@@ -119,7 +128,7 @@ func TestClusterClient_RunServicesEngine(t *testing.T) {
 	_ = clientSet.CoreV1().Services(dummyNamespaceName).Delete(
 		context.TODO(), dummyProxlessName, v1.DeleteOptions{})
 	time.Sleep(1 * time.Second)
-	if len(memory.m) > 0 {
+	if memory.helper_countRoutes() > 0 {
 		t.Errorf("RunServicesEngine(); the service must be removed from the memory")
 	}
 	_, err =
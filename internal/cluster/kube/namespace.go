@@ -0,0 +1,106 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+)
+
+// annotations of the namespace used as default values by its services
+var namespaceDefaultAnnotations = []string{
+	clusterutils.AnnotationServiceTTLSeconds,
+	clusterutils.AnnotationServiceReadinessTimeoutSeconds,
+	clusterutils.AnnotationServiceMinUptimeSeconds,
+	clusterutils.AnnotationServicePinnedUntil,
+}
+
+func parseNamespace(obj interface{}) (*corev1.Namespace, error) {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("event for invalid object; got %T want *core.Namespace", obj))
+	}
+	return ns, nil
+}
+
+// return a copy of the service with the annotations inherited from the namespace
+// the service is returned as is if its namespace is not annotated with `proxless/enabled`
+func applyNamespaceOptIn(clientSet kubernetes.Interface, ns *corev1.Namespace, svc *corev1.Service) *corev1.Service {
+	if ns == nil || !clusterutils.IsProxlessEnabled(ns.ObjectMeta) {
+		return svc
+	}
+
+	// the services created by proxless must not be managed
+	if isProxlessService(svc) || svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return svc
+	}
+
+	deployName := ""
+
+	// the services that are not annotated are only managed if they target a single deployment
+	if !clusterutils.IsAnnotationsProxlessCompatible(svc.ObjectMeta) {
+		deployNames, err := getDeploymentsForService(clientSet, svc)
+
+		if err != nil {
+			logger.Errorf(err, "Error retrieving the deployments of service %s.%s", svc.Name, svc.Namespace)
+			return svc
+		}
+
+		if len(deployNames) != 1 {
+			logger.Debugf("Service %s.%s matches %d deployments - skipping", svc.Name, svc.Namespace, len(deployNames))
+			return svc
+		}
+
+		deployName = deployNames[0]
+	}
+
+	svc = svc.DeepCopy()
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+
+	if deployName != "" {
+		svc.Annotations[clusterutils.AnnotationServiceDeployKey] = deployName
+	}
+
+	for _, annotation := range namespaceDefaultAnnotations {
+		if _, ok := svc.Annotations[annotation]; !ok && ns.Annotations[annotation] != "" {
+			svc.Annotations[annotation] = ns.Annotations[annotation]
+		}
+	}
+
+	return svc
+}
+
+// the namespace lister is nil if the namespace opt-in is disabled
+func applyNamespaceOptInFromLister(
+	clientSet kubernetes.Interface, lister listerscorev1.NamespaceLister, svc *corev1.Service) *corev1.Service {
+	if lister == nil {
+		return svc
+	}
+
+	ns, err := lister.Get(svc.Namespace)
+
+	if err != nil {
+		return svc
+	}
+
+	return applyNamespaceOptIn(clientSet, ns, svc)
+}
+
+func isNamespaceOptInChanged(oldNs, newNs *corev1.Namespace) bool {
+	if clusterutils.IsProxlessEnabled(oldNs.ObjectMeta) != clusterutils.IsProxlessEnabled(newNs.ObjectMeta) {
+		return true
+	}
+
+	for _, annotation := range namespaceDefaultAnnotations {
+		if oldNs.Annotations[annotation] != newNs.Annotations[annotation] {
+			return true
+		}
+	}
+
+	return false
+}
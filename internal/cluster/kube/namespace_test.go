@@ -0,0 +1,87 @@
+package kube
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"testing"
+	"time"
+)
+
+func Test_applyNamespaceOptIn(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	helper_createDeploymentWithTemplateLabels(t, clientSet, "deploy-a", map[string]string{"app": "a"})
+	helper_createDeploymentWithTemplateLabels(t, clientSet, "deploy-b1", map[string]string{"app": "b", "v": "1"})
+	helper_createDeploymentWithTemplateLabels(t, clientSet, "deploy-b2", map[string]string{"app": "b", "v": "2"})
+
+	enabledNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: dummyNamespaceName, Annotations: map[string]string{
+		clusterutils.AnnotationEnabled:           "true",
+		clusterutils.AnnotationServiceTTLSeconds: "120",
+	}}}
+	disabledNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: dummyNamespaceName}}
+
+	newSvc := func(selector, annotations map[string]string, svcType corev1.ServiceType) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: dummyProxlessName, Namespace: dummyNamespaceName, Annotations: annotations},
+			Spec:       corev1.ServiceSpec{Selector: selector, Type: svcType},
+		}
+	}
+
+	testCases := []struct {
+		ns               *corev1.Namespace
+		svc              *corev1.Service
+		deployNameWanted string
+		ttlSecondsWanted string
+	}{
+		{disabledNs, newSvc(map[string]string{"app": "a"}, nil, ""), "", ""},
+		{enabledNs, newSvc(map[string]string{"app": "a"}, nil, ""), "deploy-a", "120"},
+		{enabledNs, newSvc(map[string]string{"app": "a"},
+			map[string]string{clusterutils.AnnotationServiceTTLSeconds: "30"}, ""), "deploy-a", "30"},
+		{enabledNs, newSvc(map[string]string{"app": "b"}, nil, ""), "", ""}, // multiple deployments
+		{enabledNs, newSvc(map[string]string{"app": "c"}, nil, ""), "", ""}, // no deployment
+		{enabledNs, newSvc(nil, nil, ""), "", ""},                           // no selector
+		{enabledNs, newSvc(map[string]string{"app": "a"}, nil, corev1.ServiceTypeExternalName), "", ""},
+		{enabledNs, newSvc(map[string]string{"app": "a"},
+			map[string]string{clusterutils.AnnotationServiceDeployKey: "deploy-z"}, ""), "deploy-z", "120"},
+	}
+
+	for i, tc := range testCases {
+		svc := applyNamespaceOptIn(clientSet, tc.ns, tc.svc)
+		assert.Equal(t, tc.deployNameWanted, svc.Annotations[clusterutils.AnnotationServiceDeployKey], i)
+		assert.Equal(t, tc.ttlSecondsWanted, svc.Annotations[clusterutils.AnnotationServiceTTLSeconds], i)
+	}
+}
+
+func Test_runNamespacesInformer(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	helper_createDeploymentWithTemplateLabels(t, clientSet, dummyProxlessName, map[string]string{"app": "a"})
+	_, err := clientSet.CoreV1().Services(dummyNamespaceName).Create(context.TODO(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: dummyProxlessName, Namespace: dummyNamespaceName},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "a"}},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	memory := fakeMemory{m: map[string]string{}, replicas: map[string]int{}, endpoints: map[string][]string{}}
+	informer := informers.NewSharedInformerFactory(clientSet, time.Minute).Core().V1().Namespaces().Informer()
+	go runNamespacesInformer(
+		clientSet, newEventRecorder(clientSet), informer, dummyProxlessName, dummyNamespaceName,
+		memory.helper_upsertMemory, memory.helper_deleteRouteFromMemory, stopCh)
+	time.Sleep(100 * time.Millisecond)
+
+	id := clusterutils.GenRouteId(dummyProxlessName, dummyNamespaceName)
+	helper_updateNamespaceAnnotations(t, clientSet, map[string]string{clusterutils.AnnotationEnabled: "true"})
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, dummyProxlessName, memory.helper_getDeployment(id))
+
+	helper_updateNamespaceAnnotations(t, clientSet, nil)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, memory.helper_countRoutes())
+}
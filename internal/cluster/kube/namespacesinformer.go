@@ -0,0 +1,64 @@
+package kube
+
+import (
+	"context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+)
+
+// the services of a namespace are re-evaluated when its proxless annotations change
+// the informer is shared with the services informer which reads the namespaces from its lister
+func runNamespacesInformer(
+	clientSet kubernetes.Interface,
+	recorder record.EventRecorder,
+	informer cache.SharedIndexInformer,
+	proxlessService, proxlessNamespace string,
+	upsertMemory func(route *model.Route) error,
+	deleteRouteFromMemory func(id string) error,
+	stopCh <-chan struct{},
+) {
+	eventHandler := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNs, err := parseNamespace(oldObj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process namespace in UpdateFunc handler")
+				return
+			}
+
+			newNs, err := parseNamespace(newObj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process namespace in UpdateFunc handler")
+				return
+			}
+
+			if !isNamespaceOptInChanged(oldNs, newNs) {
+				return
+			}
+
+			logger.Debugf("Update namespace handler - %s", newNs.Name)
+
+			services, err := clientSet.CoreV1().Services(newNs.Name).List(context.TODO(), metav1.ListOptions{})
+
+			if err != nil {
+				logger.Errorf(err, "Cannot list the services of namespace %s", newNs.Name)
+				return
+			}
+
+			for i := range services.Items {
+				svc := &services.Items[i]
+				updateServiceMemory(
+					clientSet, recorder, applyNamespaceOptIn(clientSet, oldNs, svc), applyNamespaceOptIn(clientSet, newNs, svc),
+					false, proxlessService, proxlessNamespace, upsertMemory, deleteRouteFromMemory)
+			}
+		},
+	}
+	informer.AddEventHandler(eventHandler)
+
+	informer.Run(stopCh)
+}
@@ -0,0 +1,234 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+	"time"
+)
+
+var proxlessRouteResource = schema.GroupVersionResource{
+	Group:    "proxless.io",
+	Version:  "v1alpha1",
+	Resource: "proxlessroutes",
+}
+
+// the CRD is handled with the dynamic client - no need to generate a typed clientset for a single resource
+type proxlessRoute struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              proxlessRouteSpec   `json:"spec"`
+	Status            proxlessRouteStatus `json:"status,omitempty"`
+}
+
+type proxlessRouteSpec struct {
+	Domains                 []string            `json:"domains,omitempty"`
+	Service                 string              `json:"service"`
+	Deployment              string              `json:"deployment"`
+	Port                    *intstr.IntOrString `json:"port,omitempty"`
+	Ports                   []proxlessRoutePort `json:"ports,omitempty"` // the domains routed to another port than `port`
+	TTLSeconds              *int                `json:"ttlSeconds,omitempty"`
+	ReadinessTimeoutSeconds *int                `json:"readinessTimeoutSeconds,omitempty"`
+	MinUptimeSeconds        *int                `json:"minUptimeSeconds,omitempty"`
+	PinnedUntil             *metav1.Time        `json:"pinnedUntil,omitempty"`
+}
+
+// same as `domain:port` in the `proxless/domains` annotation
+type proxlessRoutePort struct {
+	Port    intstr.IntOrString `json:"port"`
+	Domains []string           `json:"domains"`
+}
+
+type proxlessRouteStatus struct {
+	Active   bool     `json:"active"`
+	Running  bool     `json:"running"`
+	LastUsed string   `json:"lastUsed,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+func parseProxlessRoute(obj interface{}) (*proxlessRoute, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("event for invalid object; got %T want *unstructured.Unstructured", obj))
+	}
+
+	pr := &proxlessRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, pr); err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+func validateProxlessRoute(pr *proxlessRoute) []error {
+	var errs []error
+
+	if pr.Spec.Service == "" {
+		errs = append(errs, errors.New("spec.service must not be empty"))
+	}
+
+	if pr.Spec.Deployment == "" {
+		errs = append(errs, errors.New("spec.deployment must not be empty"))
+	}
+
+	if pr.Spec.TTLSeconds != nil && *pr.Spec.TTLSeconds <= 0 {
+		errs = append(errs, errors.New("spec.ttlSeconds must be a positive integer"))
+	}
+
+	if pr.Spec.ReadinessTimeoutSeconds != nil && *pr.Spec.ReadinessTimeoutSeconds <= 0 {
+		errs = append(errs, errors.New("spec.readinessTimeoutSeconds must be a positive integer"))
+	}
+
+	if pr.Spec.MinUptimeSeconds != nil && *pr.Spec.MinUptimeSeconds <= 0 {
+		errs = append(errs, errors.New("spec.minUptimeSeconds must be a positive integer"))
+	}
+
+	for i, port := range pr.Spec.Ports {
+		if len(port.Domains) == 0 {
+			errs = append(errs, errors.New(fmt.Sprintf("spec.ports[%d].domains must not be empty", i)))
+		}
+	}
+
+	return errs
+}
+
+// same as discovery - the route of an annotated service is owned by the services engine
+// both would have the same id otherwise
+func isServiceAnnotated(clientSet kubernetes.Interface, name, namespace string) bool {
+	svc, err := clientSet.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+
+	return err == nil && clusterutils.IsAnnotationsProxlessCompatible(svc.ObjectMeta)
+}
+
+func genProxlessRouteId(pr *proxlessRoute) string {
+	return clusterutils.GenRouteId(pr.Spec.Service, pr.Namespace)
+}
+
+// same as `addServiceToMemory` - the route is built from the spec instead of the annotations
+// return whether the route is in memory and the errors to write in the status
+func addProxlessRouteToMemory(
+	clientSet kubernetes.Interface, pr *proxlessRoute, namespaceScoped bool,
+	proxlessSvc, proxlessNamespace string,
+	upsertMemory func(route *model.Route) error,
+) (bool, []error) {
+	errs := validateProxlessRoute(pr)
+
+	if len(errs) > 0 {
+		return false, errs
+	}
+
+	if isServiceAnnotated(clientSet, pr.Spec.Service, pr.Namespace) {
+		return false, []error{errors.New(fmt.Sprintf(
+			"service %s is already managed by its proxless annotations", pr.Spec.Service))}
+	}
+
+	portRef := ""
+	if pr.Spec.Port != nil {
+		portRef = pr.Spec.Port.String()
+	}
+
+	domainsPortsRefs := map[string]string{}
+	for _, port := range pr.Spec.Ports {
+		for _, domain := range port.Domains {
+			domainsPortsRefs[domain] = port.Port.String()
+		}
+	}
+
+	route, errs := genRouteFromService(
+		clientSet, pr.Spec.Service, pr.Namespace, pr.Spec.Deployment, portRef, pr.Spec.Domains, domainsPortsRefs,
+		pr.Spec.TTLSeconds, pr.Spec.ReadinessTimeoutSeconds, namespaceScoped, proxlessSvc, proxlessNamespace)
+
+	if route == nil {
+		return false, errs
+	}
+
+	route.SetMinUptimeSeconds(pr.Spec.MinUptimeSeconds)
+	if pr.Spec.PinnedUntil != nil {
+		route.SetPinnedUntil(pr.Spec.PinnedUntil.Time)
+	}
+
+	if err := upsertMemory(route); err != nil {
+		logger.Errorf(err, "Error adding proxless route %s.%s into memory", pr.Name, pr.Namespace)
+		return false, append(errs, err)
+	}
+
+	logger.Debugf("Proxless route %s.%s added into memory", pr.Name, pr.Namespace)
+
+	return true, errs
+}
+
+func removeProxlessRouteFromMemory(
+	clientSet kubernetes.Interface, pr *proxlessRoute, deleteRouteFromMemory func(id string) error) {
+	// the route was never added - it must not remove the one of the annotated service
+	if pr.Spec.Service == "" || isServiceAnnotated(clientSet, pr.Spec.Service, pr.Namespace) {
+		return
+	}
+
+	_ = deleteProxlessService(clientSet, pr.Spec.Service, pr.Namespace)
+
+	if err := deleteRouteFromMemory(genProxlessRouteId(pr)); err != nil {
+		logger.Errorf(err, "Error removing proxless route %s.%s from memory", pr.Name, pr.Namespace)
+	} else {
+		logger.Debugf("Proxless route %s.%s removed from memory", pr.Name, pr.Namespace)
+	}
+}
+
+func genProxlessRouteStatus(active bool, route *model.Route, errs []error) proxlessRouteStatus {
+	status := proxlessRouteStatus{Active: active}
+
+	if route != nil {
+		status.Running = route.GetIsRunning()
+		status.LastUsed = route.GetLastUsed().UTC().Format(time.RFC3339)
+	}
+
+	for _, err := range errs {
+		status.Errors = append(status.Errors, err.Error())
+	}
+
+	return status
+}
+
+// the status subresource is only patched if it changed
+// otherwise each patch would trigger the informer which would patch the status again
+func updateProxlessRouteStatus(dynamicClient dynamic.Interface, pr *proxlessRoute, status proxlessRouteStatus) {
+	newStatus, err := json.Marshal(status)
+
+	if err != nil {
+		logger.Errorf(err, "Could not generate the status of proxless route %s.%s", pr.Name, pr.Namespace)
+		return
+	}
+
+	if oldStatus, err := json.Marshal(pr.Status); err == nil && string(oldStatus) == string(newStatus) {
+		return
+	}
+
+	// replace the whole status - a merge patch would not remove the errors that are fixed
+	payloadBytes, err := json.Marshal([]map[string]interface{}{{
+		"op":    "add",
+		"path":  "/status",
+		"value": status,
+	}})
+
+	if err != nil {
+		logger.Errorf(err, "Could not generate the status of proxless route %s.%s", pr.Name, pr.Namespace)
+		return
+	}
+
+	_, err = dynamicClient.Resource(proxlessRouteResource).Namespace(pr.Namespace).Patch(
+		context.TODO(), pr.Name, k8stypes.JSONPatchType, payloadBytes, metav1.PatchOptions{}, "status")
+
+	if err != nil {
+		logger.Errorf(err, "Could not update the status of proxless route %s.%s", pr.Name, pr.Namespace)
+	}
+}
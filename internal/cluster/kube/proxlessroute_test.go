@@ -0,0 +1,213 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/model"
+	"testing"
+	"time"
+)
+
+func Test_parseProxlessRoute(t *testing.T) {
+	pr, err := parseProxlessRoute(helper_newProxlessRoute(dummyProxlessName, dummyProxlessName))
+	assert.NoError(t, err)
+	assert.Equal(t, dummyProxlessName, pr.Name)
+	assert.Equal(t, dummyProxlessName, pr.Spec.Service)
+	assert.Equal(t, []string{"dummy.io"}, pr.Spec.Domains)
+	assert.Equal(t, 30, *pr.Spec.TTLSeconds)
+	assert.Equal(t, "http", pr.Spec.Port.String())
+
+	_, err = parseProxlessRoute(&corev1.Service{})
+	assert.Error(t, err)
+}
+
+func Test_validateProxlessRoute(t *testing.T) {
+	zero, negative := 0, -1
+
+	testCases := []struct {
+		spec       proxlessRouteSpec
+		errsWanted int
+	}{
+		{proxlessRouteSpec{Service: "svc", Deployment: "deploy"}, 0},
+		{proxlessRouteSpec{}, 2},
+		{proxlessRouteSpec{
+			Service: "svc", Deployment: "deploy",
+			TTLSeconds: &zero, ReadinessTimeoutSeconds: &negative}, 2},
+		{proxlessRouteSpec{Service: "svc", Deployment: "deploy", MinUptimeSeconds: &zero}, 1},
+	}
+
+	for _, tc := range testCases {
+		assert.Len(t, validateProxlessRoute(&proxlessRoute{Spec: tc.spec}), tc.errsWanted)
+	}
+}
+
+func Test_genProxlessRouteStatus(t *testing.T) {
+	route, err := model.NewRoute(
+		"id", "svc", "", "deploy", "ns", []string{"example.io"}, true, nil, nil)
+	assert.NoError(t, err)
+	lastUsed := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	route.SetLastUsed(lastUsed)
+
+	assert.Equal(t,
+		proxlessRouteStatus{Active: true, Running: true, LastUsed: "2020-01-01T00:00:00Z"},
+		genProxlessRouteStatus(true, route, nil))
+	assert.Equal(t,
+		proxlessRouteStatus{Errors: []string{"invalid"}},
+		genProxlessRouteStatus(false, nil, []error{errors.New("invalid")}))
+}
+
+func Test_addProxlessRouteToMemory(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	helper_createProxlessCompatibleDeployment(t, clientSet)
+
+	memory := fakeMemory{m: map[string]string{}, replicas: map[string]int{}, endpoints: map[string][]string{}}
+	pr, err := parseProxlessRoute(helper_newProxlessRoute(dummyProxlessName, dummyProxlessName))
+	assert.NoError(t, err)
+
+	// the service does not exist
+	active, errs := addProxlessRouteToMemory(
+		clientSet, pr, true, dummyProxlessName, dummyNamespaceName, memory.helper_upsertMemory)
+	assert.False(t, active)
+	assert.Len(t, errs, 1)
+
+	_, err = clientSet.CoreV1().Services(dummyNamespaceName).Create(context.TODO(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: dummyProxlessName, Namespace: dummyNamespaceName},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+		}},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	active, errs = addProxlessRouteToMemory(
+		clientSet, pr, true, dummyProxlessName, dummyNamespaceName, memory.helper_upsertMemory)
+	assert.True(t, active)
+	assert.Len(t, errs, 0)
+	assert.Equal(t, dummyProxlessName, memory.helper_getDeployment(clusterutils.GenRouteId(dummyProxlessName, dummyNamespaceName)))
+
+	// the proxless service must have been created
+	_, err = clientSet.CoreV1().Services(dummyNamespaceName).Get(
+		context.TODO(), clusterutils.GenServiceToAppName(dummyProxlessName), metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	removeProxlessRouteFromMemory(clientSet, pr, memory.helper_deleteRouteFromMemory)
+	assert.Equal(t, 0, memory.helper_countRoutes())
+}
+
+// the route of an annotated service is owned by the services engine
+func Test_addProxlessRouteToMemory_AnnotatedService(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	service := helper_createProxlessCompatibleService(t, clientSet)
+
+	id := clusterutils.GenRouteId(service.Name, service.Namespace)
+	memory := fakeMemory{m: map[string]string{id: "annotated"}, replicas: map[string]int{}, endpoints: map[string][]string{}}
+	pr, err := parseProxlessRoute(helper_newProxlessRoute(dummyProxlessName, service.Name))
+	assert.NoError(t, err)
+
+	active, errs := addProxlessRouteToMemory(
+		clientSet, pr, true, dummyProxlessName, dummyNamespaceName, memory.helper_upsertMemory)
+	assert.False(t, active)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "annotated", memory.helper_getDeployment(id))
+
+	// deleting the proxless route must not remove the route of the annotated service
+	removeProxlessRouteFromMemory(clientSet, pr, memory.helper_deleteRouteFromMemory)
+	assert.Equal(t, "annotated", memory.helper_getDeployment(id))
+}
+
+func Test_addProxlessRouteToMemory_Ports(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	helper_createProxlessCompatibleDeployment(t, clientSet)
+
+	_, err := clientSet.CoreV1().Services(dummyNamespaceName).Create(context.TODO(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: dummyProxlessName, Namespace: dummyNamespaceName},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+			{Name: "admin", Port: 81, TargetPort: intstr.FromInt(9090)},
+		}},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	pr, err := parseProxlessRoute(helper_newProxlessRoute(dummyProxlessName, dummyProxlessName))
+	assert.NoError(t, err)
+	pr.Spec.Ports = []proxlessRoutePort{
+		{Port: intstr.FromString("admin"), Domains: []string{"admin.dummy.io"}},
+		{Port: intstr.FromInt(82), Domains: []string{"unknown.dummy.io"}},
+	}
+
+	var route *model.Route
+	upsertMemory := func(r *model.Route) error {
+		route = r
+		return nil
+	}
+
+	active, errs := addProxlessRouteToMemory(
+		clientSet, pr, true, dummyProxlessName, dummyNamespaceName, upsertMemory)
+	assert.True(t, active)
+	// the unknown port is reported and its domain uses the default port
+	assert.Len(t, errs, 1)
+
+	if assert.NotNil(t, route) {
+		assert.Equal(t, "8080", route.GetPortByDomain("dummy.io"))
+		assert.Equal(t, "9090", route.GetPortByDomain("admin.dummy.io"))
+		assert.Equal(t, "8080", route.GetPortByDomain("unknown.dummy.io"))
+		assert.Contains(t, route.GetDomains(), "admin.dummy.io")
+	}
+
+	// the domains of a port must not be empty
+	pr.Spec.Ports = []proxlessRoutePort{{Port: intstr.FromString("admin")}}
+	active, errs = addProxlessRouteToMemory(
+		clientSet, pr, true, dummyProxlessName, dummyNamespaceName, upsertMemory)
+	assert.False(t, active)
+	assert.Len(t, errs, 1)
+}
+
+func Test_runProxlessRoutesInformer(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	helper_createNamespace(t, clientSet)
+	helper_createProxlessCompatibleDeployment(t, clientSet)
+	helper_createRandomService(t, clientSet)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	memory := fakeMemory{m: map[string]string{}, replicas: map[string]int{}, endpoints: map[string][]string{}}
+	getRouteFromMemory := func(id string) (*model.Route, error) {
+		return model.NewRoute(id, dummyNonProxlessName, "", memory.helper_getDeployment(id), dummyNamespaceName, []string{"dummy.io"}, true, nil, nil)
+	}
+	go runProxlessRoutesInformer(
+		clientSet, dynamicClient, dummyNamespaceName, dummyProxlessName, dummyNamespaceName, 60,
+		memory.helper_upsertMemory, memory.helper_deleteRouteFromMemory, getRouteFromMemory, func() {}, stopCh)
+
+	resource := dynamicClient.Resource(proxlessRouteResource).Namespace(dummyNamespaceName)
+	_, err := resource.Create(
+		context.TODO(), helper_newProxlessRoute(dummyProxlessName, dummyNonProxlessName), metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	id := clusterutils.GenRouteId(dummyNonProxlessName, dummyNamespaceName)
+	assert.Equal(t, dummyProxlessName, memory.helper_getDeployment(id))
+
+	// the status must have been written
+	u, err := resource.Get(context.TODO(), dummyProxlessName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	pr, err := parseProxlessRoute(u)
+	assert.NoError(t, err)
+	assert.True(t, pr.Status.Active)
+	assert.True(t, pr.Status.Running)
+	assert.Len(t, pr.Status.Errors, 1) // port `http` not found in the service
+
+	assert.NoError(t, resource.Delete(context.TODO(), dummyProxlessName, metav1.DeleteOptions{}))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, memory.helper_countRoutes())
+}
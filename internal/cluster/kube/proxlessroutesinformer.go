@@ -0,0 +1,95 @@
+package kube
+
+import (
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+	"time"
+)
+
+func runProxlessRoutesInformer(
+	clientSet kubernetes.Interface,
+	dynamicClient dynamic.Interface,
+	namespaceScope, proxlessService, proxlessNamespace string,
+	informerResyncInterval int,
+	upsertMemory func(route *model.Route) error,
+	deleteRouteFromMemory func(id string) error,
+	getRouteFromMemory func(id string) (*model.Route, error),
+	onSynced func(),
+	stopCh <-chan struct{},
+) {
+	namespaceScoped := namespaceScope != ""
+	informer := dynamicinformer.NewFilteredDynamicSharedInformerFactory(
+		dynamicClient, time.Duration(informerResyncInterval)*time.Second, namespaceScope, nil).
+		ForResource(proxlessRouteResource).Informer()
+
+	// the resync also refreshes the `running` and `lastUsed` fields of the status
+	upsert := func(pr *proxlessRoute) {
+		active, errs := addProxlessRouteToMemory(
+			clientSet, pr, namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
+
+		var route *model.Route
+		if active {
+			route, _ = getRouteFromMemory(genProxlessRouteId(pr))
+		}
+
+		updateProxlessRouteStatus(dynamicClient, pr, genProxlessRouteStatus(active, route, errs))
+	}
+
+	eventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pr, err := parseProxlessRoute(obj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process proxless route in AddFunc handler")
+				return
+			}
+
+			logger.Debugf("Add proxless route handler - %s.%s", pr.Name, pr.Namespace)
+			upsert(pr)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPr, err := parseProxlessRoute(oldObj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process proxless route in UpdateFunc handler")
+				return
+			}
+
+			newPr, err := parseProxlessRoute(newObj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process proxless route in UpdateFunc handler")
+				return
+			}
+
+			logger.Debugf("Update proxless route handler - %s.%s", newPr.Name, newPr.Namespace)
+
+			// the route id is based on the service - the old route must be removed if it changed
+			if oldPr.Spec.Service != newPr.Spec.Service {
+				removeProxlessRouteFromMemory(clientSet, oldPr, deleteRouteFromMemory)
+			}
+
+			upsert(newPr)
+		},
+		DeleteFunc: func(obj interface{}) {
+			pr, err := parseProxlessRoute(obj)
+
+			if err != nil {
+				logger.Errorf(err, "Cannot process proxless route in DeleteFunc handler")
+				return
+			}
+
+			logger.Debugf("Remove proxless route handler - %s.%s", pr.Name, pr.Namespace)
+			removeProxlessRouteFromMemory(clientSet, pr, deleteRouteFromMemory)
+		},
+	}
+	informer.AddEventHandler(eventHandler)
+
+	go notifySynced(informer, onSynced, stopCh)
+
+	informer.Run(stopCh)
+}
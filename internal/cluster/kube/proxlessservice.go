@@ -0,0 +1,157 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+	"strings"
+)
+
+const (
+	proxlessServiceOwnerAnnotation = "owner"
+	proxlessServiceOwnerValue      = "proxless"
+)
+
+// the services `[service]-proxless` created by proxless for the internal connections
+func isProxlessService(svc *corev1.Service) bool {
+	return svc.Annotations[proxlessServiceOwnerAnnotation] == proxlessServiceOwnerValue
+}
+
+// the proxless service is garbage collected by kubernetes when the application service is deleted
+func genProxlessService(appSvc *corev1.Service, proxlessSvc, proxlessNs string) *corev1.Service {
+	isController := true
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        clusterutils.GenServiceToAppName(appSvc.Name),
+			Namespace:   appSvc.Namespace,
+			Annotations: map[string]string{proxlessServiceOwnerAnnotation: proxlessServiceOwnerValue},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Service",
+				Name:       appSvc.Name,
+				UID:        appSvc.UID,
+				Controller: &isController,
+			}},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: fmt.Sprintf("%s.%s.svc.cluster.local", proxlessSvc, proxlessNs),
+		},
+	}
+}
+
+func isProxlessServiceDrifted(existing, desired *corev1.Service) bool {
+	if existing.Spec.Type != desired.Spec.Type || existing.Spec.ExternalName != desired.Spec.ExternalName {
+		return true
+	}
+
+	for _, ref := range existing.OwnerReferences {
+		if ref.UID == desired.OwnerReferences[0].UID {
+			return false
+		}
+	}
+
+	return true
+}
+
+func createProxlessService(
+	clientSet kubernetes.Interface, appSvc *corev1.Service, proxlessSvc, proxlessNs string) (*corev1.Service, error) {
+	desired := genProxlessService(appSvc, proxlessSvc, proxlessNs)
+	svc, err := clientSet.CoreV1().Services(appSvc.Namespace).Create(context.TODO(), desired, metav1.CreateOptions{})
+
+	if !k8serrors.IsAlreadyExists(err) {
+		return svc, err
+	}
+
+	existing, err := clientSet.CoreV1().Services(appSvc.Namespace).Get(context.TODO(), desired.Name, metav1.GetOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// never take over a service proxless did not create
+	if !isProxlessService(existing) {
+		return nil, errors.New(fmt.Sprintf(
+			"service %s already exists and is not managed by proxless", desired.Name))
+	}
+
+	if !isProxlessServiceDrifted(existing, desired) {
+		return existing, nil
+	}
+
+	// e.g. proxless has been moved to another namespace or the application service has been recreated
+	logger.Warnf(nil, "Proxless service %s.%s drifted - updating it", desired.Name, desired.Namespace)
+
+	existing = existing.DeepCopy()
+	existing.OwnerReferences = desired.OwnerReferences
+	existing.Spec.Type = desired.Spec.Type
+	existing.Spec.ExternalName = desired.Spec.ExternalName
+	existing.Spec.ClusterIP = ""
+	existing.Spec.Ports = nil
+	existing.Spec.Selector = nil
+
+	return clientSet.CoreV1().Services(appSvc.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{})
+}
+
+// the service is only deleted if it has been created by proxless
+func deleteProxlessService(clientSet kubernetes.Interface, appSvc, appNs string) error {
+	name := clusterutils.GenServiceToAppName(appSvc)
+	svc, err := clientSet.CoreV1().Services(appNs).Get(context.TODO(), name, metav1.GetOptions{})
+
+	if err != nil {
+		return err
+	}
+
+	if !isProxlessService(svc) {
+		return errors.New(fmt.Sprintf("service %s is not managed by proxless", name))
+	}
+
+	return clientSet.CoreV1().Services(appNs).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// delete the proxless services whose application service does not exist anymore
+// the owner references already handle it - this is for the services created by older versions or if the
+// application service was deleted before its owner reference was set
+func deleteOrphanProxlessServices(clientSet kubernetes.Interface, namespaceScope string) []error {
+	var errs []error
+
+	services, err := clientSet.CoreV1().Services(namespaceScope).List(context.TODO(), metav1.ListOptions{})
+
+	if err != nil {
+		return append(errs, err)
+	}
+
+	existing := map[string]bool{}
+	for _, svc := range services.Items {
+		existing[svc.Namespace+"/"+svc.Name] = true
+	}
+
+	suffix := clusterutils.GenServiceToAppName("")
+
+	for _, svc := range services.Items {
+		if !isProxlessService(&svc) || !strings.HasSuffix(svc.Name, suffix) {
+			continue
+		}
+
+		if existing[svc.Namespace+"/"+strings.TrimSuffix(svc.Name, suffix)] {
+			continue
+		}
+
+		err := clientSet.CoreV1().Services(svc.Namespace).Delete(context.TODO(), svc.Name, metav1.DeleteOptions{})
+
+		if err != nil && !k8serrors.IsNotFound(err) {
+			errs = append(errs, err)
+		} else {
+			logger.Infof("Orphan proxless service %s.%s deleted", svc.Name, svc.Namespace)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,70 @@
+package kube
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"testing"
+)
+
+func Test_createProxlessService(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	appSvc := helper_newService(dummyNonProxlessName)
+	appSvc.UID = k8stypes.UID("uid-1")
+	name := clusterutils.GenServiceToAppName(dummyNonProxlessName)
+
+	svc, err := createProxlessService(clientSet, appSvc, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.Equal(t, "dummy-proxless.dummy-namespace.svc.cluster.local", svc.Spec.ExternalName)
+	assert.Equal(t, appSvc.UID, svc.OwnerReferences[0].UID)
+
+	// already up to date
+	_, err = createProxlessService(clientSet, appSvc, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+
+	// proxless moved to another namespace and the service has been recreated
+	appSvc.UID = k8stypes.UID("uid-2")
+	_, err = createProxlessService(clientSet, appSvc, dummyProxlessName, "other")
+	assert.NoError(t, err)
+
+	svc = helper_getService(t, clientSet, name)
+	assert.Equal(t, "dummy-proxless.other.svc.cluster.local", svc.Spec.ExternalName)
+	assert.Len(t, svc.OwnerReferences, 1)
+	assert.Equal(t, appSvc.UID, svc.OwnerReferences[0].UID)
+
+	// a service not created by proxless must not be taken over
+	svc.Annotations = nil
+	helper_updateService(t, clientSet, svc)
+	_, err = createProxlessService(clientSet, appSvc, dummyProxlessName, dummyNamespaceName)
+	assert.Error(t, err)
+	assert.Error(t, deleteProxlessService(clientSet, dummyNonProxlessName, dummyNamespaceName))
+	helper_assertServiceExists(t, clientSet, name, true)
+}
+
+func Test_deleteOrphanProxlessServices(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	helper_createRandomService(t, clientSet)
+
+	_, err := createProxlessService(clientSet, helper_newService(dummyNonProxlessName), dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	_, err = createProxlessService(clientSet, helper_newService("deleted"), dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+
+	// not created by proxless
+	_, err = clientSet.CoreV1().Services(dummyNamespaceName).Create(context.TODO(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "user-proxless", Namespace: dummyNamespaceName},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	assert.Len(t, deleteOrphanProxlessServices(clientSet, dummyNamespaceName), 0)
+
+	helper_assertServiceExists(t, clientSet, clusterutils.GenServiceToAppName(dummyNonProxlessName), true)
+	helper_assertServiceExists(t, clientSet, clusterutils.GenServiceToAppName("deleted"), false)
+	helper_assertServiceExists(t, clientSet, "user-proxless", true)
+}
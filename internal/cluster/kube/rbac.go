@@ -0,0 +1,131 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type permission struct {
+	group       string
+	resource    string
+	subresource string
+	verbs       []string
+	// namespaces are not namespaced - always checked cluster wide
+	clusterScoped bool
+}
+
+// the permissions of the role and the cluster role of the helm chart used by the enabled features
+// the `coordination.k8s.io` leases are not checked - proxless does not use them
+// there is no leader election between the replicas
+func getRequiredPermissions(proxlessRoutes, discovery, namespaceOptIn bool) []permission {
+	permissions := []permission{
+		{group: "", resource: "services", verbs: []string{"get", "list", "watch", "create", "delete", "patch", "update"}},
+		{group: "apps", resource: "deployments", verbs: []string{"get", "list", "watch", "patch", "update"}},
+		{group: "autoscaling", resource: "horizontalpodautoscalers", verbs: []string{"get", "list", "patch"}},
+		{group: "", resource: "events", verbs: []string{"create", "patch"}},
+		// the requests waiting for a deployment to be scaled up are released by the endpoint slices
+		{group: "discovery.k8s.io", resource: "endpointslices", verbs: []string{"get", "list", "watch"}},
+	}
+
+	if proxlessRoutes {
+		permissions = append(permissions,
+			permission{group: "proxless.io", resource: "proxlessroutes", verbs: []string{"get", "list", "watch"}},
+			permission{
+				group: "proxless.io", resource: "proxlessroutes", subresource: "status",
+				verbs: []string{"get", "patch", "update"}})
+	}
+
+	if discovery {
+		permissions = append(permissions,
+			permission{group: "networking.k8s.io", resource: "ingresses", verbs: []string{"get", "list", "watch", "update"}},
+			permission{
+				group: "gateway.networking.k8s.io", resource: "httproutes", verbs: []string{"get", "list", "watch", "update"}})
+	}
+
+	if namespaceOptIn {
+		permissions = append(permissions,
+			permission{group: "", resource: "namespaces", verbs: []string{"get", "list", "watch"}, clusterScoped: true})
+	}
+
+	return permissions
+}
+
+// check the permissions of the service account of proxless with SelfSubjectAccessReviews
+// `namespaceScope` empty means cluster wide
+// return an error per missing permission
+func CheckPermissions(
+	clientSet kubernetes.Interface, namespaceScope string,
+	proxlessRoutes, discovery, namespaceOptIn bool) []error {
+	var errs []error
+
+	// see `RunServicesEngine` - the namespace opt-in is ignored when proxless is namespace scoped
+	permissions := getRequiredPermissions(proxlessRoutes, discovery, namespaceOptIn && namespaceScope == "")
+
+	for _, p := range permissions {
+		namespace := namespaceScope
+		if p.clusterScoped {
+			namespace = ""
+		}
+
+		for _, verb := range p.verbs {
+			allowed, err := isAllowed(clientSet, &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        verb,
+				Group:       p.group,
+				Resource:    p.resource,
+				Subresource: p.subresource,
+			})
+
+			if err != nil {
+				errs = append(errs, err)
+			} else if !allowed {
+				errs = append(errs, errors.New(
+					fmt.Sprintf("cannot %s %s %s", verb, genResourceName(p), genScopeName(namespace))))
+			}
+		}
+	}
+
+	return errs
+}
+
+func isAllowed(clientSet kubernetes.Interface, attributes *authorizationv1.ResourceAttributes) (bool, error) {
+	review, err := clientSet.AuthorizationV1().SelfSubjectAccessReviews().Create(
+		context.TODO(),
+		&authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attributes},
+		},
+		metav1.CreateOptions{})
+
+	if err != nil {
+		return false, err
+	}
+
+	return review.Status.Allowed, nil
+}
+
+// e.g. `deployments.apps` or `proxlessroutes.proxless.io/status`
+func genResourceName(p permission) string {
+	name := p.resource
+
+	if p.group != "" {
+		name = fmt.Sprintf("%s.%s", name, p.group)
+	}
+
+	if p.subresource != "" {
+		name = fmt.Sprintf("%s/%s", name, p.subresource)
+	}
+
+	return name
+}
+
+func genScopeName(namespace string) string {
+	if namespace == "" {
+		return "cluster wide"
+	}
+
+	return fmt.Sprintf("in namespace %s", namespace)
+}
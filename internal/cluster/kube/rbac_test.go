@@ -0,0 +1,70 @@
+package kube
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+
+	var reviewed []authorizationv1.ResourceAttributes
+
+	// everything is allowed but the updates of the deployments
+	clientSet.PrependReactor(
+		"create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			attributes := review.Spec.ResourceAttributes
+			reviewed = append(reviewed, *attributes)
+
+			review.Status.Allowed = !(attributes.Resource == "deployments" && attributes.Verb == "update")
+
+			return true, review, nil
+		})
+
+	errs := CheckPermissions(clientSet, dummyNamespaceName, false, false, true)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "cannot update deployments.apps in namespace "+dummyNamespaceName, errs[0].Error())
+	}
+
+	// the namespace opt-in is ignored when proxless is namespace scoped
+	for _, attributes := range reviewed {
+		assert.NotEqual(t, "namespaces", attributes.Resource)
+		assert.Equal(t, dummyNamespaceName, attributes.Namespace)
+	}
+
+	reviewed = nil
+	errs = CheckPermissions(clientSet, "", true, true, true)
+	assert.Len(t, errs, 1)
+	assert.Len(t, reviewed, 37)
+	for _, attributes := range reviewed {
+		assert.Empty(t, attributes.Namespace)
+	}
+
+	clientSet.PrependReactor(
+		"create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("unauthorized")
+		})
+
+	assert.Len(t, CheckPermissions(clientSet, dummyNamespaceName, false, false, false), 20)
+}
+
+func Test_genResourceName(t *testing.T) {
+	testCases := []struct {
+		p    permission
+		want string
+	}{
+		{permission{resource: "services"}, "services"},
+		{permission{group: "apps", resource: "deployments"}, "deployments.apps"},
+		{permission{group: "proxless.io", resource: "proxlessroutes", subresource: "status"}, "proxlessroutes.proxless.io/status"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, genResourceName(tc.p))
+	}
+}
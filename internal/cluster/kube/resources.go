@@ -0,0 +1,51 @@
+package kube
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+)
+
+// cpu (cores) and memory (GB) requested by the pods of the deployment once scaled up
+// this is what the cluster saves while the deployment is scaled down
+func getDeploymentResourceRequests(clientSet kubernetes.Interface, name, namespace string) (float64, float64, error) {
+	deploy, err := getDeployment(clientSet, name, namespace)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	replicas, err := getScaleUpReplicas(clientSet, name, namespace)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cpu, memory := getPodResourceRequests(&deploy.Spec.Template.Spec)
+
+	return float64(cpu.MilliValue()) / 1000 * float64(replicas),
+		float64(memory.Value()) / 1e9 * float64(replicas),
+		nil
+}
+
+// same as the scheduler - the sum of the containers or the biggest init container if it is bigger
+func getPodResourceRequests(pod *corev1.PodSpec) (*resource.Quantity, *resource.Quantity) {
+	cpu, memory := resource.NewQuantity(0, resource.DecimalSI), resource.NewQuantity(0, resource.BinarySI)
+
+	for _, container := range pod.Containers {
+		cpu.Add(*container.Resources.Requests.Cpu())
+		memory.Add(*container.Resources.Requests.Memory())
+	}
+
+	for _, container := range pod.InitContainers {
+		if container.Resources.Requests.Cpu().Cmp(*cpu) > 0 {
+			cpu = container.Resources.Requests.Cpu()
+		}
+
+		if container.Resources.Requests.Memory().Cmp(*memory) > 0 {
+			memory = container.Resources.Requests.Memory()
+		}
+	}
+
+	return cpu, memory
+}
@@ -0,0 +1,59 @@
+package kube
+
+import (
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func Test_getDeploymentResourceRequests(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+
+	_, _, err := getDeploymentResourceRequests(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.Error(t, err)
+
+	deploy := helper_createProxlessCompatibleDeployment(t, clientSet)
+	deploy.Spec.Template.Spec.Containers = []corev1.Container{
+		helper_newContainer("250m", "512M"),
+		helper_newContainer("250m", "512M"),
+	}
+	helper_updateDeployment(t, clientSet, deploy)
+
+	cpu, memory, err := getDeploymentResourceRequests(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, cpu)
+	assert.Equal(t, 1.024, memory)
+
+	// the HPA has been paused with minReplicas = 3
+	helper_createHPA(t, clientSet, dummyProxlessName, 3)
+	assert.NoError(t, pauseHPA(clientSet, dummyProxlessName, dummyNamespaceName))
+
+	cpu, memory, err = getDeploymentResourceRequests(clientSet, dummyProxlessName, dummyNamespaceName)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, cpu)
+	assert.InDelta(t, 3.072, memory, 0.0001)
+}
+
+func Test_getPodResourceRequests(t *testing.T) {
+	testCases := []struct {
+		containers, initContainers []corev1.Container
+		cpu, memory                string
+	}{
+		{nil, nil, "0", "0"},
+		{[]corev1.Container{{}}, nil, "0", "0"}, // no requests
+		{[]corev1.Container{helper_newContainer("100m", "128Mi"), helper_newContainer("200m", "128Mi")},
+			nil, "300m", "256Mi"},
+		{[]corev1.Container{helper_newContainer("100m", "128Mi")},
+			[]corev1.Container{helper_newContainer("1", "64Mi")}, "1", "128Mi"},
+	}
+
+	for _, tc := range testCases {
+		cpu, memory := getPodResourceRequests(&corev1.PodSpec{Containers: tc.containers, InitContainers: tc.initContainers})
+
+		assert.Equal(t, 0, cpu.Cmp(resource.MustParse(tc.cpu)), cpu.String())
+		assert.Equal(t, 0, memory.Cmp(resource.MustParse(tc.memory)), memory.String())
+	}
+}
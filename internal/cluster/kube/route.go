@@ -0,0 +1,107 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+	"sort"
+	"strings"
+)
+
+// build the route of a service that is not configured with the annotations (e.g. proxless route, ingress)
+// the proxless service is created like for the annotated services
+// the domains of `domainsPortsRefs` are routed to their own port instead of `portRef`
+// return nil if the route cannot be built and the errors to report to the user
+func genRouteFromService(
+	clientSet kubernetes.Interface, serviceName, namespace, deployName, portRef string, extraDomains []string,
+	domainsPortsRefs map[string]string,
+	ttlSeconds, readinessTimeoutSeconds *int, namespaceScoped bool, proxlessSvc, proxlessNamespace string,
+) (*model.Route, []error) {
+	var errs []error
+
+	svc, err := clientSet.CoreV1().Services(namespace).Get(context.TODO(), serviceName, metav1.GetOptions{})
+
+	if err != nil {
+		logger.Errorf(err, "Error finding service %s.%s", serviceName, namespace)
+		return nil, append(errs, err)
+	}
+
+	if _, err := createProxlessService(clientSet, svc, proxlessSvc, proxlessNamespace); err != nil {
+		logger.Errorf(err, "Error creating proxless service for %s.%s", svc.Name, svc.Namespace)
+		// do not return here - we don't wanna break the proxy forwarding
+	}
+
+	deployNames, err := resolveServiceDeployment(clientSet, svc, deployName, false)
+
+	if err != nil {
+		logger.Warnf(err, "Cannot find the deployment of service %s.%s", svc.Name, svc.Namespace)
+		return nil, append(errs, err)
+	}
+
+	deploy, err := getDeployment(clientSet, deployNames[0], namespace)
+
+	if err != nil {
+		logger.Errorf(err, "Error retrieving deployment %s.%s", deployNames[0], namespace)
+		// do not return here - the deployment might be created later
+		errs = append(errs, err)
+		deploy = nil
+	}
+
+	port, found := getPortFromServicePorts(svc.Spec.Ports, deploy, portRef)
+
+	if !found {
+		errs = append(errs, errors.New(fmt.Sprintf("Port %s not found in service %s - using the first port", portRef, svc.Name)))
+	}
+
+	domainsPorts := getDomainsPortsFromServicePorts(svc.Spec.Ports, deploy, domainsPortsRefs)
+
+	// sorted - the errors are compared with the ones of the previous status
+	portsDomains := make([]string, 0, len(domainsPortsRefs))
+	for domain := range domainsPortsRefs {
+		portsDomains = append(portsDomains, domain)
+	}
+	sort.Strings(portsDomains)
+
+	for _, domain := range portsDomains {
+		if _, ok := domainsPorts[domain]; !ok {
+			errs = append(errs, errors.New(fmt.Sprintf(
+				"Port %s of domain %s not found in service %s - using the default port", domainsPortsRefs[domain], domain, svc.Name)))
+		}
+	}
+
+	domains := clusterutils.GenDomains(
+		strings.Join(append(append([]string{}, extraDomains...), portsDomains...), ","), svc.Name, svc.Namespace, namespaceScoped)
+	isRunning := deploy != nil && isDeploymentRunning(deploy)
+
+	route, err := model.NewRoute(
+		clusterutils.GenRouteId(svc.Name, svc.Namespace), svc.Name, port, deployNames[0], namespace, domains, isRunning,
+		ttlSeconds, readinessTimeoutSeconds)
+
+	if err == nil {
+		err = route.SetDeployments(deployNames)
+	}
+
+	if err != nil {
+		logger.Errorf(err, "Error creating route for service %s.%s", svc.Name, svc.Namespace)
+		return nil, append(errs, err)
+	}
+
+	route.SetPorts(getPortsFromServicePorts(svc.Spec.Ports, deploy))
+	route.SetDomainsPorts(domainsPorts)
+
+	if deploy != nil {
+		route.SetReplicas(deploy.Name, getDeploymentReplicas(deploy), int(deploy.Status.AvailableReplicas))
+
+		// the lastUsed persisted by a previous run of proxless has priority over `time.Now()`
+		if lastUsed := getLastUsedFromDeployment(deploy); lastUsed != nil {
+			route.SetLastUsed(*lastUsed)
+		}
+	}
+
+	return route, errs
+}
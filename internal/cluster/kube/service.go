@@ -4,41 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	clusterutils "kube-proxless/internal/cluster/utils"
 	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
 	"strconv"
 )
 
-func createProxlessService(
-	clientSet kubernetes.Interface, appSvc, appNs, proxlessSvc, proxlessNs string) (*corev1.Service, error) {
-	svc, err := clientSet.CoreV1().Services(appNs).Create(context.TODO(), &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        clusterutils.GenServiceToAppName(appSvc),
-			Annotations: map[string]string{"owner": "proxless"},
-		},
-		Spec: corev1.ServiceSpec{
-			Type:         "ExternalName",
-			ExternalName: fmt.Sprintf("%s.%s.svc.cluster.local", proxlessSvc, proxlessNs),
-		},
-	}, metav1.CreateOptions{})
-
-	// not an issue if proxless service already exists and we don't wanna update it
-	if k8serrors.IsAlreadyExists(err) {
-		return svc, nil
-	}
-
-	return svc, err
-}
-
-func deleteProxlessService(clientSet kubernetes.Interface, appSvc, appNs string) error {
-	return clientSet.CoreV1().Services(appNs).Delete(
-		context.TODO(), clusterutils.GenServiceToAppName(appSvc), metav1.DeleteOptions{})
-}
-
 func parseService(obj interface{}) (*corev1.Service, error) {
 	svc, ok := obj.(*corev1.Service)
 	if !ok {
@@ -47,22 +24,104 @@ func parseService(obj interface{}) (*corev1.Service, error) {
 	return svc, nil
 }
 
-func getPortFromServicePorts(ports []corev1.ServicePort) string {
-	port := ports[0] // TODO add possibility to manage multiple ports
+// return the target port of each service port indexed by the port name and the port number
+// named target ports are resolved with the container ports of the deployment
+func getPortsFromServicePorts(ports []corev1.ServicePort, deploy *appsv1.Deployment) map[string]string {
+	resolvedPorts := map[string]string{}
+
+	for _, port := range ports {
+		targetPort := resolveTargetPort(port, deploy)
+
+		if port.Name != "" {
+			resolvedPorts[port.Name] = targetPort
+		}
+		resolvedPorts[strconv.Itoa(int(port.Port))] = targetPort
+	}
+
+	return resolvedPorts
+}
+
+func resolveTargetPort(port corev1.ServicePort, deploy *appsv1.Deployment) string {
+	if port.TargetPort.Type == intstr.String {
+		if deploy != nil {
+			for _, container := range deploy.Spec.Template.Spec.Containers {
+				for _, containerPort := range container.Ports {
+					if containerPort.Name == port.TargetPort.StrVal {
+						return strconv.Itoa(int(containerPort.ContainerPort))
+					}
+				}
+			}
+		}
+
+		logger.Warnf(nil, "Could not resolve named target port %s - using port %d", port.TargetPort.StrVal, port.Port)
+		return strconv.Itoa(int(port.Port))
+	}
+
+	if port.TargetPort.IntVal == 0 { // `targetPort` defaults to `port`
+		return strconv.Itoa(int(port.Port))
+	}
 
 	return strconv.Itoa(int(port.TargetPort.IntVal))
 }
 
+// return the target port of the service port matching `portRef` (name or number)
+// return the target port of the first service port if `portRef` is empty or not found - the bool is false if not found
+func getPortFromServicePorts(ports []corev1.ServicePort, deploy *appsv1.Deployment, portRef string) (string, bool) {
+	if len(ports) == 0 {
+		return "", portRef == ""
+	}
+
+	if portRef != "" {
+		if port, ok := getPortsFromServicePorts(ports, deploy)[portRef]; ok {
+			return port, true
+		}
+	}
+
+	return resolveTargetPort(ports[0], deploy), portRef == ""
+}
+
+// return the target port of each domain
+func getDomainsPortsFromServicePorts(
+	ports []corev1.ServicePort, deploy *appsv1.Deployment, domainsPortsRefs map[string]string) map[string]string {
+	resolvedPorts := getPortsFromServicePorts(ports, deploy)
+
+	domainsPorts := map[string]string{}
+	for domain, portRef := range domainsPortsRefs {
+		if port, ok := resolvedPorts[portRef]; ok {
+			domainsPorts[domain] = port
+		} else {
+			logger.Warnf(nil, "Port %s of domain %s not found in the service ports", portRef, domain)
+		}
+	}
+
+	return domainsPorts
+}
+
 func addServiceToMemory(
-	clientset kubernetes.Interface, svc *corev1.Service, namespaceScoped bool,
+	clientset kubernetes.Interface, recorder record.EventRecorder, svc *corev1.Service, namespaceScoped bool,
 	proxlessSvc, proxlessNamespace string,
-	upsertMemory func(
-		id, name, port, deployName, namespace string, domains []string, isRunning bool, ttlSeconds, readinessTimeoutSeconds *int) error,
+	upsertMemory func(route *model.Route) error,
 ) {
 	if clusterutils.IsAnnotationsProxlessCompatible(svc.ObjectMeta) {
+		// the events and the status are written on the annotated service - not the one targeted by `proxless/service`
+		annotatedSvc := svc
+		active := false
+		var domains []string
+
+		errs := clusterutils.ValidateServiceAnnotations(svc.Annotations)
+		for _, err := range errs {
+			recorder.Event(annotatedSvc, corev1.EventTypeWarning, eventReasonAnnotationInvalid, err.Error())
+		}
+
+		defer func() {
+			updateServiceStatus(clientset, annotatedSvc, active, domains, errs)
+		}()
+
 		deployName := svc.Annotations[clusterutils.AnnotationServiceDeployKey]
-		domains :=
-			clusterutils.GenDomains(svc.Annotations[clusterutils.AnnotationServiceDomainKey], svc.Name, svc.Namespace, namespaceScoped)
+		domainsAnnotation, domainsPortsRefs :=
+			clusterutils.ParseDomainsPorts(svc.Annotations[clusterutils.AnnotationServiceDomainKey])
+		domains = clusterutils.GenDomains(domainsAnnotation, svc.Name, svc.Namespace, namespaceScoped)
+		portRef := svc.Annotations[clusterutils.AnnotationServicePort]
 		ttlSeconds := clusterutils.ParseStringToIntPointer(svc.Annotations[clusterutils.AnnotationServiceTTLSeconds])
 		readinessTimeoutSeconds := clusterutils.ParseStringToIntPointer(svc.Annotations[clusterutils.AnnotationServiceReadinessTimeoutSeconds])
 
@@ -73,10 +132,11 @@ func addServiceToMemory(
 
 			if err != nil {
 				logger.Errorf(err, "Error finding service %s.%s", serviceName, appNs)
+				errs = append(errs, err)
 				return
 			}
 		} else {
-			_, err := createProxlessService(clientset, svc.Name, svc.Namespace, proxlessSvc, proxlessNamespace)
+			_, err := createProxlessService(clientset, svc, proxlessSvc, proxlessNamespace)
 
 			if err != nil {
 				logger.Errorf(err, "Error creating proxless service for %s.%s", svc.Name, svc.Namespace)
@@ -84,17 +144,77 @@ func addServiceToMemory(
 			}
 		}
 
-		port := getPortFromServicePorts(svc.Spec.Ports)
+		wakeAll := annotatedSvc.Annotations[clusterutils.AnnotationServiceWakeAllDeployments] == "true"
+		deployNames, err := resolveServiceDeployment(clientset, svc, deployName, wakeAll)
+
+		if err != nil {
+			logger.Warnf(err, "Cannot find the deployment of service %s.%s", svc.Name, svc.Namespace)
+			errs = append(errs, err)
+			recorder.Event(annotatedSvc, corev1.EventTypeWarning, eventReasonDeploymentUnknown, err.Error())
+			return
+		}
+
+		// the ports are resolved with the first deployment if the service wakes up multiple deployments
+		deploy, err := getDeployment(clientset, deployNames[0], svc.Namespace)
+
+		if err != nil {
+			logger.Errorf(err, "Error retrieving deployment %s.%s", deployNames[0], svc.Namespace)
+			// do not return here - the deployment might be created later
+			deploy = nil
+		}
+
+		port, found := getPortFromServicePorts(svc.Spec.Ports, deploy, portRef)
 
-		isRunning := isDeploymentRunning(clientset, deployName, svc.Namespace)
+		if !found {
+			logger.Warnf(nil, "Port %s not found in service %s.%s - using the first port", portRef, svc.Name, svc.Namespace)
+
+			err := errors.New(fmt.Sprintf("%s %s not found in the ports of service %s - using the first port",
+				clusterutils.AnnotationServicePort, portRef, svc.Name))
+			errs = append(errs, err)
+			recorder.Event(annotatedSvc, corev1.EventTypeWarning, eventReasonAnnotationInvalid, err.Error())
+		}
+
+		isRunning := deploy != nil && isDeploymentRunning(deploy)
 
 		id := clusterutils.GenRouteId(svc.Name, svc.Namespace)
-		err = upsertMemory(id, svc.Name, port, deployName, svc.Namespace, domains, isRunning, ttlSeconds, readinessTimeoutSeconds)
+		route, err := model.NewRoute(
+			id, svc.Name, port, deployNames[0], svc.Namespace, domains, isRunning, ttlSeconds, readinessTimeoutSeconds)
+
+		if err == nil {
+			err = route.SetDeployments(deployNames)
+		}
+
+		if err != nil {
+			logger.Errorf(err, "Error creating route for service %s.%s", svc.Name, svc.Namespace)
+			errs = append(errs, err)
+			return
+		}
+
+		route.SetMinUptimeSeconds(
+			clusterutils.ParseStringToIntPointer(annotatedSvc.Annotations[clusterutils.AnnotationServiceMinUptimeSeconds]))
+		route.SetPinnedUntil(clusterutils.ParseStringToTime(annotatedSvc.Annotations[clusterutils.AnnotationServicePinnedUntil]))
+		route.SetPorts(getPortsFromServicePorts(svc.Spec.Ports, deploy))
+		route.SetDomainsPorts(getDomainsPortsFromServicePorts(svc.Spec.Ports, deploy, domainsPortsRefs))
+
+		if deploy != nil {
+			route.SetReplicas(deploy.Name, getDeploymentReplicas(deploy), int(deploy.Status.AvailableReplicas))
+
+			// the lastUsed persisted by a previous run of proxless has priority over `time.Now()`
+			if lastUsed := getLastUsedFromDeployment(deploy); lastUsed != nil {
+				route.SetLastUsed(*lastUsed)
+			}
+		}
+
+		err = upsertMemory(route)
 
 		if err == nil {
 			logger.Debugf("Service %s.%s added into memory", svc.Name, svc.Namespace)
+			active = true
 		} else {
 			logger.Errorf(err, "Error adding service %s.%s into memory", svc.Name, svc.Namespace)
+			// the memory only refuses a route if its deployment or domains are owned by another route
+			errs = append(errs, err)
+			recorder.Event(annotatedSvc, corev1.EventTypeWarning, eventReasonDomainConflict, err.Error())
 		}
 	}
 }
@@ -129,21 +249,21 @@ func removeServiceFromMemory(
 }
 
 func updateServiceMemory(
-	clientset kubernetes.Interface, oldSvc, newSvc *corev1.Service, namespaceScoped bool,
+	clientset kubernetes.Interface, recorder record.EventRecorder, oldSvc, newSvc *corev1.Service, namespaceScoped bool,
 	proxlessService, proxlessNamespace string,
-	upsertMemory func(
-		id, name, port, deployName, namespace string, domains []string, isRunning bool, ttlSeconds, readinessTimeoutSeconds *int) error,
+	upsertMemory func(route *model.Route) error,
 	deleteRouteFromMemory func(id string) error,
 ) {
 	if clusterutils.IsAnnotationsProxlessCompatible(oldSvc.ObjectMeta) &&
 		clusterutils.IsAnnotationsProxlessCompatible(newSvc.ObjectMeta) { // updating service
 		// the `addServiceToMemory` is idempotent so we can reuse it in the update
-		addServiceToMemory(clientset, newSvc, namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
+		addServiceToMemory(clientset, recorder, newSvc, namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
 	} else if !clusterutils.IsAnnotationsProxlessCompatible(oldSvc.ObjectMeta) &&
 		clusterutils.IsAnnotationsProxlessCompatible(newSvc.ObjectMeta) { // adding new service
-		addServiceToMemory(clientset, newSvc, namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
+		addServiceToMemory(clientset, recorder, newSvc, namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
 	} else if clusterutils.IsAnnotationsProxlessCompatible(oldSvc.ObjectMeta) &&
 		!clusterutils.IsAnnotationsProxlessCompatible(newSvc.ObjectMeta) { // removing service
 		removeServiceFromMemory(clientset, oldSvc, deleteRouteFromMemory)
+		removeServiceStatus(clientset, newSvc)
 	}
 }
@@ -1,8 +1,17 @@
 package kube
 
 import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/model"
 	"testing"
 )
 
@@ -26,20 +35,163 @@ func Test_parseService(t *testing.T) {
 }
 
 func Test_getPortFromServicePorts(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+						{Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9090}}},
+					},
+				},
+			},
+		},
+	}
+
+	multiplePorts := []corev1.ServicePort{
+		{Name: "http", Port: 80, TargetPort: intstr.FromString("http")},
+		{Name: "metrics", Port: 9000, TargetPort: intstr.FromString("metrics")},
+		{Name: "admin", Port: 8000, TargetPort: intstr.FromInt(8001)},
+	}
+
 	testCases := []struct {
-		port []corev1.ServicePort
-		want string
+		port      []corev1.ServicePort
+		deploy    *appsv1.Deployment
+		portRef   string
+		want      string
+		wantFound bool
 	}{
-		{[]corev1.ServicePort{{TargetPort: intstr.IntOrString{IntVal: 80}}}, "80"},
-		{[]corev1.ServicePort{{TargetPort: intstr.IntOrString{IntVal: 8080}}, {TargetPort: intstr.IntOrString{IntVal: 80}}}, "8080"},
+		{[]corev1.ServicePort{{TargetPort: intstr.IntOrString{IntVal: 80}}}, nil, "", "80", true},
+		{[]corev1.ServicePort{{TargetPort: intstr.IntOrString{IntVal: 8080}}, {TargetPort: intstr.IntOrString{IntVal: 80}}}, nil, "", "8080", true},
+		{nil, nil, "", "", true},
+		{nil, nil, "http", "", false},
+		// `targetPort` defaults to `port`
+		{[]corev1.ServicePort{{Port: 80}}, nil, "", "80", true},
+		// named target port resolved with the deployment
+		{multiplePorts, deploy, "", "8080", true},
+		// named target port not found - fallback to the service port
+		{multiplePorts, nil, "", "80", true},
+		// port selected by name
+		{multiplePorts, deploy, "metrics", "9090", true},
+		{multiplePorts, deploy, "admin", "8001", true},
+		// port selected by number
+		{multiplePorts, deploy, "9000", "9090", true},
+		// port not found - fallback to the first port
+		{multiplePorts, deploy, "unknown", "8080", false},
 	}
 
 	for _, tc := range testCases {
-		got := getPortFromServicePorts(tc.port)
+		got, gotFound := getPortFromServicePorts(tc.port, tc.deploy, tc.portRef)
 
-		if got != tc.want {
-			t.Errorf("getPortFromServicePorts(%v) = %s; want = %s",
-				tc.port, got, tc.want)
+		if got != tc.want || gotFound != tc.wantFound {
+			t.Errorf("getPortFromServicePorts(%v, %s) = %s, %t; want = %s, %t",
+				tc.port, tc.portRef, got, gotFound, tc.want, tc.wantFound)
 		}
 	}
 }
+
+func Test_getDomainsPortsFromServicePorts(t *testing.T) {
+	ports := []corev1.ServicePort{
+		{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+		{Name: "admin", Port: 8000, TargetPort: intstr.FromInt(8001)},
+	}
+
+	got := getDomainsPortsFromServicePorts(ports, nil, map[string]string{
+		"example.io":       "http",
+		"admin.example.io": "8000",
+		"wrong.example.io": "unknown",
+	})
+
+	assert.Equal(t, map[string]string{"example.io": "8080", "admin.example.io": "8001"}, got)
+}
+
+func Test_addServiceToMemory_EventsAndStatus(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	recorder := record.NewFakeRecorder(10)
+
+	helper_createNamespace(t, clientSet)
+	svc := helper_createProxlessCompatibleService(t, clientSet)
+	svc.Annotations[clusterutils.AnnotationServiceTTLSeconds] = "abc"
+
+	upsertMemory := func(route *model.Route) error {
+		return errors.New("domain dummy.io is already owned by another route")
+	}
+	addServiceToMemory(clientSet, recorder, svc, true, dummyProxlessName, dummyNamespaceName, upsertMemory)
+
+	assert.Contains(t, <-recorder.Events, eventReasonAnnotationInvalid)
+	assert.Contains(t, <-recorder.Events, eventReasonDomainConflict)
+	assert.Len(t, recorder.Events, 0)
+
+	// the errors are also written in the status of the service
+	svc = helper_getService(t, clientSet, dummyProxlessName)
+	assert.Contains(t, svc.Annotations[clusterutils.AnnotationServiceStatus], `"active":false`)
+	assert.Contains(t, svc.Annotations[clusterutils.AnnotationServiceStatus], "already owned by another route")
+}
+
+func Test_addServiceToMemory_InferDeployment(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	recorder := record.NewFakeRecorder(10)
+	memory := fakeMemory{m: map[string]string{}, replicas: map[string]int{}, endpoints: map[string][]string{}}
+
+	helper_createNamespace(t, clientSet)
+	helper_createDeploymentWithTemplateLabels(t, clientSet, "deploy-a", map[string]string{"app": "a"})
+	helper_createDeploymentWithTemplateLabels(t, clientSet, "deploy-b", map[string]string{"app": "a"})
+
+	svc, err := clientSet.CoreV1().Services(dummyNamespaceName).Create(context.TODO(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        dummyProxlessName,
+			Namespace:   dummyNamespaceName,
+			Annotations: map[string]string{clusterutils.AnnotationEnabled: "true"},
+		},
+		Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "a"}},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// the selector matches 2 deployments
+	addServiceToMemory(clientSet, recorder, svc, true, dummyProxlessName, dummyNamespaceName, memory.helper_upsertMemory)
+	assert.Contains(t, <-recorder.Events, eventReasonDeploymentUnknown)
+	assert.Equal(t, 0, memory.helper_countRoutes())
+
+	svc.Annotations[clusterutils.AnnotationServiceWakeAllDeployments] = "true"
+	addServiceToMemory(clientSet, recorder, svc, true, dummyProxlessName, dummyNamespaceName, memory.helper_upsertMemory)
+	assert.Equal(t, "deploy-a,deploy-b", memory.helper_getDeployment(clusterutils.GenRouteId(dummyProxlessName, dummyNamespaceName)))
+	assert.Len(t, recorder.Events, 0)
+}
+
+func Test_addServiceToMemory_UnknownPort(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	recorder := record.NewFakeRecorder(10)
+
+	helper_createNamespace(t, clientSet)
+	helper_createProxlessCompatibleDeployment(t, clientSet)
+	svc := helper_createProxlessCompatibleService(t, clientSet)
+	svc.Annotations[clusterutils.AnnotationServiceDeployKey] = dummyProxlessName
+	svc.Annotations[clusterutils.AnnotationServicePort] = "unknown"
+	svc.Spec.Ports = []corev1.ServicePort{
+		{Name: "http", Port: 80, TargetPort: intstr.FromInt(9090)},
+		{Name: "admin", Port: 8000, TargetPort: intstr.FromInt(8001)},
+	}
+	svc, err := clientSet.CoreV1().Services(dummyNamespaceName).Update(context.TODO(), svc, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	var route *model.Route
+	upsertMemory := func(r *model.Route) error {
+		route = r
+		return nil
+	}
+
+	// the first port of the service is used instead of the default port
+	addServiceToMemory(clientSet, recorder, svc, true, dummyProxlessName, dummyNamespaceName, upsertMemory)
+	if assert.NotNil(t, route) {
+		assert.Equal(t, "9090", route.GetPort())
+	}
+	assert.Contains(t, <-recorder.Events, eventReasonAnnotationInvalid)
+
+	// same for the routes that are not built from the annotations
+	route, errs := genRouteFromService(clientSet, svc.Name, dummyNamespaceName, dummyProxlessName, "unknown", nil, nil,
+		nil, nil, true, dummyProxlessName, dummyNamespaceName)
+	if assert.NotNil(t, route) {
+		assert.Equal(t, "9090", route.GetPort())
+	}
+	assert.Len(t, errs, 1)
+}
@@ -3,18 +3,24 @@ package kube
 import (
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
 	"time"
 )
 
 func runServicesInformer(
 	clientSet kubernetes.Interface,
+	recorder record.EventRecorder,
+	namespaceLister listerscorev1.NamespaceLister, // optional - only used by the namespace opt-in
 	namespaceScope, proxlessService, proxlessNamespace string,
 	informerResyncInterval int,
-	upsertMemory func(
-		id, name, port, deployName, namespace string, domains []string, isRunning bool, ttlSeconds, readinessTimeoutSeconds *int) error,
+	upsertMemory func(route *model.Route) error,
 	deleteRouteFromMemory func(id string) error,
+	onSynced func(),
+	stopCh <-chan struct{},
 ) {
 	namespaceScoped := false
 	opts := make([]informers.SharedInformerOption, 0)
@@ -36,7 +42,8 @@ func runServicesInformer(
 			}
 
 			logger.Debugf("Add service handler - %s.%s", svc.Name, svc.Namespace)
-			addServiceToMemory(clientSet, svc, namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
+			svc = applyNamespaceOptInFromLister(clientSet, namespaceLister, svc)
+			addServiceToMemory(clientSet, recorder, svc, namespaceScoped, proxlessService, proxlessNamespace, upsertMemory)
 
 			return
 		},
@@ -54,8 +61,10 @@ func runServicesInformer(
 			}
 
 			logger.Debugf("Update service handler - %s.%s", newSvc.Name, newSvc.Namespace)
+			oldSvc = applyNamespaceOptInFromLister(clientSet, namespaceLister, oldSvc)
+			newSvc = applyNamespaceOptInFromLister(clientSet, namespaceLister, newSvc)
 			updateServiceMemory(
-				clientSet, oldSvc, newSvc, namespaceScoped, proxlessService, proxlessNamespace,
+				clientSet, recorder, oldSvc, newSvc, namespaceScoped, proxlessService, proxlessNamespace,
 				upsertMemory, deleteRouteFromMemory)
 
 			return
@@ -69,6 +78,7 @@ func runServicesInformer(
 			}
 
 			logger.Debugf("Remove service handler - %s.%s", svc.Name, svc.Namespace)
+			svc = applyNamespaceOptInFromLister(clientSet, namespaceLister, svc)
 			removeServiceFromMemory(clientSet, svc, deleteRouteFromMemory)
 
 			return
@@ -76,7 +86,7 @@ func runServicesInformer(
 	}
 	informer.AddEventHandler(eventHandler)
 
-	stopCh := make(chan struct{})
-	defer close(stopCh)
+	go notifySynced(informer, onSynced, stopCh)
+
 	informer.Run(stopCh)
 }
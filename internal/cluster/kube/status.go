@@ -0,0 +1,84 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/logger"
+)
+
+// written in the `proxless/status` annotation of the service
+type serviceStatus struct {
+	Active  bool     `json:"active"`
+	Domains []string `json:"domains,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+func genServiceStatus(active bool, domains []string, errs []error) (string, error) {
+	status := serviceStatus{Active: active, Domains: domains}
+	for _, err := range errs {
+		status.Errors = append(status.Errors, err.Error())
+	}
+
+	statusBytes, err := json.Marshal(status)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(statusBytes), nil
+}
+
+// `nil` removes the annotation
+func patchServiceStatus(clientSet kubernetes.Interface, name, namespace string, status interface{}) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				clusterutils.AnnotationServiceStatus: status,
+			},
+		},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = clientSet.CoreV1().Services(namespace).Patch(
+		context.TODO(), name, k8stypes.MergePatchType, payloadBytes, metav1.PatchOptions{})
+
+	return err
+}
+
+// the service is only patched if the status changed
+// otherwise each patch would trigger the services informer which would patch the service again
+func updateServiceStatus(
+	clientSet kubernetes.Interface, svc *corev1.Service, active bool, domains []string, errs []error) {
+	status, err := genServiceStatus(active, domains, errs)
+
+	if err != nil {
+		logger.Errorf(err, "Could not generate the status of service %s.%s", svc.Name, svc.Namespace)
+		return
+	}
+
+	if svc.Annotations[clusterutils.AnnotationServiceStatus] == status {
+		return
+	}
+
+	if err := patchServiceStatus(clientSet, svc.Name, svc.Namespace, status); err != nil {
+		logger.Errorf(err, "Could not update the status of service %s.%s", svc.Name, svc.Namespace)
+	}
+}
+
+func removeServiceStatus(clientSet kubernetes.Interface, svc *corev1.Service) {
+	if !metav1.HasAnnotation(svc.ObjectMeta, clusterutils.AnnotationServiceStatus) {
+		return
+	}
+
+	if err := patchServiceStatus(clientSet, svc.Name, svc.Namespace, nil); err != nil {
+		logger.Errorf(err, "Could not remove the status of service %s.%s", svc.Name, svc.Namespace)
+	}
+}
@@ -0,0 +1,48 @@
+package kube
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"testing"
+)
+
+func Test_genServiceStatus(t *testing.T) {
+	testCases := []struct {
+		active  bool
+		domains []string
+		errs    []error
+		want    string
+	}{
+		{false, nil, nil, `{"active":false}`},
+		{true, []string{"example.io"}, nil, `{"active":true,"domains":["example.io"]}`},
+		{false, []string{"example.io"}, []error{errors.New("invalid")}, `{"active":false,"domains":["example.io"],"errors":["invalid"]}`},
+	}
+
+	for _, tc := range testCases {
+		got, err := genServiceStatus(tc.active, tc.domains, tc.errs)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func Test_updateServiceStatus(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	helper_createNamespace(t, clientSet)
+	svc := helper_createProxlessCompatibleService(t, clientSet)
+
+	updateServiceStatus(clientSet, svc, true, []string{"dummy.io"}, nil)
+	svc = helper_getService(t, clientSet, dummyProxlessName)
+	assert.Equal(t, `{"active":true,"domains":["dummy.io"]}`, svc.Annotations[clusterutils.AnnotationServiceStatus])
+
+	// the status did not change - the service must not be patched again
+	actionsCount := len(clientSet.Actions())
+	updateServiceStatus(clientSet, svc, true, []string{"dummy.io"}, nil)
+	assert.Len(t, clientSet.Actions(), actionsCount)
+
+	removeServiceStatus(clientSet, svc)
+	svc = helper_getService(t, clientSet, dummyProxlessName)
+	assert.False(t, metav1.HasAnnotation(svc.ObjectMeta, clusterutils.AnnotationServiceStatus))
+}
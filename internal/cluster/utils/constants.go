@@ -1,9 +1,18 @@
 package utils
 
 const (
+	AnnotationPrefix                         = "proxless/"
 	AnnotationServiceDomainKey               = "proxless/domains"
 	AnnotationServiceDeployKey               = "proxless/deployment"
 	AnnotationServiceTTLSeconds              = "proxless/ttl-seconds"
 	AnnotationServiceReadinessTimeoutSeconds = "proxless/readiness-timeout-seconds"
+	AnnotationServiceMinUptimeSeconds        = "proxless/min-uptime-seconds"
+	AnnotationServicePinnedUntil             = "proxless/pinned-until"
 	AnnotationServiceServiceName             = "proxless/service"
+	AnnotationServicePort                    = "proxless/port"
+	AnnotationServiceStatus                  = "proxless/status"
+	AnnotationEnabled                        = "proxless/enabled"
+	AnnotationServiceWakeAllDeployments      = "proxless/wake-all-deployments"
+	AnnotationDeploymentLastUsed             = "proxless/last-used"
+	AnnotationHPAMinReplicas                 = "proxless/hpa-min-replicas"
 )
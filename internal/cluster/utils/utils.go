@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func GenServiceToAppName(svcName string) string {
@@ -34,8 +37,48 @@ func GenDomains(domains, name, namespace string, namespaceScoped bool) []string
 	return domainsArray
 }
 
+// the domains can target a specific port of the service with the `domain:port` syntax
+// return the domains without the ports and the port (name or number) of each domain
+func ParseDomainsPorts(domains string) (string, map[string]string) {
+	domainsPorts := map[string]string{}
+
+	if domains == "" {
+		return domains, domainsPorts
+	}
+
+	var domainsArray []string
+	for _, d := range strings.Split(domains, ",") {
+		if i := strings.LastIndex(d, ":"); i >= 0 {
+			domainsPorts[d[:i]] = d[i+1:]
+			d = d[:i]
+		}
+		domainsArray = append(domainsArray, d)
+	}
+
+	return strings.Join(domainsArray, ","), domainsPorts
+}
+
+// `proxless/deployment` is optional - the deployment is inferred from the selector of the service if missing
 func IsAnnotationsProxlessCompatible(meta metav1.ObjectMeta) bool {
-	return metav1.HasAnnotation(meta, AnnotationServiceDeployKey)
+	return metav1.HasAnnotation(meta, AnnotationServiceDeployKey) || IsProxlessEnabled(meta)
+}
+
+// a route can wake up multiple deployments - see `proxless/wake-all-deployments`
+func SplitDeployments(deployName string) []string {
+	var deployNames []string
+
+	for _, d := range strings.Split(deployName, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			deployNames = append(deployNames, d)
+		}
+	}
+
+	return deployNames
+}
+
+// used by the objects discovered by proxless (e.g. ingress) instead of the `proxless/deployment` annotation
+func IsProxlessEnabled(meta metav1.ObjectMeta) bool {
+	return meta.Annotations[AnnotationEnabled] == "true"
 }
 
 // return nil if error
@@ -48,3 +91,65 @@ func ParseStringToIntPointer(s string) *int {
 
 	return &sInt
 }
+
+// zero time if empty or invalid
+func ParseStringToTime(s string) time.Time {
+	if t := ParseStringToTimePointer(s); t != nil {
+		return *t
+	}
+
+	return time.Time{}
+}
+
+// return nil if error
+func ParseStringToTimePointer(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+
+	if err != nil {
+		return nil
+	}
+
+	return &t
+}
+
+// return the errors of the proxless annotations of a service
+// an invalid annotation is ignored (default value) so it does not prevent the service from being added in memory
+func ValidateServiceAnnotations(annotations map[string]string) []error {
+	var errs []error
+
+	if deployName, ok := annotations[AnnotationServiceDeployKey]; ok && deployName == "" {
+		errs = append(errs, errors.New(fmt.Sprintf("%s must not be empty", AnnotationServiceDeployKey)))
+	}
+
+	for _, key := range []string{
+		AnnotationServiceTTLSeconds, AnnotationServiceReadinessTimeoutSeconds, AnnotationServiceMinUptimeSeconds} {
+		if value, ok := annotations[key]; ok {
+			if i := ParseStringToIntPointer(value); i == nil || *i <= 0 {
+				errs = append(errs, errors.New(fmt.Sprintf("%s must be a positive integer - got %q", key, value)))
+			}
+		}
+	}
+
+	if value, ok := annotations[AnnotationServicePinnedUntil]; ok && ParseStringToTimePointer(value) == nil {
+		errs = append(errs, errors.New(
+			fmt.Sprintf("%s must be a RFC3339 date - got %q", AnnotationServicePinnedUntil, value)))
+	}
+
+	if domains := annotations[AnnotationServiceDomainKey]; domains != "" {
+		for _, d := range strings.Split(domains, ",") {
+			domain := d
+			if i := strings.LastIndex(d, ":"); i >= 0 {
+				domain = d[:i]
+				if i == len(d)-1 {
+					domain = "" // empty port
+				}
+			}
+
+			if len(validation.IsDNS1123Subdomain(domain)) > 0 {
+				errs = append(errs, errors.New(fmt.Sprintf("%s contains an invalid domain %q", AnnotationServiceDomainKey, d)))
+			}
+		}
+	}
+
+	return errs
+}
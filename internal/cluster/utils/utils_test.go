@@ -5,6 +5,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"kube-proxless/internal/utils"
 	"testing"
+	"time"
 )
 
 func Test_GenDomains(t *testing.T) {
@@ -68,6 +69,30 @@ func Test_GenDomains(t *testing.T) {
 	}
 }
 
+func TestParseDomainsPorts(t *testing.T) {
+	testCases := []struct {
+		domains          string
+		wantDomains      string
+		wantDomainsPorts map[string]string
+	}{
+		{"", "", map[string]string{}},
+		{"example.io", "example.io", map[string]string{}},
+		{"example.io,example.com", "example.io,example.com", map[string]string{}},
+		{
+			"example.io:8080,admin.example.io:admin,example.com",
+			"example.io,admin.example.io,example.com",
+			map[string]string{"example.io": "8080", "admin.example.io": "admin"},
+		},
+	}
+
+	for _, tc := range testCases {
+		gotDomains, gotDomainsPorts := ParseDomainsPorts(tc.domains)
+
+		assert.Equal(t, tc.wantDomains, gotDomains)
+		assert.Equal(t, tc.wantDomainsPorts, gotDomainsPorts)
+	}
+}
+
 func Test_IsAnnotationsProxlessCompatible(t *testing.T) {
 	testCases := []struct {
 		annotations map[string]string
@@ -92,6 +117,19 @@ func Test_IsAnnotationsProxlessCompatible(t *testing.T) {
 			},
 			true,
 		},
+		{
+			map[string]string{
+				AnnotationServiceDomainKey: "domain",
+				AnnotationEnabled:          "true",
+			},
+			true,
+		},
+		{
+			map[string]string{
+				AnnotationEnabled: "false",
+			},
+			false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -104,6 +142,22 @@ func Test_IsAnnotationsProxlessCompatible(t *testing.T) {
 	}
 }
 
+func TestSplitDeployments(t *testing.T) {
+	testCases := []struct {
+		deployName string
+		want       []string
+	}{
+		{"deploy-a", []string{"deploy-a"}},
+		{"deploy-a,deploy-b", []string{"deploy-a", "deploy-b"}},
+		{" deploy-a , deploy-b,", []string{"deploy-a", "deploy-b"}},
+		{"", nil},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, SplitDeployments(tc.deployName), tc.deployName)
+	}
+}
+
 func TestParseStringToIntPointer(t *testing.T) {
 	testCases := []struct {
 		s    string
@@ -123,6 +177,58 @@ func TestParseStringToIntPointer(t *testing.T) {
 	}
 }
 
+func TestParseStringToTimePointer(t *testing.T) {
+	testCases := []struct {
+		s    string
+		want *time.Time
+	}{
+		{"", nil},
+		{"notatime", nil},
+		{"2020-05-01T10:00:00Z", parseTimeToPointer(time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC))},
+	}
+
+	for _, tc := range testCases {
+		if tc.want != nil {
+			assert.True(t, tc.want.Equal(*ParseStringToTimePointer(tc.s)))
+		} else {
+			assert.Nil(t, ParseStringToTimePointer(tc.s))
+		}
+	}
+}
+
+func parseTimeToPointer(t time.Time) *time.Time {
+	return &t
+}
+
 func parseIntToPointer(i int) *int {
 	return &i
 }
+
+func TestValidateServiceAnnotations(t *testing.T) {
+	testCases := []struct {
+		annotations map[string]string
+		errsWanted  int
+	}{
+		{map[string]string{}, 0},
+		{map[string]string{
+			AnnotationServiceDeployKey:               "deploy",
+			AnnotationServiceDomainKey:               "example.io,admin.example.io:8001",
+			AnnotationServiceTTLSeconds:              "30",
+			AnnotationServiceReadinessTimeoutSeconds: "60",
+		}, 0},
+		{map[string]string{AnnotationServiceDeployKey: ""}, 1},
+		{map[string]string{AnnotationServiceTTLSeconds: "abc"}, 1},
+		{map[string]string{AnnotationServiceTTLSeconds: "0", AnnotationServiceReadinessTimeoutSeconds: "-1"}, 2},
+		{map[string]string{AnnotationServiceMinUptimeSeconds: "300"}, 0},
+		{map[string]string{AnnotationServiceMinUptimeSeconds: "0"}, 1},
+		{map[string]string{AnnotationServicePinnedUntil: "2020-06-01T18:00:00Z"}, 0},
+		{map[string]string{AnnotationServicePinnedUntil: "tomorrow"}, 1},
+		{map[string]string{AnnotationServiceDomainKey: "example.io,,admin.example.io:"}, 2},
+		{map[string]string{AnnotationServiceDomainKey: ":8080"}, 1},
+		{map[string]string{AnnotationServiceDomainKey: "Example.io,example_io,-example.io"}, 3},
+	}
+
+	for _, tc := range testCases {
+		assert.Len(t, ValidateServiceAnnotations(tc.annotations), tc.errsWanted, tc.annotations)
+	}
+}
@@ -1,49 +1,331 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	_ "github.com/joho/godotenv/autoload"
+	"io/ioutil"
 	"kube-proxless/internal/logger"
 	"os"
+	"sigs.k8s.io/yaml"
 	"strconv"
+	"strings"
 )
 
-var (
-	KubeConfigPath                        string
-	Port                                  string
-	MaxConsPerHost                        int
-	ProxlessNamespace                     string
-	ProxlessService                       string
-	NamespaceScope                        string
-	ServerlessTTLSeconds                  int
-	DeploymentReadinessTimeoutSeconds     int
-	RedisURL                              string
-	ScaleDownCheckIntervalSeconds         int
-	ServicesInformerResyncIntervalSeconds int
-)
+// the keys of the config file are the camelCase version of the env vars, e.g. `serverlessTTLSeconds`
+// the env vars have priority over the config file
+type Config struct {
+	LogLevel string `json:"logLevel"`
+
+	KubeConfigPath   string `json:"kubeConfigPath"`
+	Port             string `json:"port"`
+	MaxConsPerHost   int    `json:"maxConsPerHost"`
+	ProxyToEndpoints bool   `json:"proxyToEndpoints"`
+	LoadBalancing    string `json:"loadBalancing"`
+
+	ProxlessNamespace string `json:"proxlessNamespace"`
+	ProxlessService   string `json:"proxlessService"`
+	NamespaceScoped   bool   `json:"namespaceScoped"`
+	// ProxlessNamespace if NamespaceScoped, empty otherwise
+	NamespaceScope string `json:"-"`
+
+	ServerlessTTLSeconds                   int  `json:"serverlessTTLSeconds"`
+	MinUptimeSeconds                       int  `json:"minUptimeSeconds"`
+	AdaptiveTTL                            bool `json:"adaptiveTTL"`
+	AdaptiveTTLMaxSeconds                  int  `json:"adaptiveTTLMaxSeconds"`
+	DeploymentReadinessTimeoutSeconds      int  `json:"deploymentReadinessTimeoutSeconds"`
+	DeploymentReadinessPollIntervalSeconds int  `json:"deploymentReadinessPollIntervalSeconds"`
+
+	RedisURL                           string `json:"redisURL"`
+	RedisStateStore                    bool   `json:"redisStateStore"`
+	StateStoreTTLSeconds               int    `json:"stateStoreTTLSeconds"`
+	StateStoreReconcileIntervalSeconds int    `json:"stateStoreReconcileIntervalSeconds"`
+
+	ScaleDownCheckIntervalSeconds         int `json:"scaleDownCheckIntervalSeconds"`
+	ServicesInformerResyncIntervalSeconds int `json:"servicesInformerResyncIntervalSeconds"`
+	ProxlessServicesGCIntervalSeconds     int `json:"proxlessServicesGCIntervalSeconds"`
+	ConfigWatchIntervalSeconds            int `json:"configWatchIntervalSeconds"`
+
+	// readiness fails during the delay, then the in-flight requests are drained up to the grace period
+	// the idle keep-alive connections are closed after the idle timeout - they would block the drain otherwise
+	ShutdownDelaySeconds       int `json:"shutdownDelaySeconds"`
+	ShutdownGracePeriodSeconds int `json:"shutdownGracePeriodSeconds"`
+	IdleTimeoutSeconds         int `json:"idleTimeoutSeconds"`
+
+	PersistLastUsed                 bool `json:"persistLastUsed"`
+	PersistLastUsedIntervalSeconds  int  `json:"persistLastUsedIntervalSeconds"`
+	PersistLastUsedThresholdSeconds int  `json:"persistLastUsedThresholdSeconds"`
+
+	ProxlessRoutes bool `json:"proxlessRoutes"`
+	Discovery      bool `json:"discovery"`
+	NamespaceOptIn bool `json:"namespaceOptIn"`
+
+	SavingsCollectIntervalSeconds int     `json:"savingsCollectIntervalSeconds"`
+	SavingsCPUHourPrice           float64 `json:"savingsCPUHourPrice"`
+	SavingsMemoryGBHourPrice      float64 `json:"savingsMemoryGBHourPrice"`
+
+	PreWarm                     bool    `json:"preWarm"`
+	PreWarmCheckIntervalSeconds int     `json:"preWarmCheckIntervalSeconds"`
+	PreWarmLeadSeconds          int     `json:"preWarmLeadSeconds"`
+	PreWarmConfidence           float64 `json:"preWarmConfidence"`
+	PreWarmMinWeeks             int     `json:"preWarmMinWeeks"`
+
+	DryRun    bool   `json:"dryRun"`
+	AdminPort string `json:"adminPort"`
 
-func LoadEnvVars() {
-	KubeConfigPath = os.Getenv("KUBE_CONFIG_PATH")
+	WebhookEnabled  bool   `json:"webhookEnabled"`
+	WebhookPort     string `json:"webhookPort"`
+	WebhookCertFile string `json:"webhookCertFile"`
+	WebhookKeyFile  string `json:"webhookKeyFile"`
+}
+
+func NewDefaultConfig() *Config {
+	return &Config{
+		LogLevel: "info",
+
+		Port:           "80",
+		MaxConsPerHost: 10000,
+		LoadBalancing:  "round-robin",
+
+		ProxlessNamespace: "proxless",
+		ProxlessService:   "proxless",
+		NamespaceScoped:   true,
+		NamespaceScope:    "proxless",
+
+		ServerlessTTLSeconds:                   30,
+		AdaptiveTTLMaxSeconds:                  3600,
+		DeploymentReadinessTimeoutSeconds:      30,
+		DeploymentReadinessPollIntervalSeconds: 5,
+
+		StateStoreTTLSeconds:               86400,
+		StateStoreReconcileIntervalSeconds: 30,
 
-	Port = getString("PORT", "80")
-	MaxConsPerHost = getInt("MAX_CONS_PER_HOST", 10000)
+		ScaleDownCheckIntervalSeconds:         30,
+		ServicesInformerResyncIntervalSeconds: 60,
+		ProxlessServicesGCIntervalSeconds:     300,
+		ConfigWatchIntervalSeconds:            10,
 
-	ProxlessNamespace = getString("PROXLESS_NAMESPACE", "proxless")
-	ProxlessService = getString("PROXLESS_SERVICE", "proxless")
+		ShutdownDelaySeconds:       5,
+		ShutdownGracePeriodSeconds: 20,
+		IdleTimeoutSeconds:         10,
 
-	if getBool("NAMESPACE_SCOPED", true) {
-		NamespaceScope = ProxlessNamespace
+		PersistLastUsedIntervalSeconds:  60,
+		PersistLastUsedThresholdSeconds: 60,
+
+		SavingsCollectIntervalSeconds: 60,
+
+		PreWarmCheckIntervalSeconds: 60,
+		PreWarmLeadSeconds:          300,
+		PreWarmConfidence:           0.5,
+		PreWarmMinWeeks:             2,
+
+		AdminPort: "8081",
+
+		WebhookPort:     "8443",
+		WebhookCertFile: "/etc/proxless/webhook/tls.crt",
+		WebhookKeyFile:  "/etc/proxless/webhook/tls.key",
 	}
+}
 
-	ServerlessTTLSeconds = getInt("SERVERLESS_TTL_SECONDS", 30)
-	DeploymentReadinessTimeoutSeconds = getInt("DEPLOYMENT_READINESS_TIMEOUT_SECONDS", 30)
+// load the defaults, then the config file if `path` is not empty, then the env vars, then the flags
+func Load(path string, flags Flags) (*Config, error) {
+	cfg := NewDefaultConfig()
 
-	RedisURL = os.Getenv("REDIS_URL")
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
 
-	ScaleDownCheckIntervalSeconds = getInt("SCALE_DOWN_CHECK_INTERVAL_SECONDS", 30)
-	ServicesInformerResyncIntervalSeconds = getInt("SERVICES_INFORMER_RESYNC_INTERVAL_SECONDS", 60)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("could not read the config file %s: %s", path, err))
+		}
+
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, errors.New(fmt.Sprintf("could not parse the config file %s: %s", path, err))
+		}
+	}
+
+	errs := loadEnvVars(cfg)
+	errs = append(errs, applyFlags(cfg, flags)...)
+
+	if cfg.NamespaceScoped {
+		cfg.NamespaceScope = cfg.ProxlessNamespace
+	} else {
+		cfg.NamespaceScope = ""
+	}
+
+	errs = append(errs, cfg.Validate()...)
+
+	if len(errs) > 0 {
+		var msgs []string
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+
+		return nil, errors.New(fmt.Sprintf("invalid config: %s", strings.Join(msgs, ", ")))
+	}
+
+	return cfg, nil
 }
 
-func getString(key, fallback string) string {
+func loadEnvVars(cfg *Config) []error {
+	l := &envLoader{}
+
+	cfg.LogLevel = l.getString("LOG_LEVEL", cfg.LogLevel)
+
+	cfg.KubeConfigPath = l.getString("KUBE_CONFIG_PATH", cfg.KubeConfigPath)
+
+	cfg.Port = l.getString("PORT", cfg.Port)
+	cfg.MaxConsPerHost = l.getInt("MAX_CONS_PER_HOST", cfg.MaxConsPerHost)
+	cfg.ProxyToEndpoints = l.getBool("PROXY_TO_ENDPOINTS", cfg.ProxyToEndpoints)
+	cfg.LoadBalancing = l.getString("LOAD_BALANCING", cfg.LoadBalancing)
+
+	cfg.ProxlessNamespace = l.getString("PROXLESS_NAMESPACE", cfg.ProxlessNamespace)
+	cfg.ProxlessService = l.getString("PROXLESS_SERVICE", cfg.ProxlessService)
+	cfg.NamespaceScoped = l.getBool("NAMESPACE_SCOPED", cfg.NamespaceScoped)
+
+	cfg.ServerlessTTLSeconds = l.getInt("SERVERLESS_TTL_SECONDS", cfg.ServerlessTTLSeconds)
+	cfg.MinUptimeSeconds = l.getInt("MIN_UPTIME_SECONDS", cfg.MinUptimeSeconds)
+	cfg.AdaptiveTTL = l.getBool("ADAPTIVE_TTL", cfg.AdaptiveTTL)
+	cfg.AdaptiveTTLMaxSeconds = l.getInt("ADAPTIVE_TTL_MAX_SECONDS", cfg.AdaptiveTTLMaxSeconds)
+	cfg.DeploymentReadinessTimeoutSeconds = l.getInt(
+		"DEPLOYMENT_READINESS_TIMEOUT_SECONDS", cfg.DeploymentReadinessTimeoutSeconds)
+	cfg.DeploymentReadinessPollIntervalSeconds = l.getInt(
+		"DEPLOYMENT_READINESS_POLL_INTERVAL_SECONDS", cfg.DeploymentReadinessPollIntervalSeconds)
+
+	cfg.RedisURL = l.getString("REDIS_URL", cfg.RedisURL)
+	cfg.RedisStateStore = l.getBool("REDIS_STATE_STORE", cfg.RedisStateStore)
+	cfg.StateStoreTTLSeconds = l.getInt("STATE_STORE_TTL_SECONDS", cfg.StateStoreTTLSeconds)
+	cfg.StateStoreReconcileIntervalSeconds = l.getInt(
+		"STATE_STORE_RECONCILE_INTERVAL_SECONDS", cfg.StateStoreReconcileIntervalSeconds)
+
+	cfg.ScaleDownCheckIntervalSeconds = l.getInt(
+		"SCALE_DOWN_CHECK_INTERVAL_SECONDS", cfg.ScaleDownCheckIntervalSeconds)
+	cfg.ServicesInformerResyncIntervalSeconds = l.getInt(
+		"SERVICES_INFORMER_RESYNC_INTERVAL_SECONDS", cfg.ServicesInformerResyncIntervalSeconds)
+	cfg.ProxlessServicesGCIntervalSeconds = l.getInt(
+		"PROXLESS_SERVICES_GC_INTERVAL_SECONDS", cfg.ProxlessServicesGCIntervalSeconds)
+	cfg.ConfigWatchIntervalSeconds = l.getInt("CONFIG_WATCH_INTERVAL_SECONDS", cfg.ConfigWatchIntervalSeconds)
+
+	cfg.ShutdownDelaySeconds = l.getInt("SHUTDOWN_DELAY_SECONDS", cfg.ShutdownDelaySeconds)
+	cfg.ShutdownGracePeriodSeconds = l.getInt("SHUTDOWN_GRACE_PERIOD_SECONDS", cfg.ShutdownGracePeriodSeconds)
+	cfg.IdleTimeoutSeconds = l.getInt("IDLE_TIMEOUT_SECONDS", cfg.IdleTimeoutSeconds)
+
+	cfg.PersistLastUsed = l.getBool("PERSIST_LAST_USED", cfg.PersistLastUsed)
+	cfg.PersistLastUsedIntervalSeconds = l.getInt(
+		"PERSIST_LAST_USED_INTERVAL_SECONDS", cfg.PersistLastUsedIntervalSeconds)
+	cfg.PersistLastUsedThresholdSeconds = l.getInt(
+		"PERSIST_LAST_USED_THRESHOLD_SECONDS", cfg.PersistLastUsedThresholdSeconds)
+
+	cfg.ProxlessRoutes = l.getBool("PROXLESS_ROUTES", cfg.ProxlessRoutes)
+	cfg.Discovery = l.getBool("DISCOVERY", cfg.Discovery)
+	cfg.NamespaceOptIn = l.getBool("NAMESPACE_OPT_IN", cfg.NamespaceOptIn)
+
+	cfg.SavingsCollectIntervalSeconds = l.getInt(
+		"SAVINGS_COLLECT_INTERVAL_SECONDS", cfg.SavingsCollectIntervalSeconds)
+	cfg.SavingsCPUHourPrice = l.getFloat("SAVINGS_CPU_HOUR_PRICE", cfg.SavingsCPUHourPrice)
+	cfg.SavingsMemoryGBHourPrice = l.getFloat("SAVINGS_MEMORY_GB_HOUR_PRICE", cfg.SavingsMemoryGBHourPrice)
+
+	cfg.PreWarm = l.getBool("PREWARM", cfg.PreWarm)
+	cfg.PreWarmCheckIntervalSeconds = l.getInt("PREWARM_CHECK_INTERVAL_SECONDS", cfg.PreWarmCheckIntervalSeconds)
+	cfg.PreWarmLeadSeconds = l.getInt("PREWARM_LEAD_SECONDS", cfg.PreWarmLeadSeconds)
+	cfg.PreWarmConfidence = l.getFloat("PREWARM_CONFIDENCE", cfg.PreWarmConfidence)
+	cfg.PreWarmMinWeeks = l.getInt("PREWARM_MIN_WEEKS", cfg.PreWarmMinWeeks)
+
+	cfg.DryRun = l.getBool("DRY_RUN", cfg.DryRun)
+	cfg.AdminPort = l.getString("ADMIN_PORT", cfg.AdminPort)
+
+	cfg.WebhookEnabled = l.getBool("WEBHOOK_ENABLED", cfg.WebhookEnabled)
+	cfg.WebhookPort = l.getString("WEBHOOK_PORT", cfg.WebhookPort)
+	cfg.WebhookCertFile = l.getString("WEBHOOK_CERT_FILE", cfg.WebhookCertFile)
+	cfg.WebhookKeyFile = l.getString("WEBHOOK_KEY_FILE", cfg.WebhookKeyFile)
+
+	return l.errs
+}
+
+// return all the invalid settings instead of failing on the first one
+func (c *Config) Validate() []error {
+	var errs []error
+
+	for key, value := range map[string]string{
+		"port":        c.Port,
+		"adminPort":   c.AdminPort,
+		"webhookPort": c.WebhookPort,
+	} {
+		if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, errors.New(fmt.Sprintf("%s must be a port number - got %q", key, value)))
+		}
+	}
+
+	for key, value := range map[string]int{
+		"maxConsPerHost":                         c.MaxConsPerHost,
+		"serverlessTTLSeconds":                   c.ServerlessTTLSeconds,
+		"adaptiveTTLMaxSeconds":                  c.AdaptiveTTLMaxSeconds,
+		"deploymentReadinessTimeoutSeconds":      c.DeploymentReadinessTimeoutSeconds,
+		"deploymentReadinessPollIntervalSeconds": c.DeploymentReadinessPollIntervalSeconds,
+		"stateStoreTTLSeconds":                   c.StateStoreTTLSeconds,
+		"stateStoreReconcileIntervalSeconds":     c.StateStoreReconcileIntervalSeconds,
+		"scaleDownCheckIntervalSeconds":          c.ScaleDownCheckIntervalSeconds,
+		"servicesInformerResyncIntervalSeconds":  c.ServicesInformerResyncIntervalSeconds,
+		"proxlessServicesGCIntervalSeconds":      c.ProxlessServicesGCIntervalSeconds,
+		"configWatchIntervalSeconds":             c.ConfigWatchIntervalSeconds,
+		"shutdownGracePeriodSeconds":             c.ShutdownGracePeriodSeconds,
+		"idleTimeoutSeconds":                     c.IdleTimeoutSeconds,
+		"persistLastUsedIntervalSeconds":         c.PersistLastUsedIntervalSeconds,
+		"savingsCollectIntervalSeconds":          c.SavingsCollectIntervalSeconds,
+		"preWarmCheckIntervalSeconds":            c.PreWarmCheckIntervalSeconds,
+		"preWarmMinWeeks":                        c.PreWarmMinWeeks,
+	} {
+		if value < 1 {
+			errs = append(errs, errors.New(fmt.Sprintf("%s must be a positive integer - got %d", key, value)))
+		}
+	}
+
+	for key, value := range map[string]int{
+		"minUptimeSeconds":                c.MinUptimeSeconds,
+		"persistLastUsedThresholdSeconds": c.PersistLastUsedThresholdSeconds,
+		"preWarmLeadSeconds":              c.PreWarmLeadSeconds,
+		"shutdownDelaySeconds":            c.ShutdownDelaySeconds,
+	} {
+		if value < 0 {
+			errs = append(errs, errors.New(fmt.Sprintf("%s must not be negative - got %d", key, value)))
+		}
+	}
+
+	if c.IdleTimeoutSeconds >= c.ShutdownGracePeriodSeconds {
+		errs = append(errs, errors.New(fmt.Sprintf(
+			"idleTimeoutSeconds must be lower than shutdownGracePeriodSeconds - got %d", c.IdleTimeoutSeconds)))
+	}
+
+	if c.SavingsCPUHourPrice < 0 || c.SavingsMemoryGBHourPrice < 0 {
+		errs = append(errs, errors.New("savingsCPUHourPrice and savingsMemoryGBHourPrice must not be negative"))
+	}
+
+	if c.PreWarmConfidence < 0 || c.PreWarmConfidence > 1 {
+		errs = append(errs, errors.New(fmt.Sprintf("preWarmConfidence must be between 0 and 1 - got %v", c.PreWarmConfidence)))
+	}
+
+	if c.LoadBalancing != "round-robin" && c.LoadBalancing != "least-connections" {
+		errs = append(errs, errors.New(fmt.Sprintf(
+			"loadBalancing must be round-robin or least-connections - got %q", c.LoadBalancing)))
+	}
+
+	if !logger.IsValidLevel(c.LogLevel) {
+		errs = append(errs, errors.New(fmt.Sprintf(
+			"logLevel must be verbose, debug, info, error, fatal or panic - got %q", c.LogLevel)))
+	}
+
+	if c.ProxlessNamespace == "" || c.ProxlessService == "" {
+		errs = append(errs, errors.New("proxlessNamespace and proxlessService must not be empty"))
+	}
+
+	return errs
+}
+
+// collect the parsing errors of the env vars
+type envLoader struct {
+	errs []error
+}
+
+func (l *envLoader) getString(key, fallback string) string {
 	var result string
 	if os.Getenv(key) != "" {
 		result = os.Getenv(key)
@@ -56,12 +338,13 @@ func getString(key, fallback string) string {
 	return result
 }
 
-func getInt(key string, fallback int) int {
+func (l *envLoader) getInt(key string, fallback int) int {
 	var result int
 	if os.Getenv(key) != "" {
 		intVal, err := strconv.Atoi(os.Getenv(key))
 		if err != nil {
-			logger.Panicf(err, "error parsing int from env var: %s", key)
+			l.errs = append(l.errs, errors.New(fmt.Sprintf("env var %s must be an integer - got %q", key, os.Getenv(key))))
+			return fallback
 		}
 		result = intVal
 	} else {
@@ -73,12 +356,31 @@ func getInt(key string, fallback int) int {
 	return result
 }
 
-func getBool(key string, fallback bool) bool {
+func (l *envLoader) getFloat(key string, fallback float64) float64 {
+	var result float64
+	if os.Getenv(key) != "" {
+		floatVal, err := strconv.ParseFloat(os.Getenv(key), 64)
+		if err != nil {
+			l.errs = append(l.errs, errors.New(fmt.Sprintf("env var %s must be a number - got %q", key, os.Getenv(key))))
+			return fallback
+		}
+		result = floatVal
+	} else {
+		result = fallback
+	}
+
+	logger.Debugf("Successfully loaded env var: %s=%v", key, result)
+
+	return result
+}
+
+func (l *envLoader) getBool(key string, fallback bool) bool {
 	var result bool
 	if os.Getenv(key) != "" {
 		boolVal, err := strconv.ParseBool(os.Getenv(key))
 		if err != nil {
-			logger.Panicf(err, "error parsing bool from env var: %s", key)
+			l.errs = append(l.errs, errors.New(fmt.Sprintf("env var %s must be a boolean - got %q", key, os.Getenv(key))))
+			return fallback
 		}
 		result = boolVal
 	} else {
@@ -1,13 +1,104 @@
 package config
 
 import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
-func TestLoadEnvVars(t *testing.T) {
-	// make sure it does not panic
-	LoadEnvVars()
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proxless")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		content string
+		env     map[string]string
+		wantErr bool
+		want    func(cfg *Config)
+	}{
+		{"", nil, false, func(cfg *Config) {}},
+		{"serverlessTTLSeconds: 120\nnamespaceScoped: false\n", nil, false, func(cfg *Config) {
+			cfg.ServerlessTTLSeconds = 120
+			cfg.NamespaceScoped = false
+			cfg.NamespaceScope = ""
+		}},
+		// the env vars have priority over the file
+		{"serverlessTTLSeconds: 120\n", map[string]string{"SERVERLESS_TTL_SECONDS": "60"}, false, func(cfg *Config) {
+			cfg.ServerlessTTLSeconds = 60
+		}},
+		{"serverlessTTLSecond: 120\n", nil, true, nil},
+		{"serverlessTTLSeconds: abc\n", nil, true, nil},
+		{"serverlessTTLSeconds: 0\n", nil, true, nil},
+		{"", map[string]string{"SERVERLESS_TTL_SECONDS": "abc"}, true, nil},
+	}
+
+	for i, tc := range testCases {
+		path := filepath.Join(dir, "config.yaml")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(tc.content), 0644))
+
+		for k, v := range tc.env {
+			_ = os.Setenv(k, v)
+		}
+
+		cfg, err := Load(path, nil)
+
+		for k := range tc.env {
+			_ = os.Unsetenv(k)
+		}
+
+		if tc.wantErr {
+			assert.Error(t, err, i)
+			continue
+		}
+
+		want := NewDefaultConfig()
+		tc.want(want)
+		assert.NoError(t, err, i)
+		assert.Equal(t, want, cfg, i)
+	}
+
+	_, err = Load(filepath.Join(dir, "unknown.yaml"), nil)
+	assert.Error(t, err)
+
+	// no config file
+	cfg, err := Load("", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, NewDefaultConfig(), cfg)
+
+	// the flags have priority over the env vars
+	_ = os.Setenv("SERVERLESS_TTL_SECONDS", "60")
+	cfg, err = Load("", Flags{"serverlessTTLSeconds": "90"})
+	_ = os.Unsetenv("SERVERLESS_TTL_SECONDS")
+	assert.NoError(t, err)
+	assert.Equal(t, 90, cfg.ServerlessTTLSeconds)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		update     func(cfg *Config)
+		errsWanted int
+	}{
+		{func(cfg *Config) {}, 0},
+		{func(cfg *Config) { cfg.Port = "http" }, 1},
+		{func(cfg *Config) { cfg.AdminPort = "70000" }, 1},
+		{func(cfg *Config) { cfg.ScaleDownCheckIntervalSeconds = 0; cfg.MinUptimeSeconds = -1 }, 2},
+		{func(cfg *Config) { cfg.PreWarmConfidence = 1.5 }, 1},
+		{func(cfg *Config) { cfg.SavingsCPUHourPrice = -1 }, 1},
+		{func(cfg *Config) { cfg.LoadBalancing = "random" }, 1},
+		{func(cfg *Config) { cfg.LogLevel = "trace" }, 1},
+		{func(cfg *Config) { cfg.LogLevel = "DEBUG" }, 0},
+		{func(cfg *Config) { cfg.ProxlessService = "" }, 1},
+		{func(cfg *Config) { cfg.IdleTimeoutSeconds = 20 }, 1},
+	}
+
+	for i, tc := range testCases {
+		cfg := NewDefaultConfig()
+		tc.update(cfg)
+		assert.Len(t, cfg.Validate(), tc.errsWanted, i)
+	}
 }
 
 func Test_getString(t *testing.T) {
@@ -22,7 +113,8 @@ func Test_getString(t *testing.T) {
 
 	for _, tc := range testCases {
 		_ = os.Setenv(env, tc.value)
-		got := getString(env, tc.defaultValue)
+		l := &envLoader{}
+		got := l.getString(env, tc.defaultValue)
 
 		if got != tc.want {
 			t.Errorf("getString(%s, %s) = %s; want = %s", tc.value, tc.defaultValue, got, tc.want)
@@ -35,7 +127,7 @@ func Test_getInt(t *testing.T) {
 	testCases := []struct {
 		value              string
 		defaultValue, want int
-		mustPanic          bool
+		wantErr            bool
 	}{
 		{"", 0, 0, false},
 		{"", 1, 1, false},
@@ -45,31 +137,22 @@ func Test_getInt(t *testing.T) {
 
 	for _, tc := range testCases {
 		_ = os.Setenv(env, tc.value)
-		got := assertParseIntPanic(t, env, tc.defaultValue, tc.mustPanic)
+		l := &envLoader{}
+		got := l.getInt(env, tc.defaultValue)
 
-		if !tc.mustPanic && got != tc.want {
-			t.Errorf("getInt(%s, %d) = %d; want = %d", env, tc.defaultValue, got, tc.want)
+		if got != tc.want || (len(l.errs) > 0) != tc.wantErr {
+			t.Errorf("getInt(%s, %d) = %d, %v; want = %d, wantErr = %t",
+				env, tc.defaultValue, got, l.errs, tc.want, tc.wantErr)
 		}
 	}
 }
 
-func assertParseIntPanic(t *testing.T, key string, defaultValue int, mustPanic bool) int {
-	defer func() {
-		if r := recover(); (r != nil) != mustPanic {
-			t.Errorf("getInt(%s, %d); panic = %t; mustPanic = %t",
-				key, defaultValue, r != nil, mustPanic)
-		}
-	}()
-
-	return getInt(key, defaultValue)
-}
-
 func Test_getBool(t *testing.T) {
 	env := "env"
 	testCases := []struct {
 		value              string
 		defaultValue, want bool
-		mustPanic          bool
+		wantErr            bool
 	}{
 		{"", true, true, false},
 		{"false", true, false, false},
@@ -78,21 +161,37 @@ func Test_getBool(t *testing.T) {
 
 	for _, tc := range testCases {
 		_ = os.Setenv(env, tc.value)
-		got := assertParseBoolPanic(t, env, tc.defaultValue, tc.mustPanic)
+		l := &envLoader{}
+		got := l.getBool(env, tc.defaultValue)
 
-		if !tc.mustPanic && got != tc.want {
-			t.Errorf("getBool(%s, %t) = %t; want = %t", env, tc.defaultValue, got, tc.want)
+		if got != tc.want || (len(l.errs) > 0) != tc.wantErr {
+			t.Errorf("getBool(%s, %t) = %t, %v; want = %t, wantErr = %t",
+				env, tc.defaultValue, got, l.errs, tc.want, tc.wantErr)
 		}
 	}
 }
 
-func assertParseBoolPanic(t *testing.T, key string, defaultValue, mustPanic bool) bool {
-	defer func() {
-		if r := recover(); (r != nil) != mustPanic {
-			t.Errorf("getBool(%s, %t); panic = %t; mustPanic = %t",
-				key, defaultValue, r != nil, mustPanic)
-		}
-	}()
+func Test_getFloat(t *testing.T) {
+	env := "env"
+	testCases := []struct {
+		value              string
+		defaultValue, want float64
+		wantErr            bool
+	}{
+		{"", 0, 0, false},
+		{"", 1.5, 1.5, false},
+		{"something", 2, 2, true},
+		{"0.031", 2, 0.031, false},
+	}
 
-	return getBool(key, defaultValue)
+	for _, tc := range testCases {
+		_ = os.Setenv(env, tc.value)
+		l := &envLoader{}
+		got := l.getFloat(env, tc.defaultValue)
+
+		if got != tc.want || (len(l.errs) > 0) != tc.wantErr {
+			t.Errorf("getFloat(%s, %f) = %f, %v; want = %f, wantErr = %t",
+				env, tc.defaultValue, got, l.errs, tc.want, tc.wantErr)
+		}
+	}
 }
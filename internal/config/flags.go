@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// value of the command line flags set by the user, by config key
+// the flags mirror the keys of the config file in kebab-case, e.g. `--serverless-ttl-seconds` for `serverlessTTLSeconds`
+// they have priority over the env vars and the config file
+type Flags map[string]string
+
+func RegisterFlags(fs *flag.FlagSet) Flags {
+	flags := Flags{}
+	defaults := reflect.ValueOf(*NewDefaultConfig())
+
+	for i := 0; i < defaults.NumField(); i++ {
+		key := getConfigKey(defaults.Type().Field(i))
+
+		// derived from the other settings
+		if key == "-" {
+			continue
+		}
+
+		fs.Var(&flagValue{
+			key:   key,
+			kind:  defaults.Field(i).Kind(),
+			def:   fmt.Sprint(defaults.Field(i).Interface()),
+			flags: flags,
+		}, toKebabCase(key), fmt.Sprintf("config key %s", key))
+	}
+
+	return flags
+}
+
+type flagValue struct {
+	key   string
+	kind  reflect.Kind
+	def   string
+	flags Flags
+}
+
+func (v *flagValue) String() string {
+	if v.flags != nil {
+		if value, ok := v.flags[v.key]; ok {
+			return value
+		}
+	}
+
+	return v.def
+}
+
+// reject the invalid values while parsing the flags
+func (v *flagValue) Set(value string) error {
+	if _, err := parseValue(v.kind, value); err != nil {
+		return err
+	}
+
+	v.flags[v.key] = value
+
+	return nil
+}
+
+// `--dry-run` is the same as `--dry-run=true`
+func (v *flagValue) IsBoolFlag() bool {
+	return v.kind == reflect.Bool
+}
+
+func applyFlags(cfg *Config, flags Flags) []error {
+	var errs []error
+
+	v := reflect.ValueOf(cfg).Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		key := getConfigKey(v.Type().Field(i))
+
+		raw, ok := flags[key]
+
+		if !ok {
+			continue
+		}
+
+		value, err := parseValue(v.Field(i).Kind(), raw)
+
+		if err != nil {
+			errs = append(errs, errors.New(fmt.Sprintf("flag --%s %s", toKebabCase(key), err)))
+			continue
+		}
+
+		v.Field(i).Set(reflect.ValueOf(value))
+	}
+
+	return errs
+}
+
+func parseValue(kind reflect.Kind, raw string) (interface{}, error) {
+	switch kind {
+	case reflect.Int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("must be an integer - got %q", raw))
+		}
+		return value, nil
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("must be a number - got %q", raw))
+		}
+		return value, nil
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("must be a boolean - got %q", raw))
+		}
+		return value, nil
+	default:
+		return raw, nil
+	}
+}
+
+func getConfigKey(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+// serverlessTTLSeconds -> serverless-ttl-seconds
+func toKebabCase(key string) string {
+	runes := []rune(key)
+	var sb strings.Builder
+
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previousIsLower := unicode.IsLower(runes[i-1])
+			// end of an acronym, e.g. the `S` of `TTLSeconds`
+			nextIsLower := unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if previousIsLower || nextIsLower {
+				sb.WriteRune('-')
+			}
+		}
+
+		sb.WriteRune(unicode.ToLower(r))
+	}
+
+	return sb.String()
+}
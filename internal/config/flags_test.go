@@ -0,0 +1,67 @@
+package config
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func Test_toKebabCase(t *testing.T) {
+	testCases := []struct {
+		key, want string
+	}{
+		{"port", "port"},
+		{"serverlessTTLSeconds", "serverless-ttl-seconds"},
+		{"adaptiveTTL", "adaptive-ttl"},
+		{"redisURL", "redis-url"},
+		{"savingsMemoryGBHourPrice", "savings-memory-gb-hour-price"},
+		{"preWarmConfidence", "pre-warm-confidence"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, toKebabCase(tc.key))
+	}
+}
+
+func TestRegisterFlags(t *testing.T) {
+	testCases := []struct {
+		args    []string
+		wantErr bool
+		want    Flags
+	}{
+		{[]string{}, false, Flags{}},
+		{[]string{"--serverless-ttl-seconds=60", "--dry-run", "--redis-url", "redis:6379"}, false, Flags{
+			"serverlessTTLSeconds": "60", "dryRun": "true", "redisURL": "redis:6379"}},
+		{[]string{"--serverless-ttl-seconds=abc"}, true, nil},
+		{[]string{"--unknown"}, true, nil},
+	}
+
+	for _, tc := range testCases {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		flags := RegisterFlags(fs)
+
+		err := fs.Parse(tc.args)
+
+		if tc.wantErr {
+			assert.Error(t, err, tc.args)
+			continue
+		}
+
+		assert.NoError(t, err, tc.args)
+		assert.Equal(t, tc.want, flags, tc.args)
+	}
+}
+
+func Test_applyFlags(t *testing.T) {
+	cfg := NewDefaultConfig()
+
+	errs := applyFlags(cfg, Flags{"serverlessTTLSeconds": "60", "preWarmConfidence": "0.8", "port": "8080"})
+	assert.Empty(t, errs)
+	assert.Equal(t, 60, cfg.ServerlessTTLSeconds)
+	assert.Equal(t, 0.8, cfg.PreWarmConfidence)
+	assert.Equal(t, "8080", cfg.Port)
+
+	assert.Len(t, applyFlags(cfg, Flags{"dryRun": "maybe"}), 1)
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"kube-proxless/internal/logger"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the components read the config through the provider on each use
+// so that the reloadable settings are applied without restarting proxless
+type Provider struct {
+	config *Config
+	lock   sync.RWMutex
+}
+
+func NewProvider(cfg *Config) *Provider {
+	return &Provider{
+		config: cfg,
+		lock:   sync.RWMutex{},
+	}
+}
+
+// do not modify the returned config - it is replaced, not updated, when the config is reloaded
+func (p *Provider) Get() *Config {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.config
+}
+
+// apply the reloadable settings of `next`
+// return the settings that changed but require a restart
+func (p *Provider) reload(next *Config) []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	reloaded := *p.config
+
+	reloaded.LogLevel = next.LogLevel
+	reloaded.ServerlessTTLSeconds = next.ServerlessTTLSeconds
+	reloaded.MinUptimeSeconds = next.MinUptimeSeconds
+	reloaded.AdaptiveTTL = next.AdaptiveTTL
+	reloaded.AdaptiveTTLMaxSeconds = next.AdaptiveTTLMaxSeconds
+	reloaded.DeploymentReadinessTimeoutSeconds = next.DeploymentReadinessTimeoutSeconds
+	reloaded.SavingsCPUHourPrice = next.SavingsCPUHourPrice
+	reloaded.SavingsMemoryGBHourPrice = next.SavingsMemoryGBHourPrice
+	reloaded.PreWarmLeadSeconds = next.PreWarmLeadSeconds
+	reloaded.PreWarmConfidence = next.PreWarmConfidence
+	reloaded.PreWarmMinWeeks = next.PreWarmMinWeeks
+
+	if reloaded.LogLevel != p.config.LogLevel {
+		logger.SetLevel(reloaded.LogLevel)
+	}
+
+	p.config = &reloaded
+
+	return diffConfig(&reloaded, next)
+}
+
+// the json keys of the settings that differ
+func diffConfig(a, b *Config) []string {
+	var keys []string
+
+	va := reflect.ValueOf(*a)
+	vb := reflect.ValueOf(*b)
+
+	for i := 0; i < va.NumField(); i++ {
+		key := getConfigKey(va.Type().Field(i))
+
+		// derived from the other settings
+		if key == "-" {
+			continue
+		}
+
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// reload the config when the content of the file changes - a kubernetes configmap is updated in place
+// an invalid config is logged and ignored
+func (p *Provider) RunWatcher(path string, flags Flags, checkInterval int) {
+	logger.Infof("Starting Config Watcher on %s...", path)
+
+	content, _ := ioutil.ReadFile(path)
+
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Errorf(nil, "Config Watcher panic. Restarting...")
+			p.RunWatcher(path, flags, checkInterval)
+		}
+	}()
+
+	for {
+		time.Sleep(time.Duration(checkInterval) * time.Second)
+
+		next, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			logger.Errorf(err, "Could not read the config file %s", path)
+			continue
+		}
+
+		if bytes.Equal(content, next) {
+			continue
+		}
+
+		content = next
+
+		if err := p.reloadFromFile(path, flags); err != nil {
+			logger.Errorf(err, "Config file %s not reloaded", path)
+		}
+	}
+}
+
+func (p *Provider) reloadFromFile(path string, flags Flags) error {
+	cfg, err := Load(path, flags)
+
+	if err != nil {
+		return err
+	}
+
+	ignored := p.reload(cfg)
+
+	logger.Infof("Config file %s reloaded", path)
+
+	if len(ignored) > 0 {
+		logger.Warnf(nil, "Settings %s changed in %s - restart proxless to apply them",
+			strings.Join(ignored, ", "), path)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProvider_reload(t *testing.T) {
+	p := NewProvider(NewDefaultConfig())
+	previous := p.Get()
+
+	next := NewDefaultConfig()
+	next.ServerlessTTLSeconds = 120
+	next.DeploymentReadinessTimeoutSeconds = 60
+	next.Port = "8080"
+	next.DryRun = true
+
+	assert.Equal(t, []string{"port", "dryRun"}, p.reload(next))
+
+	assert.Equal(t, 120, p.Get().ServerlessTTLSeconds)
+	assert.Equal(t, 60, p.Get().DeploymentReadinessTimeoutSeconds)
+	assert.Equal(t, "80", p.Get().Port)
+	assert.False(t, p.Get().DryRun)
+
+	// the previous config is not modified
+	assert.Equal(t, NewDefaultConfig(), previous)
+}
+
+func TestProvider_reloadFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proxless")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("minUptimeSeconds: 300\n"), 0644))
+
+	p := NewProvider(NewDefaultConfig())
+	assert.NoError(t, p.reloadFromFile(path, nil))
+	assert.Equal(t, 300, p.Get().MinUptimeSeconds)
+
+	// an invalid config is ignored
+	assert.NoError(t, ioutil.WriteFile(path, []byte("minUptimeSeconds: -1\n"), 0644))
+	assert.Error(t, p.reloadFromFile(path, nil))
+	assert.Equal(t, 300, p.Get().MinUptimeSeconds)
+}
+
+func Test_diffConfig(t *testing.T) {
+	a := NewDefaultConfig()
+	b := NewDefaultConfig()
+	assert.Empty(t, diffConfig(a, b))
+
+	b.NamespaceScoped = false
+	b.NamespaceScope = ""
+	assert.Equal(t, []string{"namespaceScoped"}, diffConfig(a, b))
+}
@@ -1,12 +1,18 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"kube-proxless/internal/cluster"
 	"kube-proxless/internal/config"
 	"kube-proxless/internal/logger"
 	"kube-proxless/internal/memory"
 	"kube-proxless/internal/model"
 	"kube-proxless/internal/pubsub"
+	"kube-proxless/internal/store"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,22 +20,64 @@ type Interface interface {
 	GetRouteByDomainFromMemory(domain string) (*model.Route, error)
 	UpdateLastUsedInMemory(id string) error
 	UpdateIsRunningInMemory(id string) error
-	ScaleUpDeployment(name, namespace string, readinessTimeoutSeconds int) error
-	RunDownScaler(checkInterval int)
-	RunServicesEngine()
+	ScaleUpDeployment(deploymentName, serviceName, namespace string, readinessTimeoutSeconds int) error
+	RunDownScaler(ctx context.Context, checkInterval int)
+	RunServicesEngine(ctx context.Context)
+	RunProxlessRoutesEngine(ctx context.Context)
+	RunDiscoveryEngine(ctx context.Context)
+	RunStateReconciler(ctx context.Context, reconcileInterval int)
+	RunLastUsedPersister(ctx context.Context, persistInterval, persistThreshold int)
+	RunProxlessServicesCollector(ctx context.Context, collectInterval int)
+	GetDryRunReport() []DryRunRouteReport
+	GetDryRunActions() []cluster.DryRunActionReport
+	RunSavingsCollector(ctx context.Context, collectInterval int)
+	GetSavingsReport() SavingsReport
+	RunPreWarmer(ctx context.Context, checkInterval int)
+	GetPreWarmReport() []PreWarmRouteReport
+	PinRoute(id string, until time.Time) error
+	GetPinnedRoutes() []PinnedRouteReport
+	ValidateRoute(id string, deployNames []string, namespace string, domains []string) []error
+	Drain()
+	IsDraining() bool
+	CheckLiveness() []error
+	CheckReadiness() []error
 }
 
 type controller struct {
 	memory  memory.Interface
 	cluster cluster.Interface
 	pubsub  pubsub.Interface
+	store   store.Interface
+	config  *config.Provider
+	// lastUsed written in the cluster for each route - only used by the lastUsed persister
+	lastUsedPersisted map[string]time.Time
+	// projected scale downs/ups - only used in dry-run mode
+	dryRun *dryRunReport
+	// resources saved while the deployments are scaled down
+	savings *savingsTracker
+	// traffic histograms and predictions of the pre-warmer
+	preWarmer *preWarmer
+	// 1 once proxless is shutting down - read by the servers with `atomic`
+	draining int32
+	// heartbeats of the DownScaler and sync of the engines
+	health *healthTracker
 }
 
-func NewController(memory memory.Interface, cluster cluster.Interface, ps pubsub.Interface) *controller {
+func NewController(
+	memory memory.Interface, cluster cluster.Interface, ps pubsub.Interface, st store.Interface,
+	cfg *config.Provider) *controller {
 	return &controller{
 		memory:  memory,
 		cluster: cluster,
 		pubsub:  ps,
+		store:   st,
+		config:  cfg,
+
+		lastUsedPersisted: map[string]time.Time{},
+		dryRun:            newDryRunReport(),
+		savings:           newSavingsTracker(),
+		preWarmer:         newPreWarmer(),
+		health:            newHealthTracker(time.Now()),
 	}
 }
 
@@ -39,10 +87,24 @@ func (c *controller) GetRouteByDomainFromMemory(domain string) (*model.Route, er
 
 func (c *controller) UpdateLastUsedInMemory(id string) error {
 	now := time.Now()
+	if c.config.Get().DryRun && c.dryRun.scaleUp(id, now) {
+		logger.Infof("[dry-run] Would have scaled up the deployment of route %s", id)
+	}
+
+	c.preWarmer.record(id, now)
+
+	return updateLastUsed(c, id, now)
+}
+
+func updateLastUsed(c *controller, id string, now time.Time) error {
 	if c.pubsub != nil {
 		c.pubsub.PublishLastUsed(id, now)
 	}
 
+	if c.store != nil {
+		c.store.SetLastUsed(id, now)
+	}
+
 	return c.memory.UpdateLastUsed(id, now)
 }
 
@@ -54,28 +116,126 @@ func (c *controller) UpdateIsRunningInMemory(id string) error {
 	return c.memory.UpdateIsRunning(id, true)
 }
 
-func (c *controller) ScaleUpDeployment(name, namespace string, readinessTimeoutSeconds int) error {
-	return c.cluster.ScaleUpDeployment(name, namespace, readinessTimeoutSeconds)
+func (c *controller) ScaleUpDeployment(
+	deploymentName, serviceName, namespace string, readinessTimeoutSeconds int) error {
+	if c.config.Get().DryRun {
+		logger.Infof("[dry-run] Would scale up deployment %s.%s", deploymentName, namespace)
+		return nil
+	}
+
+	return c.cluster.ScaleUpDeployment(deploymentName, serviceName, namespace, readinessTimeoutSeconds)
+}
+
+// same as a request - the deployments are scaled up concurrently
+func wakeUpRoutes(c *controller, routes []model.Route) []error {
+	var errs []error
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, route := range routes {
+		_ = c.UpdateIsRunningInMemory(route.GetId())
+
+		wg.Add(1)
+		go func(route model.Route) {
+			defer wg.Done()
+
+			if err := c.ScaleUpDeployment(
+				route.GetDeployment(), route.GetService(), route.GetNamespace(),
+				getReadinessTimeoutSeconds(c, route)); err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+			}
+		}(route)
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+func getReadinessTimeoutSeconds(c *controller, route model.Route) int {
+	if route.GetReadinessTimeoutSeconds() != nil {
+		return *route.GetReadinessTimeoutSeconds()
+	}
+
+	return c.config.Get().DeploymentReadinessTimeoutSeconds
 }
 
-func (c *controller) RunDownScaler(checkInterval int) {
+func (c *controller) GetDryRunReport() []DryRunRouteReport {
+	return c.dryRun.get(time.Now())
+}
+
+// the writes on the cluster skipped in dry-run mode
+func (c *controller) GetDryRunActions() []cluster.DryRunActionReport {
+	return c.cluster.GetDryRunActions()
+}
+
+// return the errors that would prevent the route from being added in memory
+func (c *controller) ValidateRoute(id string, deployNames []string, namespace string, domains []string) []error {
+	var errs []error
+
+	for _, deployName := range deployNames {
+		if exists, err := c.cluster.DeploymentExists(deployName, namespace); err != nil {
+			errs = append(errs, err)
+		} else if !exists {
+			errs = append(errs, errors.New(fmt.Sprintf("Deployment %s.%s not found", deployName, namespace)))
+		}
+
+		if route, err := c.memory.GetRouteByDeployment(deployName, namespace); err == nil && route.GetId() != id {
+			errs = append(errs, errors.New(
+				fmt.Sprintf("Deployment %s.%s is already owned by %s", deployName, namespace, route.GetId())))
+		}
+	}
+
+	for _, domain := range domains {
+		if route, err := c.memory.GetRouteByDomain(domain); err == nil && route.GetId() != id {
+			errs = append(errs, errors.New(fmt.Sprintf("Domain %s is already owned by %s", domain, route.GetId())))
+		}
+	}
+
+	return errs
+}
+
+// the readiness fails and the proxy closes the keep-alive connections - the in-flight requests are still served
+func (c *controller) Drain() {
+	atomic.StoreInt32(&c.draining, 1)
+}
+
+func (c *controller) IsDraining() bool {
+	return atomic.LoadInt32(&c.draining) == 1
+}
+
+// stop when `ctx` is done
+func (c *controller) RunDownScaler(ctx context.Context, checkInterval int) {
 	logger.Infof("Starting DownScaler...")
 
 	defer func() {
 		if err := recover(); err != nil {
 			logger.Errorf(nil, "DownScaler panic. Restarting...")
-			c.RunDownScaler(checkInterval)
+			c.RunDownScaler(ctx, checkInterval)
 		}
 	}()
 
 	for {
+		c.health.beatDownScaler(time.Now())
+
 		errs := scaleDownDeployments(c)
 
 		for _, err := range errs {
 			logger.Errorf(err, "Error during scale down")
 		}
 
-		time.Sleep(time.Duration(checkInterval) * time.Second)
+		for _, err := range wakeUpPinnedRoutes(c, time.Now()) {
+			logger.Errorf(err, "Error waking up pinned route")
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("DownScaler stopped")
+			return
+		case <-time.After(time.Duration(checkInterval) * time.Second):
+		}
 	}
 }
 
@@ -85,7 +245,21 @@ func scaleDownDeployments(c *controller) []error {
 	var errs []error
 
 	for _, route := range deploymentsToScaleDown {
-		err := c.cluster.ScaleDownDeployment(route.GetDeployment(), route.GetNamespace())
+		// already scaled down - otherwise the deployment would be patched again at every interval
+		if !route.GetIsRunning() {
+			continue
+		}
+
+		// the deployment keeps running - the route is only recorded in the dry-run report
+		if c.config.Get().DryRun {
+			if c.dryRun.scaleDown(route, time.Now()) {
+				logger.Infof("[dry-run] Would scale down deployment %s.%s", route.GetDeployment(), route.GetNamespace())
+			}
+			continue
+		}
+
+		err := c.cluster.ScaleDownDeployment(
+			route.GetDeployment(), route.GetService(), route.GetNamespace(), route.GetLastUsed())
 
 		if err != nil {
 			errs = append(errs, err)
@@ -101,43 +275,440 @@ func scaleDownDeployments(c *controller) []error {
 	return errs
 }
 
-func (c *controller) RunServicesEngine() {
+func (c *controller) RunServicesEngine(ctx context.Context) {
 	logger.Infof("Starting Services Engine...")
 
 	defer func() {
 		if err := recover(); err != nil {
 			logger.Errorf(nil, "Services Engine panic. Restarting...")
-			c.RunServicesEngine()
+			c.RunServicesEngine(ctx)
 		}
 	}()
 
+	c.health.setServicesEngineRunning(true)
+	defer c.health.setServicesEngineRunning(false)
+
+	cfg := c.config.Get()
+
 	c.cluster.RunServicesEngine(
-		config.NamespaceScope,
-		config.ProxlessService,
-		config.ProxlessNamespace,
-		func(
-			id, name, port, deployName, namespace string,
-			domains []string, isRunning bool,
-			ttlSeconds, readinessTimeoutSeconds *int) error {
-			if c.pubsub != nil {
-				c.pubsub.SubscribeLastUsed(id, c.memory.UpdateLastUsed)
-				c.pubsub.SubscribeIsRunning(id, c.memory.UpdateIsRunning)
-			}
+		ctx,
+		cfg.NamespaceScope,
+		cfg.ProxlessService,
+		cfg.ProxlessNamespace,
+		func(route *model.Route) error {
+			return upsertRouteInMemory(c, route)
+		},
+		func(id string) error {
+			return deleteRouteFromMemory(c, id)
+		},
+		func(deployName, namespace string, replicas, availableReplicas int) error {
+			return updateReplicasInMemory(c, deployName, namespace, replicas, availableReplicas)
+		},
+		func(id string, endpoints []string) error {
+			return updateEndpointsInMemory(c, id, endpoints)
+		},
+		func() {
+			c.health.setEngineSynced(servicesEngine)
+		})
+}
 
-			route, err :=
-				model.NewRoute(id, name, port, deployName, namespace, domains, isRunning, ttlSeconds, readinessTimeoutSeconds)
+func (c *controller) RunProxlessRoutesEngine(ctx context.Context) {
+	logger.Infof("Starting Proxless Routes Engine...")
 
-			if err != nil {
-				return err
-			}
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Errorf(nil, "Proxless Routes Engine panic. Restarting...")
+			c.RunProxlessRoutesEngine(ctx)
+		}
+	}()
 
-			return c.memory.UpsertMemoryMap(route)
+	cfg := c.config.Get()
+
+	c.cluster.RunProxlessRoutesEngine(
+		ctx,
+		cfg.NamespaceScope,
+		cfg.ProxlessService,
+		cfg.ProxlessNamespace,
+		func(route *model.Route) error {
+			return upsertRouteInMemory(c, route)
 		},
 		func(id string) error {
-			if c.pubsub != nil {
-				c.pubsub.Unsubscribe(id)
-			}
+			return deleteRouteFromMemory(c, id)
+		},
+		func(id string) (*model.Route, error) {
+			return getRouteFromMemory(c, id)
+		},
+		func() {
+			c.health.setEngineSynced(proxlessRoutesEngine)
+		})
+}
+
+func (c *controller) RunDiscoveryEngine(ctx context.Context) {
+	logger.Infof("Starting Discovery Engine...")
+
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Errorf(nil, "Discovery Engine panic. Restarting...")
+			c.RunDiscoveryEngine(ctx)
+		}
+	}()
+
+	cfg := c.config.Get()
 
-			return c.memory.DeleteRoute(id)
+	c.cluster.RunDiscoveryEngine(
+		ctx,
+		cfg.NamespaceScope,
+		cfg.ProxlessService,
+		cfg.ProxlessNamespace,
+		func(route *model.Route) error {
+			return upsertRouteInMemory(c, route)
+		},
+		func(id string) error {
+			return deleteRouteFromMemory(c, id)
+		},
+		func() {
+			c.health.setEngineSynced(discoveryEngine)
 		})
 }
+
+func upsertRouteInMemory(c *controller, route *model.Route) error {
+	if c.pubsub != nil {
+		c.pubsub.SubscribeLastUsed(route.GetId(), c.memory.UpdateLastUsed)
+		c.pubsub.SubscribeIsRunning(route.GetId(), c.memory.UpdateIsRunning)
+		c.pubsub.SubscribePinLease(route.GetId(), c.memory.UpdatePinLease)
+	}
+
+	if c.store != nil {
+		restoreLastUsedFromStore(c, route)
+		restorePinLeaseFromStore(c, route)
+	}
+
+	return c.memory.UpsertMemoryMap(route)
+}
+
+func deleteRouteFromMemory(c *controller, id string) error {
+	if c.pubsub != nil {
+		c.pubsub.Unsubscribe(id)
+	}
+
+	return c.memory.DeleteRoute(id)
+}
+
+// return a copy of the route - the memory must only be updated through its interface
+func getRouteFromMemory(c *controller, id string) (*model.Route, error) {
+	route, ok := c.memory.GetRoutes()[id]
+
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Route %s not found in memory", id))
+	}
+
+	return &route, nil
+}
+
+// the endpoints of all the services are watched - the only error is the service not being a proxless service
+func updateEndpointsInMemory(c *controller, id string, endpoints []string) error {
+	_ = c.memory.UpdateEndpoints(id, endpoints)
+
+	return nil
+}
+
+// keep `isRunning` in sync with the cluster
+// someone might have scaled the deployment manually or through another autoscaler
+func updateReplicasInMemory(c *controller, deployName, namespace string, replicas, availableReplicas int) error {
+	route, err := c.memory.GetRouteByDeployment(deployName, namespace)
+
+	if err != nil { // not a proxless deployment
+		return nil
+	}
+
+	if err := c.memory.UpdateReplicas(route.GetId(), deployName, replicas, availableReplicas); err != nil {
+		return err
+	}
+
+	// the route is a copy - the replicas of the other deployments are the ones in memory
+	if route, err = c.memory.GetRouteByDeployment(deployName, namespace); err != nil {
+		return err
+	}
+
+	// a request wakes up all the deployments of the route if one of them is scaled down
+	return c.memory.UpdateIsRunning(route.GetId(), route.IsEveryDeploymentRunning())
+}
+
+// a new route starts with `lastUsed = time.Now()` (or the value persisted in the cluster)
+// if another replica (or a previous run of this one) already knows a value, we use it instead
+// the store is written on each request so it is always more accurate than the cluster
+func restoreLastUsedFromStore(c *controller, route *model.Route) {
+	if lastUsed, err := c.store.GetLastUsed(route.GetId()); err == nil {
+		route.SetLastUsed(lastUsed)
+	}
+}
+
+// a new replica must not scale down a route pinned before it started
+func restorePinLeaseFromStore(c *controller, route *model.Route) {
+	if until, err := c.store.GetPinLease(route.GetId()); err == nil {
+		route.SetPinLeaseUntil(until)
+	}
+}
+
+func (c *controller) RunStateReconciler(ctx context.Context, reconcileInterval int) {
+	logger.Infof("Starting State Reconciler...")
+
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Errorf(nil, "State Reconciler panic. Restarting...")
+			c.RunStateReconciler(ctx, reconcileInterval)
+		}
+	}()
+
+	for {
+		errs := reconcileStateFromStore(c)
+
+		for _, err := range errs {
+			logger.Errorf(err, "Error during state reconciliation")
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("State Reconciler stopped")
+			return
+		case <-time.After(time.Duration(reconcileInterval) * time.Second):
+		}
+	}
+}
+
+// catch up with the messages that might have been missed from the pubsub
+// `lastUsed` only moves forward - `isRunning` is not stored, the deployments informer tracks the actual replicas
+func reconcileStateFromStore(c *controller) []error {
+	var errs []error
+
+	for id, route := range c.memory.GetRoutes() {
+		if lastUsed, err := c.store.GetLastUsed(id); err == nil && lastUsed.After(route.GetLastUsed()) {
+			if err := c.memory.UpdateLastUsed(id, lastUsed); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if until, err := c.store.GetPinLease(id); err == nil && !until.Equal(route.GetPinLeaseUntil()) {
+			if err := c.memory.UpdatePinLease(id, until); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errs
+}
+
+func (c *controller) RunLastUsedPersister(ctx context.Context, persistInterval, persistThreshold int) {
+	logger.Infof("Starting LastUsed Persister...")
+
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Errorf(nil, "LastUsed Persister panic. Restarting...")
+			c.RunLastUsedPersister(ctx, persistInterval, persistThreshold)
+		}
+	}()
+
+	for {
+		errs := persistLastUsed(c, persistThreshold)
+
+		for _, err := range errs {
+			logger.Errorf(err, "Error persisting lastUsed")
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("LastUsed Persister stopped")
+			return
+		case <-time.After(time.Duration(persistInterval) * time.Second):
+		}
+	}
+}
+
+// write the lastUsed of each route in the cluster only if it moved by more than `persistThreshold` seconds
+// since the last write - we don't want to spam the kubernetes api on each request
+func persistLastUsed(c *controller, persistThreshold int) []error {
+	var errs []error
+
+	routes := c.memory.GetRoutes()
+
+	for id, route := range routes {
+		lastUsed := route.GetLastUsed()
+
+		if lastUsed.Sub(c.lastUsedPersisted[id]) < time.Duration(persistThreshold)*time.Second {
+			continue
+		}
+
+		err := c.cluster.PersistLastUsed(route.GetDeployment(), route.GetNamespace(), lastUsed)
+
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			c.lastUsedPersisted[id] = lastUsed
+		}
+	}
+
+	// forget about the routes that have been removed from memory
+	for id := range c.lastUsedPersisted {
+		if _, ok := routes[id]; !ok {
+			delete(c.lastUsedPersisted, id)
+		}
+	}
+
+	return errs
+}
+
+// the owner references of the proxless services are not enough - see `DeleteOrphanProxlessServices`
+func (c *controller) RunProxlessServicesCollector(ctx context.Context, collectInterval int) {
+	logger.Infof("Starting Proxless Services Collector...")
+
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Errorf(nil, "Proxless Services Collector panic. Restarting...")
+			c.RunProxlessServicesCollector(ctx, collectInterval)
+		}
+	}()
+
+	for {
+		errs := c.cluster.DeleteOrphanProxlessServices(c.config.Get().NamespaceScope)
+
+		for _, err := range errs {
+			logger.Errorf(err, "Error deleting orphan proxless services")
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("Proxless Services Collector stopped")
+			return
+		case <-time.After(time.Duration(collectInterval) * time.Second):
+		}
+	}
+}
+
+func (c *controller) RunSavingsCollector(ctx context.Context, collectInterval int) {
+	logger.Infof("Starting Savings Collector...")
+
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Errorf(nil, "Savings Collector panic. Restarting...")
+			c.RunSavingsCollector(ctx, collectInterval)
+		}
+	}()
+
+	for {
+		errs := collectSavings(c, time.Now())
+
+		for _, err := range errs {
+			logger.Errorf(err, "Error collecting the savings")
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("Savings Collector stopped")
+			return
+		case <-time.After(time.Duration(collectInterval) * time.Second):
+		}
+	}
+}
+
+// the time asleep is counted from the first collect that sees the deployment scaled down
+// so the savings are accurate to the collect interval, and never overestimated
+func collectSavings(c *controller, now time.Time) []error {
+	var errs []error
+
+	elapsed := c.savings.tick(now)
+
+	for id, route := range c.memory.GetRoutes() {
+		if route.GetIsRunning() {
+			c.savings.wakeUp(id)
+			continue
+		}
+
+		if c.savings.isAsleep(id) {
+			c.savings.add(id, elapsed)
+			continue
+		}
+
+		// the requests are fetched on each scale down - they might have changed while the deployment was running
+		cpu, memory, err := c.cluster.GetDeploymentResourceRequests(route.GetDeployment(), route.GetNamespace())
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		c.savings.sleep(route, cpu, memory)
+	}
+
+	return errs
+}
+
+func (c *controller) GetSavingsReport() SavingsReport {
+	cfg := c.config.Get()
+
+	return c.savings.get(cfg.SavingsCPUHourPrice, cfg.SavingsMemoryGBHourPrice)
+}
+
+func (c *controller) RunPreWarmer(ctx context.Context, checkInterval int) {
+	logger.Infof("Starting PreWarmer...")
+
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Errorf(nil, "PreWarmer panic. Restarting...")
+			c.RunPreWarmer(ctx, checkInterval)
+		}
+	}()
+
+	for {
+		errs := preWarmRoutes(c, time.Now())
+
+		for _, err := range errs {
+			logger.Errorf(err, "Error during pre-warm")
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("PreWarmer stopped")
+			return
+		case <-time.After(time.Duration(checkInterval) * time.Second):
+		}
+	}
+}
+
+// wake up the routes `PREWARM_LEAD_SECONDS` before the hours they are usually requested
+func preWarmRoutes(c *controller, now time.Time) []error {
+	c.preWarmer.expire(now)
+
+	cfg := c.config.Get()
+	hourStart := now.Add(time.Duration(cfg.PreWarmLeadSeconds) * time.Second).Truncate(time.Hour)
+
+	// the next hour is not within the lead time yet
+	if !hourStart.After(now) {
+		return nil
+	}
+
+	var routes []model.Route
+
+	for id, route := range c.memory.GetRoutes() {
+		if route.GetIsRunning() {
+			continue
+		}
+
+		confidence := c.preWarmer.getConfidence(id, hourStart, cfg.PreWarmMinWeeks)
+
+		if confidence < cfg.PreWarmConfidence || !c.preWarmer.preWarm(route, hourStart) {
+			continue
+		}
+
+		logger.Infof("Pre-warming route %s for %s - confidence %.2f", id, hourStart.Format(time.Kitchen), confidence)
+
+		// the TTL counts from the pre-warm - not from the last request
+		_ = updateLastUsed(c, id, now)
+		routes = append(routes, route)
+	}
+
+	return wakeUpRoutes(c, routes)
+}
+
+func (c *controller) GetPreWarmReport() []PreWarmRouteReport {
+	return c.preWarmer.get()
+}
@@ -1,18 +1,18 @@
 package controller
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
 	"kube-proxless/internal/cluster/fake"
-	"kube-proxless/internal/config"
-	"kube-proxless/internal/memory"
 	"kube-proxless/internal/model"
+	memorypubsub "kube-proxless/internal/pubsub/memory"
 	"kube-proxless/internal/utils"
 	"testing"
 	"time"
 )
 
 func TestController_GetRouteByDomainFromMemory(t *testing.T) {
-	c := NewController(memory.NewMemoryMap(), nil, nil)
+	c := helper_newController(nil, nil, nil)
 
 	// error - memory is empty
 	_, err := c.GetRouteByDomainFromMemory("mock.io")
@@ -37,7 +37,7 @@ func TestController_GetRouteByDomainFromMemory(t *testing.T) {
 }
 
 func TestController_UpdateLastUseMemory(t *testing.T) {
-	c := NewController(memory.NewMemoryMap(), nil, nil)
+	c := helper_newController(nil, nil, nil)
 
 	// error - memory is empty
 	assert.Error(t, c.UpdateLastUsedInMemory("mock.io"))
@@ -69,17 +69,17 @@ func TestController_UpdateLastUseMemory(t *testing.T) {
 }
 
 func TestController_ScaleUpDeployment(t *testing.T) {
-	c := NewController(memory.NewMemoryMap(), fake.NewCluster(), nil)
+	c := helper_newController(fake.NewCluster(), nil, nil)
 
 	// check the implemention of the fake client to understand the test
 
-	assert.NoError(t, c.ScaleUpDeployment("mock-deploy", "mock-ns", 0))
+	assert.NoError(t, c.ScaleUpDeployment("mock-deploy", "mock-svc", "mock-ns", 0))
 
-	assert.Error(t, c.ScaleUpDeployment("deploy", "ns", 0))
+	assert.Error(t, c.ScaleUpDeployment("deploy", "svc", "ns", 0))
 }
 
 func TestController_scaleDownDeployments(t *testing.T) {
-	c := NewController(memory.NewMemoryMap(), fake.NewCluster(), nil)
+	c := helper_newController(fake.NewCluster(), nil, nil)
 
 	helper_assertNoError(t, scaleDownDeployments(c))
 
@@ -95,13 +95,37 @@ func TestController_scaleDownDeployments(t *testing.T) {
 	helper_assertNoError(t, scaleDownDeployments(c))
 }
 
+func TestController_scaleDownDeployments_NotRunning(t *testing.T) {
+	cl := &fakeScaleDownCluster{Interface: fake.NewCluster()}
+	c := helper_newController(cl, nil, nil)
+	c.config.Get().ServerlessTTLSeconds = 0
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	helper_assertNoError(t, scaleDownDeployments(c))
+	assert.Equal(t, 1, cl.scaleDowns)
+	assert.False(t, route.GetIsRunning())
+
+	// already scaled down - the deployment must not be patched at every interval
+	helper_assertNoError(t, scaleDownDeployments(c))
+	assert.Equal(t, 1, cl.scaleDowns)
+}
+
 func TestController_RunDownScaler(t *testing.T) {
-	c := NewController(memory.NewMemoryMap(), fake.NewCluster(), nil)
+	c := helper_newController(fake.NewCluster(), nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
 
-	// TODO check how we wanna deal with closing the channel and stopping the routine
-	// We could use a context https://github.com/kubernetes/client-go/blob/master/examples/fake-client/main_test.go
-	// but not sure if it is worth it
-	go c.RunDownScaler(1)
+	go func() {
+		c.RunDownScaler(ctx, 1)
+		close(stopped)
+	}()
 
 	// make sure there is no panic when memory empty
 	time.Sleep(1 * time.Second)
@@ -117,22 +141,511 @@ func TestController_RunDownScaler(t *testing.T) {
 
 	// make sure there is no panic when memory had data
 	time.Sleep(1 * time.Second)
+
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Errorf("RunDownScaler(); must stop when the context is done")
+	}
+}
+
+func TestController_Runners_Stop(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, newFakeStore())
+
+	testCases := []struct {
+		name string
+		run  func(ctx context.Context)
+	}{
+		{"RunStateReconciler", func(ctx context.Context) { c.RunStateReconciler(ctx, 60) }},
+		{"RunLastUsedPersister", func(ctx context.Context) { c.RunLastUsedPersister(ctx, 60, 60) }},
+		{"RunProxlessServicesCollector", func(ctx context.Context) { c.RunProxlessServicesCollector(ctx, 60) }},
+		{"RunSavingsCollector", func(ctx context.Context) { c.RunSavingsCollector(ctx, 60) }},
+		{"RunPreWarmer", func(ctx context.Context) { c.RunPreWarmer(ctx, 60) }},
+	}
+
+	for _, tc := range testCases {
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+
+		go func() {
+			tc.run(ctx)
+			close(stopped)
+		}()
+
+		// the interval is longer than the test - the runner must not wait for its next iteration
+		cancel()
+
+		select {
+		case <-stopped:
+		case <-time.After(2 * time.Second):
+			t.Errorf("%s(); must stop when the context is done", tc.name)
+		}
+	}
 }
 
 func TestController_RunServicesEngine(t *testing.T) {
-	c := NewController(memory.NewMemoryMap(), fake.NewCluster(), nil)
+	c := helper_newController(fake.NewCluster(), nil, nil)
 
 	// check the implemention of the fake client to understand the test
-	config.NamespaceScope = "upsert"
-	c.RunServicesEngine()
+	c.config.Get().NamespaceScope = "upsert"
+	c.RunServicesEngine(context.TODO())
 
 	_, err := c.memory.GetRouteByDomain("mock.io")
 	assert.NoError(t, err)
+	assert.True(t, c.health.syncedEngines[servicesEngine])
 
-	config.NamespaceScope = "delete"
-	c.RunServicesEngine()
+	c.config.Get().NamespaceScope = "delete"
+	c.RunServicesEngine(context.TODO())
 
 	_, err = c.memory.GetRouteByDomain("mock.io")
 	assert.Error(t, err)
 
 }
+
+func TestController_RunProxlessRoutesEngine(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+
+	// check the implemention of the fake client to understand the test
+	c.config.Get().NamespaceScope = "upsert"
+	c.RunProxlessRoutesEngine(context.TODO())
+
+	_, err := c.memory.GetRouteByDomain("mock.io")
+	assert.NoError(t, err)
+
+	c.config.Get().NamespaceScope = "delete"
+	c.RunProxlessRoutesEngine(context.TODO())
+
+	_, err = c.memory.GetRouteByDomain("mock.io")
+	assert.Error(t, err)
+}
+
+func TestController_RunDiscoveryEngine(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+
+	// check the implemention of the fake client to understand the test
+	c.config.Get().NamespaceScope = "upsert"
+	c.RunDiscoveryEngine(context.TODO())
+
+	_, err := c.memory.GetRouteByDomain("mock.io")
+	assert.NoError(t, err)
+
+	c.config.Get().NamespaceScope = "delete"
+	c.RunDiscoveryEngine(context.TODO())
+
+	_, err = c.memory.GetRouteByDomain("mock.io")
+	assert.Error(t, err)
+}
+
+func TestController_getRouteFromMemory(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+
+	_, err := getRouteFromMemory(c, "mock-id")
+	assert.Error(t, err)
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	got, err := getRouteFromMemory(c, "mock-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "mock-deploy", got.GetDeployment())
+}
+
+func TestController_RunServicesEngine_RestoreLastUsedFromStore(t *testing.T) {
+	st := newFakeStore()
+	c := helper_newController(fake.NewCluster(), nil, st)
+
+	lastUsed := time.Now().Add(-time.Hour)
+	st.SetLastUsed("mock-id", lastUsed)
+
+	// check the implemention of the fake client to understand the test
+	c.config.Get().NamespaceScope = "upsert"
+	c.RunServicesEngine(context.TODO())
+
+	route, err := c.memory.GetRouteByDomain("mock.io")
+	assert.NoError(t, err)
+	assert.Equal(t, lastUsed, route.GetLastUsed())
+}
+
+func TestController_reconcileStateFromStore(t *testing.T) {
+	st := newFakeStore()
+	c := helper_newController(fake.NewCluster(), nil, st)
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	// nothing in the store - memory must not change
+	helper_assertNoError(t, reconcileStateFromStore(c))
+	assert.True(t, route.GetIsRunning())
+
+	// lastUsed must never move backward
+	lastUsedBefore := route.GetLastUsed()
+	st.SetLastUsed("mock-id", lastUsedBefore.Add(-time.Hour))
+	helper_assertNoError(t, reconcileStateFromStore(c))
+	assert.Equal(t, lastUsedBefore, route.GetLastUsed())
+
+	lastUsedAfter := lastUsedBefore.Add(time.Hour)
+	st.SetLastUsed("mock-id", lastUsedAfter)
+	helper_assertNoError(t, reconcileStateFromStore(c))
+	assert.Equal(t, lastUsedAfter, route.GetLastUsed())
+	assert.True(t, route.GetIsRunning())
+}
+
+func TestController_UpdateInMemory_WriteToStore(t *testing.T) {
+	st := newFakeStore()
+	c := helper_newController(fake.NewCluster(), nil, st)
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, false,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	assert.NoError(t, c.UpdateLastUsedInMemory("mock-id"))
+	lastUsed, err := st.GetLastUsed("mock-id")
+	assert.NoError(t, err)
+	assert.Equal(t, route.GetLastUsed(), lastUsed)
+}
+
+func TestController_persistLastUsed(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+
+	helper_assertNoError(t, persistLastUsed(c, 60))
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	// first write
+	helper_assertNoError(t, persistLastUsed(c, 60))
+	assert.Equal(t, route.GetLastUsed(), c.lastUsedPersisted["mock-id"])
+
+	// lastUsed moved but below the threshold - no write
+	persisted := route.GetLastUsed()
+	assert.NoError(t, c.memory.UpdateLastUsed("mock-id", persisted.Add(30*time.Second)))
+	helper_assertNoError(t, persistLastUsed(c, 60))
+	assert.Equal(t, persisted, c.lastUsedPersisted["mock-id"])
+
+	// lastUsed moved above the threshold - write
+	assert.NoError(t, c.memory.UpdateLastUsed("mock-id", persisted.Add(90*time.Second)))
+	helper_assertNoError(t, persistLastUsed(c, 60))
+	assert.Equal(t, persisted.Add(90*time.Second), c.lastUsedPersisted["mock-id"])
+
+	// the route is removed from memory - must be forgotten
+	assert.NoError(t, c.memory.DeleteRoute("mock-id"))
+	helper_assertNoError(t, persistLastUsed(c, 60))
+	assert.Len(t, c.lastUsedPersisted, 0)
+
+	// error - the fake cluster does not know this deployment
+	route, err = model.NewRoute(
+		"id", "svc", "", "deploy", "ns",
+		[]string{"example.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+	helper_assertAtLeastOneError(t, persistLastUsed(c, 60))
+}
+
+// two controllers sharing the same cluster and the same pubsub - like two proxless replicas
+func TestController_PubSub_Converge(t *testing.T) {
+	cluster := fake.NewCluster()
+	broker := memorypubsub.NewBroker()
+	c1 := helper_newController(cluster, memorypubsub.NewMemoryPubSub(broker), nil)
+	c2 := helper_newController(cluster, memorypubsub.NewMemoryPubSub(broker), nil)
+
+	// check the implemention of the fake client to understand the test
+	c1.config.Get().NamespaceScope = "upsert"
+	c2.config.Get().NamespaceScope = "upsert"
+	c1.RunServicesEngine(context.TODO())
+	c2.RunServicesEngine(context.TODO())
+
+	// the memory returns copies - the routes are read again after each step
+	getRoute := func(c *controller) *model.Route {
+		route, err := c.GetRouteByDomainFromMemory("mock.io")
+		assert.NoError(t, err)
+		return route
+	}
+	id := getRoute(c1).GetId()
+
+	// lastUsed updated on c1 must be updated on c2
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, c1.UpdateLastUsedInMemory(id))
+	assert.Equal(t, getRoute(c1).GetLastUsed(), getRoute(c2).GetLastUsed())
+
+	// scaled down by c1 must be scaled down on c2
+	c1.config.Get().ServerlessTTLSeconds = 0
+	helper_assertNoError(t, scaleDownDeployments(c1))
+	assert.False(t, getRoute(c1).GetIsRunning())
+	assert.False(t, getRoute(c2).GetIsRunning())
+
+	// scaled up by c2 must be running on c1
+	assert.NoError(t, c2.UpdateIsRunningInMemory(id))
+	assert.True(t, getRoute(c1).GetIsRunning())
+	route2 := getRoute(c2)
+	assert.True(t, route2.GetIsRunning())
+
+	// route removed from c2 must not receive the messages anymore
+	c2.config.Get().NamespaceScope = "delete"
+	c2.RunServicesEngine(context.TODO())
+	_, err := c2.GetRouteByDomainFromMemory("mock.io")
+	assert.Error(t, err)
+
+	// only in the memory of c1 - the routes that are not running are not scaled down
+	assert.NoError(t, c1.memory.UpdateIsRunning(id, true))
+	helper_assertNoError(t, scaleDownDeployments(c1))
+	assert.False(t, getRoute(c1).GetIsRunning())
+	assert.True(t, route2.GetIsRunning())
+}
+
+func TestController_updateReplicasInMemory(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+
+	// not a proxless deployment - ignored
+	assert.NoError(t, updateReplicasInMemory(c, "mock-deploy", "mock-ns", 1, 1))
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, false,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	// scaled up outside of proxless
+	assert.NoError(t, updateReplicasInMemory(c, "mock-deploy", "mock-ns", 2, 1))
+	assert.True(t, route.GetIsRunning())
+	assert.Equal(t, 2, route.GetReplicas())
+	assert.Equal(t, 1, route.GetAvailableReplicas())
+
+	// scaled down outside of proxless
+	assert.NoError(t, updateReplicasInMemory(c, "mock-deploy", "mock-ns", 0, 0))
+	assert.False(t, route.GetIsRunning())
+}
+
+func TestController_updateReplicasInMemory_MultipleDeployments(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy-a", "mock-ns",
+		[]string{"mock.io"}, false,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, route.SetDeployments([]string{"mock-deploy-a", "mock-deploy-b"}))
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	assert.NoError(t, updateReplicasInMemory(c, "mock-deploy-a", "mock-ns", 2, 2))
+	assert.NoError(t, updateReplicasInMemory(c, "mock-deploy-b", "mock-ns", 1, 0))
+	assert.True(t, route.GetIsRunning())
+	assert.Equal(t, 3, route.GetReplicas())
+	assert.Equal(t, 2, route.GetAvailableReplicas())
+
+	// one of the deployments scaled down outside of proxless - the next request wakes it up
+	assert.NoError(t, updateReplicasInMemory(c, "mock-deploy-b", "mock-ns", 0, 0))
+	assert.False(t, route.GetIsRunning())
+	assert.Equal(t, 2, route.GetReplicas())
+}
+
+func TestController_updateEndpointsInMemory(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+
+	// not a proxless service - ignored
+	assert.NoError(t, updateEndpointsInMemory(c, "mock-id", []string{"10.0.0.1"}))
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, false,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	assert.NoError(t, updateEndpointsInMemory(c, "mock-id", []string{"10.0.0.1"}))
+	assert.Equal(t, []string{"10.0.0.1"}, route.GetEndpoints())
+}
+
+func TestController_ValidateRoute(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	testCases := []struct {
+		id          string
+		deployNames []string
+		domains     []string
+		errsWanted  int
+	}{
+		{"mock-id", []string{"mock-deploy"}, []string{"mock.io"}, 0},                   // updating the same route
+		{"other-id", []string{"mock-deploy"}, []string{"mock.io", "other.io"}, 2},      // deployment and domain owned by mock-id
+		{"other-id", []string{"other-deploy"}, []string{"other.io"}, 1},                // deployment not found
+		{"other-id", []string{"other-deploy"}, []string{"mock.io", "other.io"}, 2},     // deployment not found and domain owned
+		{"other-id", []string{"other-deploy", "mock-deploy"}, []string{"other.io"}, 2}, // one not found and one owned
+	}
+
+	for _, tc := range testCases {
+		errs := c.ValidateRoute(tc.id, tc.deployNames, "mock-ns", tc.domains)
+		assert.Len(t, errs, tc.errsWanted, errs)
+	}
+}
+
+func TestController_scaleDownDeployments_DryRun(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+	c.config.Get().DryRun = true
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "unknown-deploy", "mock-ns",
+		[]string{"mock.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	c.config.Get().ServerlessTTLSeconds = 0
+	helper_assertNoError(t, scaleDownDeployments(c))
+	helper_assertNoError(t, scaleDownDeployments(c))
+
+	// the deployment keeps running
+	assert.True(t, route.GetIsRunning())
+
+	reports := c.GetDryRunReport()
+	assert.Len(t, reports, 1)
+	assert.Equal(t, 1, reports[0].ProjectedScaleDowns)
+
+	// the scale up is not executed - the fake cluster would fail on this deployment
+	assert.NoError(t, c.ScaleUpDeployment("unknown-deploy", "mock-svc", "mock-ns", 0))
+	assert.NoError(t, c.UpdateLastUsedInMemory("mock-id"))
+	assert.Equal(t, 1, c.GetDryRunReport()[0].ProjectedScaleUps)
+}
+
+func TestController_collectSavings(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+	now := time.Now()
+
+	helper_assertNoError(t, collectSavings(c, now))
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+	unknownRoute, err := model.NewRoute(
+		"unknown-id", "unknown-svc", "", "unknown-deploy", "mock-ns",
+		[]string{"unknown.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(unknownRoute))
+
+	// running - nothing to collect
+	helper_assertNoError(t, collectSavings(c, now.Add(time.Hour)))
+	assert.Empty(t, c.GetSavingsReport().Routes)
+
+	// the time asleep starts on the next collect
+	assert.NoError(t, c.memory.UpdateIsRunning("mock-id", false))
+	assert.NoError(t, c.memory.UpdateIsRunning("unknown-id", false))
+	helper_assertAtLeastOneError(t, collectSavings(c, now.Add(2*time.Hour)))
+	helper_assertAtLeastOneError(t, collectSavings(c, now.Add(4*time.Hour)))
+
+	// scaled up - stop counting
+	assert.NoError(t, c.memory.UpdateIsRunning("mock-id", true))
+	assert.NoError(t, c.memory.UpdateIsRunning("unknown-id", true))
+	helper_assertNoError(t, collectSavings(c, now.Add(5*time.Hour)))
+
+	report := c.GetSavingsReport()
+	assert.Len(t, report.Routes, 1)
+	assert.Equal(t, float64(7200), report.Routes[0].AsleepSeconds)
+	assert.Equal(t, float64(1), report.Routes[0].CPUHours)
+	assert.Equal(t, float64(4), report.Routes[0].MemoryGBHours)
+}
+
+func TestController_preWarmRoutes(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+	c.config.Get().PreWarmLeadSeconds = 300
+	c.config.Get().PreWarmConfidence = 0.5
+	c.config.Get().PreWarmMinWeeks = 1
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, false,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	// requested last week at 9
+	lastWeek := time.Date(2020, 6, 1, 9, 10, 0, 0, time.Local)
+	c.preWarmer.record("mock-id", lastWeek)
+
+	// not within the lead time
+	helper_assertNoError(t, preWarmRoutes(c, lastWeek.Add(week-time.Hour)))
+	assert.False(t, route.GetIsRunning())
+
+	// 5 minutes before 9
+	now := lastWeek.Add(week).Truncate(time.Hour).Add(-4 * time.Minute)
+	helper_assertNoError(t, preWarmRoutes(c, now))
+	assert.True(t, route.GetIsRunning())
+	assert.Equal(t, now, route.GetLastUsed())
+	assert.Equal(t, 1, c.GetPreWarmReport()[0].PreWarms)
+}
+
+func TestController_PinRoute(t *testing.T) {
+	st := newFakeStore()
+	c := helper_newController(fake.NewCluster(), nil, st)
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, true,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	assert.Error(t, c.PinRoute("unknown", time.Now().Add(time.Hour)))
+
+	until := time.Now().Add(time.Hour)
+	assert.NoError(t, c.PinRoute("mock-id", until))
+	assert.Equal(t, until, route.GetPinLeaseUntil())
+	lease, _ := st.GetPinLease("mock-id")
+	assert.Equal(t, until, lease)
+
+	pinned := c.GetPinnedRoutes()
+	assert.Len(t, pinned, 1)
+	assert.Equal(t, PinnedRouteReport{
+		Id: "mock-id", Deployment: "mock-deploy", Namespace: "mock-ns", PinnedUntil: until, Lease: true}, pinned[0])
+
+	// unpin
+	assert.NoError(t, c.PinRoute("mock-id", time.Time{}))
+	assert.Empty(t, c.GetPinnedRoutes())
+}
+
+func TestController_wakeUpPinnedRoutes(t *testing.T) {
+	c := helper_newController(fake.NewCluster(), nil, nil)
+	now := time.Now()
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns",
+		[]string{"mock.io"}, false,
+		nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.memory.UpsertMemoryMap(route))
+
+	// not pinned
+	helper_assertNoError(t, wakeUpPinnedRoutes(c, now))
+	assert.False(t, route.GetIsRunning())
+
+	route.SetPinnedUntil(now.Add(time.Hour))
+	helper_assertNoError(t, wakeUpPinnedRoutes(c, now))
+	assert.True(t, route.GetIsRunning())
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"kube-proxless/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// what proxless would have done for a route if it was not running in dry-run mode
+type DryRunRouteReport struct {
+	Id                  string  `json:"id"`
+	Deployment          string  `json:"deployment"`
+	Namespace           string  `json:"namespace"`
+	ProjectedScaleDowns int     `json:"projectedScaleDowns"`
+	ProjectedScaleUps   int     `json:"projectedScaleUps"`
+	ProjectedIdleHours  float64 `json:"projectedIdleHours"`
+	ScaledDown          bool    `json:"scaledDown"` // the deployment would currently be scaled down
+}
+
+type dryRunRoute struct {
+	report       DryRunRouteReport
+	idle         time.Duration
+	scaledDownAt *time.Time
+}
+
+type dryRunReport struct {
+	lock   sync.Mutex
+	routes map[string]*dryRunRoute
+}
+
+func newDryRunReport() *dryRunReport {
+	return &dryRunReport{routes: map[string]*dryRunRoute{}}
+}
+
+// return false if the deployment is already projected as scaled down
+// the downscaler returns the idle routes on each check even if their deployment is already scaled down
+func (r *dryRunReport) scaleDown(route model.Route, now time.Time) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	dr, ok := r.routes[route.GetId()]
+	if !ok {
+		dr = &dryRunRoute{}
+		r.routes[route.GetId()] = dr
+	}
+
+	dr.report.Id = route.GetId()
+	dr.report.Deployment = route.GetDeployment()
+	dr.report.Namespace = route.GetNamespace()
+
+	if dr.scaledDownAt != nil {
+		return false
+	}
+
+	dr.scaledDownAt = &now
+	dr.report.ProjectedScaleDowns++
+
+	return true
+}
+
+// return true if the request would have woken up the deployment
+func (r *dryRunReport) scaleUp(id string, now time.Time) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	dr, ok := r.routes[id]
+	if !ok || dr.scaledDownAt == nil {
+		return false
+	}
+
+	dr.idle += now.Sub(*dr.scaledDownAt)
+	dr.scaledDownAt = nil
+	dr.report.ProjectedScaleUps++
+
+	return true
+}
+
+func (r *dryRunReport) get(now time.Time) []DryRunRouteReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	reports := []DryRunRouteReport{}
+
+	for _, dr := range r.routes {
+		report := dr.report
+		idle := dr.idle
+
+		if dr.scaledDownAt != nil {
+			idle += now.Sub(*dr.scaledDownAt)
+			report.ScaledDown = true
+		}
+
+		report.ProjectedIdleHours = idle.Hours()
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Id < reports[j].Id
+	})
+
+	return reports
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"github.com/stretchr/testify/assert"
+	"kube-proxless/internal/model"
+	"testing"
+	"time"
+)
+
+func Test_dryRunReport(t *testing.T) {
+	r := newDryRunReport()
+	now := time.Now()
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns", []string{"mock.io"}, true, nil, nil)
+	assert.NoError(t, err)
+
+	// unknown route is not woken up
+	assert.False(t, r.scaleUp("mock-id", now))
+	assert.Empty(t, r.get(now))
+
+	// the route is only scaled down once per idle period
+	assert.True(t, r.scaleDown(*route, now))
+	assert.False(t, r.scaleDown(*route, now.Add(time.Minute)))
+
+	reports := r.get(now.Add(time.Hour))
+	assert.Len(t, reports, 1)
+	assert.Equal(t, DryRunRouteReport{
+		Id: "mock-id", Deployment: "mock-deploy", Namespace: "mock-ns",
+		ProjectedScaleDowns: 1, ProjectedIdleHours: 1, ScaledDown: true,
+	}, reports[0])
+
+	// the idle period stops on scale up
+	assert.True(t, r.scaleUp("mock-id", now.Add(2*time.Hour)))
+	assert.False(t, r.scaleUp("mock-id", now.Add(3*time.Hour)))
+
+	assert.True(t, r.scaleDown(*route, now.Add(4*time.Hour)))
+
+	reports = r.get(now.Add(5 * time.Hour))
+	assert.Equal(t, DryRunRouteReport{
+		Id: "mock-id", Deployment: "mock-deploy", Namespace: "mock-ns",
+		ProjectedScaleDowns: 2, ProjectedScaleUps: 1, ProjectedIdleHours: 3, ScaledDown: true,
+	}, reports[0])
+}
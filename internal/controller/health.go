@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	servicesEngine       = "services engine"
+	proxlessRoutesEngine = "proxless routes engine"
+	discoveryEngine      = "discovery engine"
+)
+
+// liveness and readiness of proxless - served on the admin port
+type healthTracker struct {
+	startedAt time.Time
+	// last iteration of the DownScaler - zero until the first one
+	downScalerHeartbeat   time.Time
+	servicesEngineRunning bool
+	// the engines with their routes in memory
+	syncedEngines map[string]bool
+	lock          sync.RWMutex
+}
+
+func newHealthTracker(now time.Time) *healthTracker {
+	return &healthTracker{
+		startedAt:     now,
+		syncedEngines: map[string]bool{},
+		lock:          sync.RWMutex{},
+	}
+}
+
+func (h *healthTracker) beatDownScaler(now time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.downScalerHeartbeat = now
+}
+
+func (h *healthTracker) setServicesEngineRunning(running bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.servicesEngineRunning = running
+}
+
+func (h *healthTracker) setEngineSynced(engine string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.syncedEngines[engine] = true
+}
+
+func (c *controller) CheckLiveness() []error {
+	return checkLiveness(c, time.Now())
+}
+
+// the DownScaler must run at least every few intervals and the services engine must not have returned
+func checkLiveness(c *controller, now time.Time) []error {
+	var errs []error
+
+	cfg := c.config.Get()
+	// waking up the pinned routes blocks the DownScaler up to the readiness timeout
+	maxDelay := time.Duration(3*cfg.ScaleDownCheckIntervalSeconds+cfg.DeploymentReadinessTimeoutSeconds) * time.Second
+
+	c.health.lock.RLock()
+	defer c.health.lock.RUnlock()
+
+	lastRun := c.health.downScalerHeartbeat
+	if lastRun.IsZero() {
+		lastRun = c.health.startedAt
+	}
+
+	if now.Sub(lastRun) > maxDelay {
+		errs = append(errs, errors.New(fmt.Sprintf("DownScaler wedged - last run at %s", lastRun.Format(time.RFC3339))))
+	}
+
+	// the engines are stopped on purpose when proxless shuts down
+	if !c.health.servicesEngineRunning && !c.IsDraining() {
+		errs = append(errs, errors.New("services engine not running"))
+	}
+
+	return errs
+}
+
+// no traffic until the routes are in memory - otherwise every request would be a 404
+func (c *controller) CheckReadiness() []error {
+	if c.IsDraining() {
+		return []error{errors.New("proxless is shutting down")}
+	}
+
+	var errs []error
+
+	cfg := c.config.Get()
+	engines := []string{servicesEngine}
+	if cfg.ProxlessRoutes {
+		engines = append(engines, proxlessRoutesEngine)
+	}
+	if cfg.Discovery {
+		engines = append(engines, discoveryEngine)
+	}
+
+	c.health.lock.RLock()
+	for _, engine := range engines {
+		if !c.health.syncedEngines[engine] {
+			errs = append(errs, errors.New(fmt.Sprintf("%s not synced", engine)))
+		}
+	}
+	c.health.lock.RUnlock()
+
+	if c.pubsub != nil {
+		if err := c.pubsub.Ping(); err != nil {
+			errs = append(errs, errors.New(fmt.Sprintf("pubsub not connected: %s", err)))
+		}
+	}
+
+	return errs
+}
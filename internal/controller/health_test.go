@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"kube-proxless/internal/cluster/fake"
+	memorypubsub "kube-proxless/internal/pubsub/memory"
+	"testing"
+	"time"
+)
+
+func Test_checkLiveness(t *testing.T) {
+	now := time.Now()
+	// 3 * 30s + 30s with the default config
+	maxDelay := 120 * time.Second
+
+	testCases := []struct {
+		heartbeat             time.Time
+		servicesEngineRunning bool
+		draining              bool
+		errsWanted            int
+	}{
+		{now, true, false, 0},
+		// the DownScaler has not run yet
+		{time.Time{}, true, false, 0},
+		{now.Add(-maxDelay - time.Second), true, false, 1},
+		{now, false, false, 1},
+		{now.Add(-maxDelay - time.Second), false, false, 2},
+		// the engines are stopped when proxless shuts down
+		{now, false, true, 0},
+	}
+
+	for i, tc := range testCases {
+		c := helper_newController(fake.NewCluster(), nil, nil)
+		c.health.startedAt = now.Add(-time.Hour)
+		if tc.heartbeat.IsZero() {
+			c.health.startedAt = now
+		}
+
+		c.health.beatDownScaler(tc.heartbeat)
+		c.health.setServicesEngineRunning(tc.servicesEngineRunning)
+		if tc.draining {
+			c.Drain()
+		}
+
+		assert.Len(t, checkLiveness(c, now), tc.errsWanted, i)
+	}
+}
+
+func TestController_CheckReadiness(t *testing.T) {
+	ps := &fakePubSub{Interface: memorypubsub.NewMemoryPubSub(memorypubsub.NewBroker())}
+	c := helper_newController(fake.NewCluster(), ps, nil)
+	c.config.Get().ProxlessRoutes = true
+
+	assert.Len(t, c.CheckReadiness(), 2)
+
+	c.health.setEngineSynced(servicesEngine)
+	c.health.setEngineSynced(proxlessRoutesEngine)
+	assert.Empty(t, c.CheckReadiness())
+
+	// the discovery engine is only expected when enabled
+	c.config.Get().Discovery = true
+	assert.Len(t, c.CheckReadiness(), 1)
+	c.health.setEngineSynced(discoveryEngine)
+
+	ps.pingErr = errors.New("connection refused")
+	assert.Len(t, c.CheckReadiness(), 1)
+
+	ps.pingErr = nil
+	assert.Empty(t, c.CheckReadiness())
+
+	c.Drain()
+	assert.Len(t, c.CheckReadiness(), 1)
+}
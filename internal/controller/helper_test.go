@@ -1,6 +1,22 @@
 package controller
 
-import "testing"
+import (
+	"errors"
+	"kube-proxless/internal/cluster"
+	"kube-proxless/internal/config"
+	"kube-proxless/internal/memory"
+	"kube-proxless/internal/pubsub"
+	"kube-proxless/internal/store"
+	"testing"
+	"time"
+)
+
+// the memory and the controller share the default config - modify it with `c.config.Get()`
+func helper_newController(cl cluster.Interface, ps pubsub.Interface, st store.Interface) *controller {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+
+	return NewController(memory.NewMemoryMap(cfg), cl, ps, st, cfg)
+}
 
 func helper_assertAtLeastOneError(t *testing.T, errs []error) {
 	if errs == nil || len(errs) == 0 {
@@ -13,3 +29,61 @@ func helper_assertNoError(t *testing.T, errs []error) {
 		t.Errorf("Array must not have any error; %s", errs)
 	}
 }
+
+type fakeStore struct {
+	lastUsed map[string]time.Time
+	pinLease map[string]time.Time
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		lastUsed: map[string]time.Time{},
+		pinLease: map[string]time.Time{},
+	}
+}
+
+func (s *fakeStore) SetLastUsed(idRoute string, lastUsed time.Time) {
+	s.lastUsed[idRoute] = lastUsed
+}
+
+func (s *fakeStore) GetLastUsed(idRoute string) (time.Time, error) {
+	if lastUsed, ok := s.lastUsed[idRoute]; ok {
+		return lastUsed, nil
+	}
+	return time.Time{}, errors.New("key not found")
+}
+
+func (s *fakeStore) SetPinLease(idRoute string, until time.Time) {
+	s.pinLease[idRoute] = until
+}
+
+func (s *fakeStore) GetPinLease(idRoute string) (time.Time, error) {
+	if until, ok := s.pinLease[idRoute]; ok {
+		return until, nil
+	}
+	return time.Time{}, errors.New("key not found")
+}
+
+func (s *fakeStore) Close() {}
+
+// the messages go through the memory pubsub - only the connection to the broker is faked
+type fakePubSub struct {
+	pubsub.Interface
+	pingErr error
+}
+
+func (p *fakePubSub) Ping() error {
+	return p.pingErr
+}
+
+// count the scale downs - the rest is handled by the fake cluster
+type fakeScaleDownCluster struct {
+	cluster.Interface
+	scaleDowns int
+}
+
+func (c *fakeScaleDownCluster) ScaleDownDeployment(
+	deploymentName, serviceName, namespace string, lastUsed time.Time) error {
+	c.scaleDowns++
+	return nil
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
+	"sort"
+	"time"
+)
+
+// a route pinned with `proxless/pinned-until` or through the admin api
+type PinnedRouteReport struct {
+	Id          string    `json:"id"`
+	Deployment  string    `json:"deployment"`
+	Namespace   string    `json:"namespace"`
+	PinnedUntil time.Time `json:"pinnedUntil"`
+	Lease       bool      `json:"lease"` // pinned through the admin api - can be removed through the api
+}
+
+// keep the deployment of the route running until `until` - a zero time removes the lease
+// the annotation `proxless/pinned-until` is not affected
+func (c *controller) PinRoute(id string, until time.Time) error {
+	route, err := getRouteFromMemory(c, id)
+
+	if err != nil {
+		return err
+	}
+
+	if err := c.memory.UpdatePinLease(id, until); err != nil {
+		return err
+	}
+
+	if c.pubsub != nil {
+		c.pubsub.PublishPinLease(id, until)
+	}
+
+	if c.store != nil {
+		c.store.SetPinLease(id, until)
+	}
+
+	if until.IsZero() {
+		logger.Infof("Route %s unpinned", id)
+		return nil
+	}
+
+	logger.Infof("Route %s pinned until %s", id, until.Format(time.RFC3339))
+
+	// the request must not wait for the deployment to be ready
+	if !route.GetIsRunning() {
+		go func() {
+			for _, err := range wakeUpRoutes(c, []model.Route{*route}) {
+				logger.Errorf(err, "Error waking up pinned route %s", id)
+			}
+		}()
+	}
+
+	return nil
+}
+
+func (c *controller) GetPinnedRoutes() []PinnedRouteReport {
+	now := time.Now()
+	reports := []PinnedRouteReport{}
+
+	for id, route := range c.memory.GetRoutes() {
+		if !route.IsPinned(now) {
+			continue
+		}
+
+		reports = append(reports, PinnedRouteReport{
+			Id:          id,
+			Deployment:  route.GetDeployment(),
+			Namespace:   route.GetNamespace(),
+			PinnedUntil: route.GetPinExpiration(),
+			Lease:       now.Before(route.GetPinLeaseUntil()),
+		})
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Id < reports[j].Id
+	})
+
+	return reports
+}
+
+// a pinned route can be asleep - e.g. pinned with the annotation, or scaled down manually
+func wakeUpPinnedRoutes(c *controller, now time.Time) []error {
+	var routes []model.Route
+
+	for _, route := range c.memory.GetRoutes() {
+		if route.IsPinned(now) && !route.GetIsRunning() {
+			routes = append(routes, route)
+		}
+	}
+
+	return wakeUpRoutes(c, routes)
+}
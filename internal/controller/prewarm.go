@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"kube-proxless/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	hoursPerWeek = 7 * 24
+	week         = hoursPerWeek * time.Hour
+)
+
+// predictions of the pre-warmer for a route
+type PreWarmRouteReport struct {
+	Id         string `json:"id"`
+	Deployment string `json:"deployment"`
+	Namespace  string `json:"namespace"`
+	PreWarms   int    `json:"preWarms"`
+	Hits       int    `json:"hits"`   // the route has been requested in the predicted hour
+	Misses     int    `json:"misses"` // the route has not been requested in the predicted hour
+}
+
+// number of weeks the route has been requested at each hour of the week
+type trafficHistogram struct {
+	hits      [hoursPerWeek]int
+	lastWeek  [hoursPerWeek]int64 // a request is only counted once per week
+	firstSeen time.Time
+}
+
+type preWarmRoute struct {
+	histogram trafficHistogram
+	report    PreWarmRouteReport
+	// end of the hour the route has been pre-warmed for - zero if no pending prediction
+	pendingUntil time.Time
+}
+
+// the histograms are kept in memory - they are lost when proxless restarts
+type preWarmer struct {
+	lock   sync.Mutex
+	routes map[string]*preWarmRoute
+}
+
+func newPreWarmer() *preWarmer {
+	return &preWarmer{routes: map[string]*preWarmRoute{}}
+}
+
+// the hour of the week in the timezone of proxless - 0 is sunday midnight
+func getHourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+func getWeek(t time.Time) int64 {
+	return t.Unix() / int64(week/time.Second)
+}
+
+// called on each request - must be cheap
+func (p *preWarmer) record(id string, now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pr, ok := p.routes[id]
+	if !ok {
+		// the weeks are counted from the start of the hour - the same hour next week is a full week later
+		pr = &preWarmRoute{histogram: trafficHistogram{firstSeen: now.Truncate(time.Hour)}}
+		p.routes[id] = pr
+	}
+
+	hour, w := getHourOfWeek(now), getWeek(now)
+	if pr.histogram.lastWeek[hour] != w {
+		pr.histogram.lastWeek[hour] = w
+		pr.histogram.hits[hour]++
+	}
+
+	if !pr.pendingUntil.IsZero() && !now.After(pr.pendingUntil) {
+		pr.pendingUntil = time.Time{}
+		pr.report.Hits++
+	}
+}
+
+// ratio of the observed weeks in which the route has been requested during the hour of `t`
+// 0 if the route has not been observed for `minWeeks` weeks yet
+func (p *preWarmer) getConfidence(id string, t time.Time, minWeeks int) float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pr, ok := p.routes[id]
+	if !ok {
+		return 0
+	}
+
+	weeks := int(t.Sub(pr.histogram.firstSeen) / week)
+	if weeks < 1 || weeks < minWeeks {
+		return 0
+	}
+
+	confidence := float64(pr.histogram.hits[getHourOfWeek(t)]) / float64(weeks)
+	if confidence > 1 {
+		return 1
+	}
+
+	return confidence
+}
+
+// return false if the route is already pre-warmed for this hour
+func (p *preWarmer) preWarm(route model.Route, hourStart time.Time) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pr, ok := p.routes[route.GetId()]
+	if !ok {
+		return false
+	}
+
+	hourEnd := hourStart.Add(time.Hour)
+	if pr.pendingUntil.Equal(hourEnd) {
+		return false
+	}
+
+	pr.report.Id = route.GetId()
+	pr.report.Deployment = route.GetDeployment()
+	pr.report.Namespace = route.GetNamespace()
+	pr.report.PreWarms++
+	pr.pendingUntil = hourEnd
+
+	return true
+}
+
+// count the predictions that expired without request as misses
+func (p *preWarmer) expire(now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, pr := range p.routes {
+		if !pr.pendingUntil.IsZero() && now.After(pr.pendingUntil) {
+			pr.pendingUntil = time.Time{}
+			pr.report.Misses++
+		}
+	}
+}
+
+func (p *preWarmer) get() []PreWarmRouteReport {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	reports := []PreWarmRouteReport{}
+
+	for _, pr := range p.routes {
+		if pr.report.PreWarms > 0 {
+			reports = append(reports, pr.report)
+		}
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Id < reports[j].Id
+	})
+
+	return reports
+}
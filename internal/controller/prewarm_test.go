@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"github.com/stretchr/testify/assert"
+	"kube-proxless/internal/model"
+	"testing"
+	"time"
+)
+
+func Test_getHourOfWeek(t *testing.T) {
+	testCases := []struct {
+		t    time.Time
+		want int
+	}{
+		{time.Date(2020, 6, 7, 0, 30, 0, 0, time.UTC), 0}, // sunday
+		{time.Date(2020, 6, 8, 9, 0, 0, 0, time.UTC), 33}, // monday
+		{time.Date(2020, 6, 13, 23, 59, 0, 0, time.UTC), 167},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, getHourOfWeek(tc.t), tc.t)
+	}
+}
+
+func Test_preWarmer(t *testing.T) {
+	p := newPreWarmer()
+	monday := time.Date(2020, 6, 1, 9, 10, 0, 0, time.UTC)
+
+	route, err := model.NewRoute("id", "svc", "", "deploy", "ns", []string{"example.io"}, true, nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(0), p.getConfidence("id", monday, 0))
+	assert.False(t, p.preWarm(*route, monday.Truncate(time.Hour)))
+
+	// requested every monday at 9 for 3 weeks - counted once per week
+	for i := 0; i < 3; i++ {
+		p.record("id", monday.Add(time.Duration(i)*week))
+		p.record("id", monday.Add(time.Duration(i)*week+time.Minute))
+	}
+
+	nextMonday := monday.Add(3 * week).Truncate(time.Hour)
+	assert.Equal(t, float64(0), p.getConfidence("id", nextMonday, 4)) // not observed long enough
+	assert.Equal(t, float64(1), p.getConfidence("id", nextMonday, 2))
+	assert.Equal(t, float64(0), p.getConfidence("id", nextMonday.Add(time.Hour), 2))
+
+	// hit - requested in the predicted hour
+	assert.True(t, p.preWarm(*route, nextMonday))
+	assert.False(t, p.preWarm(*route, nextMonday))
+	p.record("id", nextMonday.Add(5*time.Minute))
+
+	// miss - not requested in the predicted hour
+	assert.True(t, p.preWarm(*route, nextMonday.Add(week)))
+	p.expire(nextMonday.Add(week + 30*time.Minute))
+	p.expire(nextMonday.Add(week + 2*time.Hour))
+	p.record("id", nextMonday.Add(week+3*time.Hour))
+
+	assert.Equal(t, []PreWarmRouteReport{
+		{Id: "id", Deployment: "deploy", Namespace: "ns", PreWarms: 2, Hits: 1, Misses: 1},
+	}, p.get())
+}
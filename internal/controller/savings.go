@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"kube-proxless/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// resources not used by the cluster while the deployment of the route is scaled down
+type SavingsRouteReport struct {
+	Id            string  `json:"id"`
+	Deployment    string  `json:"deployment"`
+	Namespace     string  `json:"namespace"`
+	AsleepSeconds float64 `json:"asleepSeconds"`
+	CPUHours      float64 `json:"cpuHours"`
+	MemoryGBHours float64 `json:"memoryGBHours"`
+	Cost          float64 `json:"cost"`
+}
+
+type SavingsNamespaceReport struct {
+	Namespace     string  `json:"namespace"`
+	AsleepSeconds float64 `json:"asleepSeconds"`
+	CPUHours      float64 `json:"cpuHours"`
+	MemoryGBHours float64 `json:"memoryGBHours"`
+	Cost          float64 `json:"cost"`
+}
+
+type SavingsReport struct {
+	Routes     []SavingsRouteReport     `json:"routes"`
+	Namespaces []SavingsNamespaceReport `json:"namespaces"`
+}
+
+type savingsRoute struct {
+	report SavingsRouteReport
+	asleep bool
+	// requests of the deployment when it has been scaled down
+	cpu, memory float64
+}
+
+// the savings are cumulative since proxless started - the routes removed from memory are kept
+type savingsTracker struct {
+	lock        sync.Mutex
+	routes      map[string]*savingsRoute
+	lastCollect time.Time
+}
+
+func newSavingsTracker() *savingsTracker {
+	return &savingsTracker{routes: map[string]*savingsRoute{}}
+}
+
+// return the time elapsed since the last collect - 0 on the first collect
+func (s *savingsTracker) tick(now time.Time) time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	elapsed := time.Duration(0)
+	if !s.lastCollect.IsZero() {
+		elapsed = now.Sub(s.lastCollect)
+	}
+	s.lastCollect = now
+
+	return elapsed
+}
+
+func (s *savingsTracker) isAsleep(id string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sr, ok := s.routes[id]
+
+	return ok && sr.asleep
+}
+
+func (s *savingsTracker) sleep(route model.Route, cpu, memory float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sr, ok := s.routes[route.GetId()]
+	if !ok {
+		sr = &savingsRoute{}
+		s.routes[route.GetId()] = sr
+	}
+
+	sr.report.Id = route.GetId()
+	sr.report.Deployment = route.GetDeployment()
+	sr.report.Namespace = route.GetNamespace()
+	sr.asleep = true
+	sr.cpu = cpu
+	sr.memory = memory
+}
+
+func (s *savingsTracker) wakeUp(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if sr, ok := s.routes[id]; ok {
+		sr.asleep = false
+	}
+}
+
+func (s *savingsTracker) add(id string, elapsed time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sr, ok := s.routes[id]
+	if !ok || !sr.asleep {
+		return
+	}
+
+	sr.report.AsleepSeconds += elapsed.Seconds()
+	sr.report.CPUHours += sr.cpu * elapsed.Hours()
+	sr.report.MemoryGBHours += sr.memory * elapsed.Hours()
+}
+
+func (s *savingsTracker) get(cpuHourPrice, memoryGBHourPrice float64) SavingsReport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	report := SavingsReport{Routes: []SavingsRouteReport{}, Namespaces: []SavingsNamespaceReport{}}
+	namespaces := map[string]*SavingsNamespaceReport{}
+
+	for _, sr := range s.routes {
+		r := sr.report
+		r.Cost = r.CPUHours*cpuHourPrice + r.MemoryGBHours*memoryGBHourPrice
+		report.Routes = append(report.Routes, r)
+
+		ns, ok := namespaces[r.Namespace]
+		if !ok {
+			ns = &SavingsNamespaceReport{Namespace: r.Namespace}
+			namespaces[r.Namespace] = ns
+		}
+
+		ns.AsleepSeconds += r.AsleepSeconds
+		ns.CPUHours += r.CPUHours
+		ns.MemoryGBHours += r.MemoryGBHours
+		ns.Cost += r.Cost
+	}
+
+	for _, ns := range namespaces {
+		report.Namespaces = append(report.Namespaces, *ns)
+	}
+
+	sort.Slice(report.Routes, func(i, j int) bool {
+		return report.Routes[i].Id < report.Routes[j].Id
+	})
+
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+
+	return report
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"github.com/stretchr/testify/assert"
+	"kube-proxless/internal/model"
+	"testing"
+	"time"
+)
+
+func Test_savingsTracker(t *testing.T) {
+	s := newSavingsTracker()
+	now := time.Now()
+
+	route1, err := model.NewRoute("id1", "svc1", "", "deploy1", "ns1", []string{"one.io"}, true, nil, nil)
+	assert.NoError(t, err)
+	route2, err := model.NewRoute("id2", "svc2", "", "deploy2", "ns1", []string{"two.io"}, true, nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, time.Duration(0), s.tick(now))
+	assert.Equal(t, time.Hour, s.tick(now.Add(time.Hour)))
+
+	// not asleep - nothing is saved
+	s.add("id1", time.Hour)
+	assert.False(t, s.isAsleep("id1"))
+
+	s.sleep(*route1, 0.5, 2)
+	s.sleep(*route2, 1, 1)
+	assert.True(t, s.isAsleep("id1"))
+	s.add("id1", 2*time.Hour)
+	s.add("id2", time.Hour)
+
+	s.wakeUp("id1")
+	s.add("id1", time.Hour)
+
+	assert.Equal(t, SavingsReport{
+		Routes: []SavingsRouteReport{
+			{Id: "id1", Deployment: "deploy1", Namespace: "ns1",
+				AsleepSeconds: 7200, CPUHours: 1, MemoryGBHours: 4, Cost: 1*0.1 + 4*0.01},
+			{Id: "id2", Deployment: "deploy2", Namespace: "ns1",
+				AsleepSeconds: 3600, CPUHours: 1, MemoryGBHours: 1, Cost: 1*0.1 + 1*0.01},
+		},
+		Namespaces: []SavingsNamespaceReport{
+			{Namespace: "ns1", AsleepSeconds: 10800, CPUHours: 2, MemoryGBHours: 5, Cost: 2*0.1 + 5*0.01},
+		},
+	}, s.get(0.1, 0.01))
+}
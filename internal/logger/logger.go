@@ -9,10 +9,16 @@ import (
 )
 
 func InitLogger() {
-	logLevel := os.Getenv("LOG_LEVEL")
-
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
+	SetLevel(os.Getenv("LOG_LEVEL"))
+
+	// log.Logger = log.With().Caller().Logger()
+	log.Info().Msgf("logger initialized with %s settings", zerolog.GlobalLevel())
+}
+
+// can be called at any time - e.g. when the config is reloaded
+func SetLevel(logLevel string) {
 	switch strings.ToUpper(logLevel) {
 	case "VERBOSE":
 		zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
@@ -26,9 +32,15 @@ func InitLogger() {
 	default:
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	}
+}
 
-	// log.Logger = log.With().Caller().Logger()
-	log.Info().Msgf("logger initialized with %s settings", zerolog.GlobalLevel())
+func IsValidLevel(logLevel string) bool {
+	switch strings.ToUpper(logLevel) {
+	case "VERBOSE", "DEBUG", "INFO", "ERROR", "FATAL", "PANIC", "":
+		return true
+	}
+
+	return false
 }
 
 func Errorf(err error, msg string, params ...interface{}) {
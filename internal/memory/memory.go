@@ -17,26 +17,36 @@ type Interface interface {
 	GetRouteByDeployment(deploy, namespace string) (*model.Route, error)
 	UpdateLastUsed(id string, t time.Time) error
 	UpdateIsRunning(id string, isRunning bool) error
+	UpdateReplicas(id, deployment string, replicas, availableReplicas int) error
+	UpdateEndpoints(id string, endpoints []string) error
+	UpdatePinLease(id string, until time.Time) error
 	DeleteRoute(id string) error
 	GetRoutesToScaleDown() map[string]model.Route
+	GetRoutes() map[string]model.Route
 }
 
 type MemoryMap struct {
-	m    map[string]*model.Route
-	lock sync.RWMutex
+	m      map[string]*model.Route
+	lock   sync.RWMutex
+	config *config.Provider
 }
 
-func NewMemoryMap() *MemoryMap {
+func NewMemoryMap(cfg *config.Provider) *MemoryMap {
 	return &MemoryMap{
-		m:    make(map[string]*model.Route),
-		lock: sync.RWMutex{},
+		m:      make(map[string]*model.Route),
+		lock:   sync.RWMutex{},
+		config: cfg,
 	}
 }
 
+// the engines upsert concurrently - the lock is held from the ownership check to the update of the keys
 func (s *MemoryMap) UpsertMemoryMap(route *model.Route) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	// error if deployment or domains are already associated to another route
 	err := checkDeployAndDomainsOwnership(
-		s, route.GetId(), route.GetDeployment(), route.GetNamespace(), route.GetDomains())
+		s, route.GetId(), route.GetDeployments(), route.GetNamespace(), route.GetDomains())
 
 	if err != nil {
 		return err
@@ -46,8 +56,8 @@ func (s *MemoryMap) UpsertMemoryMap(route *model.Route) error {
 		// /!\ this need to be on top - otherwise the data will have already been overriden in the route
 		newKeys := cleanMemoryMap(
 			s,
-			existingRoute.GetDeployment(), existingRoute.GetNamespace(), existingRoute.GetDomains(),
-			route.GetDeployment(), route.GetNamespace(), route.GetDomains())
+			existingRoute.GetDeployments(), existingRoute.GetNamespace(), existingRoute.GetDomains(),
+			route.GetDeployments(), route.GetNamespace(), route.GetDomains())
 
 		// associate the route to new deployment key / domains
 		for _, k := range newKeys {
@@ -57,14 +67,18 @@ func (s *MemoryMap) UpsertMemoryMap(route *model.Route) error {
 		// TODO check the errors
 		_ = existingRoute.SetService(route.GetService())
 		_ = existingRoute.SetPort(route.GetPort())
-		_ = existingRoute.SetDeployment(route.GetDeployment())
+		existingRoute.SetPorts(route.GetPorts())
+		existingRoute.SetDomainsPorts(route.GetDomainsPorts())
+		_ = existingRoute.SetDeployments(route.GetDeployments())
 		_ = existingRoute.SetDomains(route.GetDomains())
 		existingRoute.SetTTLSeconds(route.GetTTLSeconds())
 		existingRoute.SetReadinessTimeoutSeconds(route.GetReadinessTimeoutSeconds())
+		existingRoute.SetMinUptimeSeconds(route.GetMinUptimeSeconds())
+		existingRoute.SetPinnedUntil(route.GetPinnedUntil())
 		// existingRoute is a pointer and it's changing dynamically - no need to "persist" the change in the map
 
 		keys := append(
-			[]string{route.GetId(), genDeploymentKey(existingRoute.GetDeployment(), existingRoute.GetNamespace())},
+			append([]string{route.GetId()}, genDeploymentKeys(existingRoute.GetDeployments(), existingRoute.GetNamespace())...),
 			route.GetDomains()...)
 		logger.Debugf("Updated route - newKeys: [%s] - keys: [%s] - obj: %v", newKeys, keys, existingRoute)
 	} else {
@@ -74,18 +88,21 @@ func (s *MemoryMap) UpsertMemoryMap(route *model.Route) error {
 	return nil
 }
 
-// return an error if deploy or domains are already associated to a different id
-func checkDeployAndDomainsOwnership(s *MemoryMap, id, deploy, ns string, domains []string) error {
-	r, err := s.GetRouteByDeployment(deploy, ns)
+// return an error if one of the deployments or domains is already associated to a different id
+// the caller must hold the lock
+func checkDeployAndDomainsOwnership(s *MemoryMap, id string, deploys []string, ns string, domains []string) error {
+	for _, deploy := range deploys {
+		r, ok := s.m[genDeploymentKey(deploy, ns)]
 
-	if err == nil && r.GetId() != id {
-		return errors.New(fmt.Sprintf("Deployment %s.%s is already owned by %s", deploy, ns, r.GetId()))
+		if ok && r.GetId() != id {
+			return errors.New(fmt.Sprintf("Deployment %s.%s is already owned by %s", deploy, ns, r.GetId()))
+		}
 	}
 
 	for _, d := range domains {
-		r, err = s.GetRouteByDomain(d)
+		r, ok := s.m[d]
 
-		if err == nil && r.GetId() != id {
+		if ok && r.GetId() != id {
 			return errors.New(fmt.Sprintf("Domain %s is already owned by %s", d, r.GetId()))
 		}
 	}
@@ -93,37 +110,29 @@ func checkDeployAndDomainsOwnership(s *MemoryMap, id, deploy, ns string, domains
 	return nil
 }
 
+// the caller must hold the lock
 func createRoute(s *MemoryMap, route *model.Route) {
-	s.lock.Lock()
-	defer s.lock.Unlock()
-
-	deploymentKey := genDeploymentKey(route.GetDeployment(), route.GetNamespace())
+	deploymentKeys := genDeploymentKeys(route.GetDeployments(), route.GetNamespace())
 	s.m[route.GetId()] = route
-	s.m[deploymentKey] = route
+	for _, k := range deploymentKeys {
+		s.m[k] = route
+	}
 	for _, d := range route.GetDomains() {
 		s.m[d] = route
 	}
 
-	keys := append([]string{route.GetId(), deploymentKey}, route.GetDomains()...)
+	keys := append(append([]string{route.GetId()}, deploymentKeys...), route.GetDomains()...)
 	logger.Debugf("Created route - keys: [%s] - obj: %v", keys, route)
 }
 
 // Remove old domains and deployment from the map if they are not == new ones
 // return the domains and deployment that are not a key in the map
+// the caller must hold the lock
 func cleanMemoryMap(
 	s *MemoryMap,
-	oldDeploy, oldNs string, oldDomains []string,
-	newDeploy, newNs string, newDomains []string) []string {
-	s.lock.Lock()
-	defer s.lock.Unlock()
-
-	var newKeys []string
-
-	deployKeyNotInMap := cleanOldDeploymentFromMemoryMap(s, oldDeploy, oldNs, newDeploy, newNs)
-
-	if deployKeyNotInMap != "" {
-		newKeys = append(newKeys, deployKeyNotInMap)
-	}
+	oldDeploys []string, oldNs string, oldDomains []string,
+	newDeploys []string, newNs string, newDomains []string) []string {
+	newKeys := cleanOldDeploymentsFromMemoryMap(s, oldDeploys, oldNs, newDeploys, newNs)
 
 	domainsNotInMap := cleanOldDomainsFromMemoryMap(s, oldDomains, newDomains)
 
@@ -138,17 +147,27 @@ func cleanMemoryMap(
 	return newKeys
 }
 
-// return the new deployment key if it does not exist in the map
-func cleanOldDeploymentFromMemoryMap(s *MemoryMap, oldDeploy, oldNs, newDeploy, newNs string) string {
-	oldDeploymentKey := genDeploymentKey(oldDeploy, oldNs)
-	newDeploymentKey := genDeploymentKey(newDeploy, newNs)
+// return the new deployment keys that do not exist in the map
+func cleanOldDeploymentsFromMemoryMap(
+	s *MemoryMap, oldDeploys []string, oldNs string, newDeploys []string, newNs string) []string {
+	oldDeploymentKeys := genDeploymentKeys(oldDeploys, oldNs)
+	newDeploymentKeys := genDeploymentKeys(newDeploys, newNs)
+
+	for _, k := range oldDeploymentKeys {
+		if !utils.Contains(newDeploymentKeys, k) {
+			delete(s.m, k)
+		}
+	}
 
-	if oldDeploymentKey != newDeploymentKey {
-		delete(s.m, oldDeploymentKey)
-		return newDeploymentKey
+	var newKeys []string
+
+	for _, k := range newDeploymentKeys {
+		if !utils.Contains(oldDeploymentKeys, k) {
+			newKeys = append(newKeys, k)
+		}
 	}
 
-	return ""
+	return newKeys
 }
 
 // TODO review complexity
@@ -181,6 +200,16 @@ func genDeploymentKey(deployment, namespace string) string {
 	return fmt.Sprintf("%s.%s", deployment, namespace)
 }
 
+func genDeploymentKeys(deployments []string, namespace string) []string {
+	keys := make([]string, 0, len(deployments))
+
+	for _, d := range deployments {
+		keys = append(keys, genDeploymentKey(d, namespace))
+	}
+
+	return keys
+}
+
 func (s *MemoryMap) GetRouteByDomain(domain string) (*model.Route, error) {
 	return getRoute(s, domain)
 }
@@ -190,12 +219,14 @@ func (s *MemoryMap) GetRouteByDeployment(deploy, namespace string) (*model.Route
 	return getRoute(s, deploymentKey)
 }
 
+// return a copy of the route - the informers update the route in memory while the proxy reads it
 func getRoute(s *MemoryMap, key string) (*model.Route, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
 	if route, ok := s.m[key]; ok {
-		return route, nil
+		r := *route
+		return &r, nil
 	}
 
 	return nil, errors.New(fmt.Sprintf("Route %s not found in map", key))
@@ -220,7 +251,124 @@ func (s *MemoryMap) UpdateIsRunning(id string, isRunning bool) error {
 
 	if route, ok := s.m[id]; ok {
 		// No need to persist in the map, it's a pointer
-		route.SetIsRunning(isRunning)
+		setIsRunning(s.config.Get(), route, isRunning, time.Now())
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf("Route %s not found in map", id))
+}
+
+// keep track of the wake ups and the scale downs for the min uptime and the adaptive TTL
+func setIsRunning(cfg *config.Config, route *model.Route, isRunning bool, now time.Time) {
+	if isRunning && !route.GetIsRunning() {
+		route.SetWokeUpAt(now)
+
+		if cfg.AdaptiveTTL {
+			route.SetTTLMultiplier(genTTLMultiplier(cfg, route, now))
+		}
+	} else if !isRunning && route.GetIsRunning() {
+		route.SetScaledDownAt(now)
+	}
+
+	route.SetIsRunning(isRunning)
+}
+
+// the TTL is doubled when the deployment is woken up before the end of the TTL - it was scaled down too early
+// and halved when it stayed scaled down longer than the TTL
+func genTTLMultiplier(cfg *config.Config, route *model.Route, wokeUpAt time.Time) int {
+	multiplier := route.GetTTLMultiplier()
+
+	if route.GetScaledDownAt().IsZero() {
+		return multiplier
+	}
+
+	asleep := wokeUpAt.Sub(route.GetScaledDownAt())
+	ttl := getTTLSeconds(cfg, route)
+
+	if asleep < time.Duration(ttl)*time.Second {
+		if ttl*2 <= maxInt(cfg.AdaptiveTTLMaxSeconds, getBaseTTLSeconds(cfg, route)) {
+			multiplier *= 2
+			logger.Infof("Route %s woken up %s after being scaled down - TTL extended to %d seconds",
+				route.GetId(), asleep.Round(time.Second), getBaseTTLSeconds(cfg, route)*multiplier)
+		}
+	} else if multiplier > 1 {
+		multiplier /= 2
+		logger.Debugf("Route %s scaled down for %s - TTL reduced to %d seconds",
+			route.GetId(), asleep.Round(time.Second), getBaseTTLSeconds(cfg, route)*multiplier)
+	}
+
+	return multiplier
+}
+
+// `proxless/ttl-seconds` or `SERVERLESS_TTL_SECONDS`
+func getBaseTTLSeconds(cfg *config.Config, route *model.Route) int {
+	if route.GetTTLSeconds() != nil {
+		return *route.GetTTLSeconds()
+	}
+
+	return cfg.ServerlessTTLSeconds
+}
+
+// the base TTL extended by the adaptive TTL
+func getTTLSeconds(cfg *config.Config, route *model.Route) int {
+	ttl := getBaseTTLSeconds(cfg, route) * route.GetTTLMultiplier()
+
+	if route.GetTTLMultiplier() > 1 && ttl > cfg.AdaptiveTTLMaxSeconds {
+		return maxInt(cfg.AdaptiveTTLMaxSeconds, getBaseTTLSeconds(cfg, route))
+	}
+
+	return ttl
+}
+
+func getMinUptimeSeconds(cfg *config.Config, route *model.Route) int {
+	if route.GetMinUptimeSeconds() != nil {
+		return *route.GetMinUptimeSeconds()
+	}
+
+	return cfg.MinUptimeSeconds
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func (s *MemoryMap) UpdateReplicas(id, deployment string, replicas, availableReplicas int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if route, ok := s.m[id]; ok {
+		// No need to persist in the map, it's a pointer
+		route.SetReplicas(deployment, replicas, availableReplicas)
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf("Route %s not found in map", id))
+}
+
+func (s *MemoryMap) UpdateEndpoints(id string, endpoints []string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if route, ok := s.m[id]; ok {
+		// No need to persist in the map, it's a pointer
+		route.SetEndpoints(endpoints)
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf("Route %s not found in map", id))
+}
+
+func (s *MemoryMap) UpdatePinLease(id string, until time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if route, ok := s.m[id]; ok {
+		// No need to persist in the map, it's a pointer
+		route.SetPinLeaseUntil(until)
 		return nil
 	}
 
@@ -232,9 +380,10 @@ func (s *MemoryMap) DeleteRoute(id string) error {
 	defer s.lock.Unlock()
 
 	if route, ok := s.m[id]; ok {
-		deploymentKey := genDeploymentKey(route.GetDeployment(), route.GetNamespace())
 		delete(s.m, route.GetId())
-		delete(s.m, deploymentKey)
+		for _, k := range genDeploymentKeys(route.GetDeployments(), route.GetNamespace()) {
+			delete(s.m, k)
+		}
 		for _, d := range route.GetDomains() {
 			delete(s.m, d)
 		}
@@ -249,14 +398,21 @@ func (s *MemoryMap) GetRoutesToScaleDown() map[string]model.Route {
 	defer s.lock.Unlock()
 
 	deploymentToScaleDown := map[string]model.Route{}
+	now := time.Now()
+	cfg := s.config.Get()
 
 	for _, route := range s.m {
 		if _, ok := deploymentToScaleDown[route.GetId()]; !ok {
-			timeIdle := time.Now().Sub(route.GetLastUsed())
+			timeIdle := now.Sub(route.GetLastUsed())
+			ttl := getTTLSeconds(cfg, route)
+
+			if route.IsPinned(now) {
+				continue
+			}
 
-			ttl := config.ServerlessTTLSeconds
-			if route.GetTTLSeconds() != nil {
-				ttl = *route.GetTTLSeconds()
+			// the deployment must stay up at least `proxless/min-uptime-seconds` after being woken up
+			if route.GetIsRunning() && now.Sub(route.GetWokeUpAt()) < time.Duration(getMinUptimeSeconds(cfg, route))*time.Second {
+				continue
 			}
 
 			// https://stackoverflow.com/a/41503910/5683655
@@ -268,3 +424,18 @@ func (s *MemoryMap) GetRoutesToScaleDown() map[string]model.Route {
 
 	return deploymentToScaleDown
 }
+
+func (s *MemoryMap) GetRoutes() map[string]model.Route {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	routes := map[string]model.Route{}
+
+	for _, route := range s.m {
+		if _, ok := routes[route.GetId()]; !ok {
+			routes[route.GetId()] = *route
+		}
+	}
+
+	return routes
+}
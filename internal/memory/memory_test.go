@@ -3,8 +3,10 @@ package memory
 import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"kube-proxless/internal/config"
 	"kube-proxless/internal/model"
 	"kube-proxless/internal/utils"
+	"sync"
 	"testing"
 	"time"
 )
@@ -18,7 +20,7 @@ type upsertTestCaseStruct struct {
 }
 
 func TestMemoryMap_UpsertMap_Create(t *testing.T) {
-	s := NewMemoryMap()
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
 
 	// create route
 	testCases := []upsertTestCaseStruct{
@@ -30,7 +32,7 @@ func TestMemoryMap_UpsertMap_Create(t *testing.T) {
 }
 
 func TestMemoryMap_UpsertMemoryMap_Update(t *testing.T) {
-	s := NewMemoryMap()
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
 
 	testCases := []upsertTestCaseStruct{
 		{"updateTestCase0", "svc0", "80", "deploy0", "ns", []string{"example.0.0"}, false},
@@ -46,6 +48,75 @@ func TestMemoryMap_UpsertMemoryMap_Update(t *testing.T) {
 	upsertMemoryMapHelper(testCases, t, s)
 }
 
+// the engines upsert from different goroutines - only one of the routes claiming the same domain must win
+func TestMemoryMap_UpsertMemoryMap_Concurrent(t *testing.T) {
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			route, err := model.NewRoute(
+				fmt.Sprintf("id%d", i), "svc", "", fmt.Sprintf("deploy%d", i), "ns", []string{"example.io"}, true, nil, nil)
+			assert.NoError(t, err)
+
+			errs <- s.UpsertMemoryMap(route)
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+
+	assert.Equal(t, 1, succeeded)
+	assert.Len(t, s.GetRoutes(), 1)
+}
+
+func TestMemoryMap_UpsertMemoryMap_MultipleDeployments(t *testing.T) {
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
+
+	route, err := model.NewRoute("0", "svc0", "", "deploy-a", "ns0", []string{"example.0"}, true, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, route.SetDeployments([]string{"deploy-a", "deploy-b"}))
+	assert.NoError(t, s.UpsertMemoryMap(route))
+
+	// each deployment is a key of the route
+	for _, deploy := range []string{"deploy-a", "deploy-b"} {
+		r, err := s.GetRouteByDeployment(deploy, "ns0")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "0", r.GetId())
+		}
+	}
+	_, err = s.GetRouteByDeployment("deploy-a,deploy-b", "ns0")
+	assert.Error(t, err)
+
+	// another route can't own one of the deployments
+	other, _ := model.NewRoute("1", "svc1", "", "deploy-b", "ns0", []string{"example.1"}, true, nil, nil)
+	assert.Error(t, s.UpsertMemoryMap(other))
+
+	updated, _ := model.NewRoute("0", "svc0", "", "deploy-b", "ns0", []string{"example.0"}, true, nil, nil)
+	assert.NoError(t, updated.SetDeployments([]string{"deploy-b", "deploy-c"}))
+	assert.NoError(t, s.UpsertMemoryMap(updated))
+
+	_, err = s.GetRouteByDeployment("deploy-a", "ns0")
+	assert.Error(t, err)
+	_, err = s.GetRouteByDeployment("deploy-c", "ns0")
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.DeleteRoute("0"))
+	assert.Len(t, s.m, 0)
+}
+
 func TestMemoryMap_genDeploymentKey(t *testing.T) {
 	deploy := "exampledeploy"
 	ns := "examplens"
@@ -59,7 +130,7 @@ func TestMemoryMap_genDeploymentKey(t *testing.T) {
 }
 
 func TestMemoryMap_CheckDeployAndDomainsOwnership(t *testing.T) {
-	s := NewMemoryMap()
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
 
 	route, err := model.NewRoute(
 		"0", "svc0", "", "deploy0", "ns0", []string{"example.0.0"}, true, nil, nil)
@@ -69,29 +140,32 @@ func TestMemoryMap_CheckDeployAndDomainsOwnership(t *testing.T) {
 	assert.NoError(t, err)
 
 	testCases := []struct {
-		id, deploy, ns string
-		domains        []string
-		errWanted      bool
+		id        string
+		deploys   []string
+		ns        string
+		domains   []string
+		errWanted bool
 	}{
-		{"0", "deploy0", "ns0", []string{"example.0.0"}, false},
-		{"0", "deploy0", "ns0", []string{"example.0.0"}, false},
-		{"0", "deploy0", "ns0", []string{"example.0.1"}, false},
-		{"1", "deploy1", "ns1", []string{"example.0.0"}, true},
-		{"1", "deploy0", "ns0", []string{"example.1.0"}, true},
+		{"0", []string{"deploy0"}, "ns0", []string{"example.0.0"}, false},
+		{"0", []string{"deploy0"}, "ns0", []string{"example.0.0"}, false},
+		{"0", []string{"deploy0"}, "ns0", []string{"example.0.1"}, false},
+		{"1", []string{"deploy1"}, "ns1", []string{"example.0.0"}, true},
+		{"1", []string{"deploy0"}, "ns0", []string{"example.1.0"}, true},
+		{"1", []string{"deploy1", "deploy0"}, "ns0", []string{"example.1.0"}, true},
 	}
 
 	for _, tc := range testCases {
-		errGot := checkDeployAndDomainsOwnership(s, tc.id, tc.deploy, tc.ns, tc.domains)
+		errGot := checkDeployAndDomainsOwnership(s, tc.id, tc.deploys, tc.ns, tc.domains)
 
 		if tc.errWanted != (errGot != nil) {
 			t.Errorf("checkDeployAndDomainsOwnership(%s, %s ,%s, %s) = %v, errWanted = %t",
-				tc.id, tc.deploy, tc.ns, tc.domains, errGot, tc.errWanted)
+				tc.id, tc.deploys, tc.ns, tc.domains, errGot, tc.errWanted)
 		}
 	}
 }
 
 func TestMemoryMap_cleanOldDeploymentFromMap(t *testing.T) {
-	s := NewMemoryMap()
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
 
 	r0, err :=
 		model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0.0"}, true, nil, nil)
@@ -122,7 +196,7 @@ func TestMemoryMap_cleanOldDeploymentFromMap(t *testing.T) {
 }
 
 func TestMemoryMap_UpdateLastUse(t *testing.T) {
-	s := NewMemoryMap()
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
 
 	r0, err :=
 		model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0.0"}, true, nil, nil)
@@ -154,7 +228,7 @@ func TestMemoryMap_UpdateLastUse(t *testing.T) {
 }
 
 func TestMemoryMap_DeleteRoute(t *testing.T) {
-	s := NewMemoryMap()
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
 
 	r0, _ := model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0.0"}, true, nil, nil)
 	createRoute(s, r0)
@@ -183,3 +257,152 @@ func TestMemoryMap_DeleteRoute(t *testing.T) {
 		}
 	}
 }
+
+func TestMemoryMap_GetRoutes(t *testing.T) {
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
+
+	assert.Len(t, s.GetRoutes(), 0)
+
+	r0, _ := model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0.0", "example.0.1"}, true, nil, nil)
+	createRoute(s, r0)
+	r1, _ := model.NewRoute("1", "svc1", "", "deploy1", "ns1", []string{"example.1.0"}, true, nil, nil)
+	createRoute(s, r1)
+
+	// each route is referenced by multiple keys but must only be returned once
+	routes := s.GetRoutes()
+	assert.Len(t, routes, 2)
+	for _, r := range []*model.Route{r0, r1} {
+		route := routes[r.GetId()]
+		assert.Equal(t, r.GetDeployment(), route.GetDeployment())
+	}
+}
+
+func TestMemoryMap_UpdateReplicas(t *testing.T) {
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
+
+	r0, _ := model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0.0"}, true, nil, nil)
+	createRoute(s, r0)
+
+	assert.NoError(t, s.UpdateReplicas(r0.GetId(), "deploy0", 2, 1))
+	assert.Equal(t, 2, r0.GetReplicas())
+	assert.Equal(t, 1, r0.GetAvailableReplicas())
+
+	assert.Error(t, s.UpdateReplicas("", "deploy0", 2, 1))
+}
+
+func TestMemoryMap_UpdateEndpoints(t *testing.T) {
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
+
+	r0, _ := model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0.0"}, true, nil, nil)
+	createRoute(s, r0)
+
+	assert.NoError(t, s.UpdateEndpoints(r0.GetId(), []string{"10.0.0.1", "10.0.0.2"}))
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, r0.GetEndpoints())
+
+	// the endpoints are kept when the service is updated
+	r1, _ := model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0.1"}, true, nil, nil)
+	assert.NoError(t, s.UpsertMemoryMap(r1))
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, r0.GetEndpoints())
+
+	assert.Error(t, s.UpdateEndpoints("", []string{}))
+}
+
+func TestMemoryMap_GetRoutesToScaleDown(t *testing.T) {
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
+	s.config.Get().ServerlessTTLSeconds = 60
+	s.config.Get().MinUptimeSeconds = 0
+	minUptimeSeconds := 300
+
+	r0, _ := model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0"}, true, nil, nil)
+	r1, _ := model.NewRoute("1", "svc1", "", "deploy1", "ns1", []string{"example.1"}, false, nil, nil)
+	r1.SetMinUptimeSeconds(&minUptimeSeconds)
+	r2, _ := model.NewRoute("2", "svc2", "", "deploy2", "ns2", []string{"example.2"}, true, nil, nil)
+	for _, r := range []*model.Route{r0, r1, r2} {
+		createRoute(s, r)
+		r.SetLastUsed(time.Now().Add(-2 * time.Minute))
+	}
+	r2.SetLastUsed(time.Now())
+
+	// r1 has just been woken up
+	assert.NoError(t, s.UpdateIsRunning("1", true))
+
+	routes := s.GetRoutesToScaleDown()
+	assert.Len(t, routes, 1)
+	assert.Contains(t, routes, "0")
+
+	// the min uptime is over
+	r1.SetWokeUpAt(time.Now().Add(-10 * time.Minute))
+	assert.Len(t, s.GetRoutesToScaleDown(), 2)
+
+	// the TTL has been extended by the adaptive TTL
+	r0.SetTTLMultiplier(4)
+	s.config.Get().AdaptiveTTLMaxSeconds = 3600
+	assert.Len(t, s.GetRoutesToScaleDown(), 1)
+}
+
+func Test_setIsRunning(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.ServerlessTTLSeconds = 60
+	cfg.AdaptiveTTL = true
+	cfg.AdaptiveTTLMaxSeconds = 200
+
+	route, _ := model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0"}, true, nil, nil)
+	now := time.Now()
+
+	// first wake up - nothing to compare with
+	setIsRunning(cfg, route, false, now)
+	assert.Equal(t, now, route.GetScaledDownAt())
+	setIsRunning(cfg, route, false, now.Add(time.Minute)) // no transition
+	assert.Equal(t, now, route.GetScaledDownAt())
+
+	testCases := []struct {
+		asleep     time.Duration
+		multiplier int
+		ttl        int
+	}{
+		{10 * time.Second, 2, 120}, // woken up right after being scaled down
+		{90 * time.Second, 2, 120}, // still before the end of the extended TTL - capped by the max
+		{200 * time.Second, 1, 60}, // slept long enough
+		{200 * time.Second, 1, 60}, // no lower than the base TTL
+		{30 * time.Second, 2, 120},
+	}
+
+	for _, tc := range testCases {
+		setIsRunning(cfg, route, false, now)
+		setIsRunning(cfg, route, true, now.Add(tc.asleep))
+
+		assert.Equal(t, now.Add(tc.asleep), route.GetWokeUpAt())
+		assert.Equal(t, tc.multiplier, route.GetTTLMultiplier(), tc.asleep)
+		assert.Equal(t, tc.ttl, getTTLSeconds(cfg, route), tc.asleep)
+
+		now = now.Add(time.Hour)
+	}
+}
+
+func TestMemoryMap_UpdatePinLease(t *testing.T) {
+	s := NewMemoryMap(config.NewProvider(config.NewDefaultConfig()))
+	s.config.Get().ServerlessTTLSeconds = 60
+	s.config.Get().MinUptimeSeconds = 0
+
+	r0, _ := model.NewRoute("0", "svc0", "", "deploy0", "ns0", []string{"example.0"}, true, nil, nil)
+	r1, _ := model.NewRoute("1", "svc1", "", "deploy1", "ns1", []string{"example.1"}, true, nil, nil)
+	r1.SetPinnedUntil(time.Now().Add(time.Hour))
+	for _, r := range []*model.Route{r0, r1} {
+		createRoute(s, r)
+		r.SetLastUsed(time.Now().Add(-2 * time.Minute))
+	}
+
+	assert.Error(t, s.UpdatePinLease("unknown", time.Now()))
+
+	// r1 is pinned with the annotation
+	routes := s.GetRoutesToScaleDown()
+	assert.Len(t, routes, 1)
+	assert.Contains(t, routes, "0")
+
+	assert.NoError(t, s.UpdatePinLease("0", time.Now().Add(time.Hour)))
+	assert.Len(t, s.GetRoutesToScaleDown(), 0)
+
+	// the lease expired
+	assert.NoError(t, s.UpdatePinLease("0", time.Now().Add(-time.Second)))
+	assert.Len(t, s.GetRoutesToScaleDown(), 1)
+}
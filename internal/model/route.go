@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"kube-proxless/internal/utils"
+	"strings"
 	"time"
 )
 
@@ -15,13 +16,28 @@ type Route struct {
 	id                      string
 	service                 string
 	port                    string
-	deployment              string
+	ports                   map[string]string // target port indexed by service port name and number
+	domainsPorts            map[string]string // target port of the domains not using the default port
+	deployments             []string          // woken up together - see `proxless/wake-all-deployments`
 	namespace               string
 	domains                 []string
 	lastUsed                time.Time
 	ttlSeconds              *int
 	readinessTimeoutSeconds *int
 	isRunning               bool
+	replicas                map[string]deploymentReplicas // indexed by deployment
+	endpoints               []string                      // addresses of the ready endpoints of the service
+	minUptimeSeconds        *int
+	wokeUpAt                time.Time // last time the deployment went from scaled down to running
+	scaledDownAt            time.Time // last time the deployment went from running to scaled down
+	ttlMultiplier           int       // adaptive TTL - see `ADAPTIVE_TTL`
+	pinnedUntil             time.Time // `proxless/pinned-until` - overridden on each update of the route
+	pinLeaseUntil           time.Time // pinned through the admin api
+}
+
+type deploymentReplicas struct {
+	replicas          int
+	availableReplicas int
 }
 
 func NewRoute(
@@ -38,7 +54,7 @@ func NewRoute(
 		id:                      id,
 		service:                 svc,
 		port:                    useDefaultPortIfEmpty(port),
-		deployment:              deploy,
+		deployments:             []string{deploy},
 		namespace:               ns,
 		domains:                 domains,
 		lastUsed:                time.Now(),
@@ -84,11 +100,23 @@ func (r *Route) SetPort(p string) error {
 	return nil
 }
 
+func (r *Route) SetPorts(ports map[string]string) {
+	r.ports = ports
+}
+
+func (r *Route) SetDomainsPorts(domainsPorts map[string]string) {
+	r.domainsPorts = domainsPorts
+}
+
 func (r *Route) SetDeployment(d string) error {
-	if d == "" {
-		return errors.New(fmt.Sprintf("SetDeployment for route %v should not be empty", r))
+	return r.SetDeployments([]string{d})
+}
+
+func (r *Route) SetDeployments(d []string) error {
+	if utils.IsArrayEmpty(d) || utils.Contains(d, "") {
+		return errors.New(fmt.Sprintf("SetDeployments for route %v should not be empty", r))
 	}
-	r.deployment = d
+	r.deployments = d
 	return nil
 }
 
@@ -112,18 +140,79 @@ func (r *Route) SetIsRunning(isRunning bool) {
 	r.isRunning = isRunning
 }
 
+// the map is copied - the copies of the route returned by the memory must not change
+func (r *Route) SetReplicas(deployment string, replicas, availableReplicas int) {
+	m := make(map[string]deploymentReplicas, len(r.replicas)+1)
+	for k, v := range r.replicas {
+		m[k] = v
+	}
+	m[deployment] = deploymentReplicas{replicas: replicas, availableReplicas: availableReplicas}
+
+	r.replicas = m
+}
+
+func (r *Route) SetEndpoints(endpoints []string) {
+	r.endpoints = endpoints
+}
+
+func (r *Route) SetMinUptimeSeconds(t *int) {
+	r.minUptimeSeconds = t
+}
+
+func (r *Route) SetWokeUpAt(t time.Time) {
+	r.wokeUpAt = t
+}
+
+func (r *Route) SetScaledDownAt(t time.Time) {
+	r.scaledDownAt = t
+}
+
+func (r *Route) SetTTLMultiplier(m int) {
+	r.ttlMultiplier = m
+}
+
+func (r *Route) SetPinnedUntil(t time.Time) {
+	r.pinnedUntil = t
+}
+
+func (r *Route) SetPinLeaseUntil(t time.Time) {
+	r.pinLeaseUntil = t
+}
+
 func (r *Route) GetDomains() []string {
 	return r.domains
 }
 
+// the deployments of the route comma separated - same format as `proxless/deployment`
 func (r *Route) GetDeployment() string {
-	return r.deployment
+	return strings.Join(r.deployments, ",")
+}
+
+func (r *Route) GetDeployments() []string {
+	return r.deployments
 }
 
 func (r *Route) GetPort() string {
 	return r.port
 }
 
+func (r *Route) GetPorts() map[string]string {
+	return r.ports
+}
+
+func (r *Route) GetDomainsPorts() map[string]string {
+	return r.domainsPorts
+}
+
+// return the port the domain must be forwarded to - the default port if the domain does not have a specific one
+func (r *Route) GetPortByDomain(domain string) string {
+	if port, ok := r.domainsPorts[domain]; ok {
+		return port
+	}
+
+	return r.port
+}
+
 func (r *Route) GetNamespace() string {
 	return r.namespace
 }
@@ -151,3 +240,76 @@ func (r *Route) GetReadinessTimeoutSeconds() *int {
 func (r *Route) GetIsRunning() bool {
 	return r.isRunning
 }
+
+func (r *Route) GetReplicas() int {
+	replicas := 0
+	for _, d := range r.deployments {
+		replicas += r.replicas[d].replicas
+	}
+	return replicas
+}
+
+func (r *Route) GetAvailableReplicas() int {
+	availableReplicas := 0
+	for _, d := range r.deployments {
+		availableReplicas += r.replicas[d].availableReplicas
+	}
+	return availableReplicas
+}
+
+// the deployments of the route are woken up together - the route is not running if one of them is scaled down
+// the deployments with unknown replicas are ignored
+func (r *Route) IsEveryDeploymentRunning() bool {
+	for _, d := range r.deployments {
+		if dr, ok := r.replicas[d]; ok && dr.replicas == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Route) GetEndpoints() []string {
+	return r.endpoints
+}
+
+func (r *Route) GetMinUptimeSeconds() *int {
+	return r.minUptimeSeconds
+}
+
+func (r *Route) GetWokeUpAt() time.Time {
+	return r.wokeUpAt
+}
+
+func (r *Route) GetScaledDownAt() time.Time {
+	return r.scaledDownAt
+}
+
+// 1 if the TTL has never been extended
+func (r *Route) GetTTLMultiplier() int {
+	if r.ttlMultiplier < 1 {
+		return 1
+	}
+
+	return r.ttlMultiplier
+}
+
+func (r *Route) GetPinnedUntil() time.Time {
+	return r.pinnedUntil
+}
+
+func (r *Route) GetPinLeaseUntil() time.Time {
+	return r.pinLeaseUntil
+}
+
+// the latest of the annotation and the lease
+func (r *Route) GetPinExpiration() time.Time {
+	if r.pinLeaseUntil.After(r.pinnedUntil) {
+		return r.pinLeaseUntil
+	}
+
+	return r.pinnedUntil
+}
+
+func (r *Route) IsPinned(now time.Time) bool {
+	return now.Before(r.GetPinExpiration())
+}
@@ -10,12 +10,12 @@ import (
 
 func TestNewRoute(t *testing.T) {
 	route := &Route{
-		id:         uuid.New().String(),
-		service:    "helloworld",
-		port:       "8080",
-		deployment: "helloworld",
-		namespace:  "helloworld",
-		domains:    []string{"helloworld.io"},
+		id:          uuid.New().String(),
+		service:     "helloworld",
+		port:        "8080",
+		deployments: []string{"helloworld"},
+		namespace:   "helloworld",
+		domains:     []string{"helloworld.io"},
 	}
 
 	routeWithDefaultPort := *route
@@ -35,7 +35,7 @@ func TestNewRoute(t *testing.T) {
 			route.id,
 			route.service,
 			route.port,
-			route.deployment,
+			route.GetDeployment(),
 			route.namespace,
 			route.domains,
 			route,
@@ -45,7 +45,7 @@ func TestNewRoute(t *testing.T) {
 			route.id,
 			"",
 			route.port,
-			route.deployment,
+			route.GetDeployment(),
 			route.namespace,
 			route.domains,
 			route,
@@ -65,7 +65,7 @@ func TestNewRoute(t *testing.T) {
 			route.id,
 			route.service,
 			route.port,
-			route.deployment,
+			route.GetDeployment(),
 			"",
 			route.domains,
 			route,
@@ -75,7 +75,7 @@ func TestNewRoute(t *testing.T) {
 			route.id,
 			route.service,
 			route.port,
-			route.deployment,
+			route.GetDeployment(),
 			route.namespace,
 			nil,
 			route,
@@ -85,7 +85,7 @@ func TestNewRoute(t *testing.T) {
 			route.id,
 			route.service,
 			route.port,
-			route.deployment,
+			route.GetDeployment(),
 			route.namespace,
 			[]string{},
 			route,
@@ -95,7 +95,7 @@ func TestNewRoute(t *testing.T) {
 			route.id,
 			route.service,
 			"",
-			route.deployment,
+			route.GetDeployment(),
 			route.namespace,
 			route.domains,
 			&routeWithDefaultPort,
@@ -148,12 +148,16 @@ func TestRoute_SetLastUsed(t *testing.T) {
 
 func TestRoute_GetDeployment(t *testing.T) {
 	route := Route{}
-	route.deployment = "example"
+	route.deployments = []string{"example"}
 	got := route.GetDeployment()
 
-	if got != route.deployment {
-		t.Errorf("GetDeployment() = %s, want %s", got, route.deployment)
+	if got != "example" {
+		t.Errorf("GetDeployment() = %s, want %s", got, "example")
 	}
+
+	route.deployments = []string{"example-a", "example-b"}
+	assert.Equal(t, "example-a,example-b", route.GetDeployment())
+	assert.Equal(t, []string{"example-a", "example-b"}, route.GetDeployments())
 }
 
 func TestRoute_GetDomains(t *testing.T) {
@@ -230,11 +234,33 @@ func TestRoute_SetDeployment(t *testing.T) {
 		errGot := route.SetDeployment(tc.param)
 
 		if tc.errWanted != (errGot != nil) {
-			t.Errorf("SetDeployment(%s) = %v; errWanted = %t", route.deployment, errGot, tc.errWanted)
+			t.Errorf("SetDeployment(%s) = %v; errWanted = %t", route.GetDeployment(), errGot, tc.errWanted)
 		}
 
-		if errGot == nil && route.deployment != tc.param {
-			t.Errorf("SetDeployment(%s) != want %s", route.deployment, tc.param)
+		if errGot == nil && route.GetDeployment() != tc.param {
+			t.Errorf("SetDeployment(%s) != want %s", route.GetDeployment(), tc.param)
+		}
+	}
+}
+
+func TestRoute_SetDeployments(t *testing.T) {
+	testCases := []struct {
+		param     []string
+		errWanted bool
+	}{
+		{[]string{"example-a", "example-b"}, false},
+		{[]string{"example-a", ""}, true},
+		{[]string{}, true},
+		{nil, true},
+	}
+
+	for _, tc := range testCases {
+		route := Route{}
+		errGot := route.SetDeployments(tc.param)
+
+		assert.Equal(t, tc.errWanted, errGot != nil, tc.param)
+		if errGot == nil {
+			assert.Equal(t, tc.param, route.GetDeployments())
 		}
 	}
 }
@@ -363,3 +389,79 @@ func TestRoute_SetReadinessTimeoutSeconds(t *testing.T) {
 
 	assert.Equal(t, 60, *route.readinessTimeoutSeconds)
 }
+
+func TestRoute_SetReplicas(t *testing.T) {
+	route := Route{deployments: []string{"deploy-a"}}
+	route.SetReplicas("deploy-a", 3, 2)
+
+	assert.Equal(t, 3, route.GetReplicas())
+	assert.Equal(t, 2, route.GetAvailableReplicas())
+	assert.True(t, route.IsEveryDeploymentRunning())
+
+	// the replicas of all the deployments are summed
+	route.deployments = []string{"deploy-a", "deploy-b"}
+	route.SetReplicas("deploy-b", 1, 0)
+	assert.Equal(t, 4, route.GetReplicas())
+	assert.Equal(t, 2, route.GetAvailableReplicas())
+
+	// the replicas of a deployment removed from the route are ignored
+	route.SetReplicas("deploy-c", 5, 5)
+	assert.Equal(t, 4, route.GetReplicas())
+
+	// a copy of the route must not change
+	routeCopy := route
+	route.SetReplicas("deploy-b", 0, 0)
+	assert.False(t, route.IsEveryDeploymentRunning())
+	assert.True(t, routeCopy.IsEveryDeploymentRunning())
+}
+
+func TestRoute_GetPortByDomain(t *testing.T) {
+	route := Route{}
+	route.port = "8080"
+	route.SetPorts(map[string]string{"http": "8080", "80": "8080", "admin": "8001", "8000": "8001"})
+	route.SetDomainsPorts(map[string]string{"admin.example.io": "8001"})
+
+	assert.Equal(t, "8080", route.GetPortByDomain("example.io"))
+	assert.Equal(t, "8001", route.GetPortByDomain("admin.example.io"))
+	assert.Len(t, route.GetPorts(), 4)
+}
+
+func TestRoute_SetEndpoints(t *testing.T) {
+	route := Route{}
+	assert.Empty(t, route.GetEndpoints())
+
+	route.SetEndpoints([]string{"10.0.0.1"})
+	assert.Equal(t, []string{"10.0.0.1"}, route.GetEndpoints())
+}
+
+func TestRoute_SetMinUptimeSeconds(t *testing.T) {
+	route := Route{}
+	assert.Nil(t, route.GetMinUptimeSeconds())
+
+	minUptimeSeconds := 300
+	route.SetMinUptimeSeconds(&minUptimeSeconds)
+	assert.Equal(t, 300, *route.GetMinUptimeSeconds())
+}
+
+func TestRoute_SetTTLMultiplier(t *testing.T) {
+	route := Route{}
+	assert.Equal(t, 1, route.GetTTLMultiplier())
+
+	route.SetTTLMultiplier(4)
+	assert.Equal(t, 4, route.GetTTLMultiplier())
+}
+
+func TestRoute_IsPinned(t *testing.T) {
+	now := time.Now()
+	route := Route{}
+	assert.False(t, route.IsPinned(now))
+
+	route.SetPinnedUntil(now.Add(time.Hour))
+	assert.True(t, route.IsPinned(now))
+	assert.Equal(t, now.Add(time.Hour), route.GetPinExpiration())
+
+	// the latest of the annotation and the lease wins
+	route.SetPinLeaseUntil(now.Add(2 * time.Hour))
+	assert.Equal(t, now.Add(2*time.Hour), route.GetPinExpiration())
+	assert.False(t, route.IsPinned(now.Add(3*time.Hour)))
+}
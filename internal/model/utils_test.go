@@ -4,11 +4,11 @@ import "testing"
 
 func Test_isEqual(t *testing.T) {
 	route := &Route{
-		service:    "helloworld",
-		port:       "8080",
-		deployment: "helloworld",
-		namespace:  "helloworld",
-		domains:    []string{"helloworld.io", "helloworld.com"},
+		service:     "helloworld",
+		port:        "8080",
+		deployments: []string{"helloworld"},
+		namespace:   "helloworld",
+		domains:     []string{"helloworld.io", "helloworld.com"},
 	}
 
 	routeWithDiffSvc := *route
@@ -18,7 +18,7 @@ func Test_isEqual(t *testing.T) {
 	routeWithDiffPort.port = "diff"
 
 	routeWithDiffDeploy := *route
-	routeWithDiffDeploy.deployment = "diff"
+	routeWithDiffDeploy.deployments = []string{"diff"}
 
 	routeWithDiffNs := *route
 	routeWithDiffNs.namespace = "diff"
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"kube-proxless/internal/pubsub"
+	"sync"
+	"time"
+)
+
+// The broker is shared by all the pubsub clients of the same process
+// Messages are delivered synchronously to every subscriber, including the publisher itself (same as Redis)
+type Broker struct {
+	lastUsedSubscribers  map[string]map[*MemoryPubSub]func(id string, lastUsed time.Time) error
+	isRunningSubscribers map[string]map[*MemoryPubSub]func(id string, isRunning bool) error
+	pinLeaseSubscribers  map[string]map[*MemoryPubSub]func(id string, until time.Time) error
+	lock                 sync.RWMutex
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		lastUsedSubscribers:  make(map[string]map[*MemoryPubSub]func(id string, lastUsed time.Time) error),
+		isRunningSubscribers: make(map[string]map[*MemoryPubSub]func(id string, isRunning bool) error),
+		pinLeaseSubscribers:  make(map[string]map[*MemoryPubSub]func(id string, until time.Time) error),
+		lock:                 sync.RWMutex{},
+	}
+}
+
+type MemoryPubSub struct {
+	broker *Broker
+}
+
+func NewMemoryPubSub(broker *Broker) pubsub.Interface {
+	return &MemoryPubSub{
+		broker: broker,
+	}
+}
+
+func (p *MemoryPubSub) PublishLastUsed(idRoute string, lastUsed time.Time) {
+	p.broker.lock.RLock()
+	var subscribers []func(id string, lastUsed time.Time) error
+	for _, updateLastUsed := range p.broker.lastUsedSubscribers[idRoute] {
+		subscribers = append(subscribers, updateLastUsed)
+	}
+	p.broker.lock.RUnlock()
+
+	// the subscribers are called without the lock so they can publish in turn
+	for _, updateLastUsed := range subscribers {
+		_ = updateLastUsed(idRoute, lastUsed)
+	}
+}
+
+func (p *MemoryPubSub) SubscribeLastUsed(idRoute string, updateLastUsed func(id string, lastUsed time.Time) error) {
+	p.broker.lock.Lock()
+	defer p.broker.lock.Unlock()
+
+	if _, ok := p.broker.lastUsedSubscribers[idRoute]; !ok {
+		p.broker.lastUsedSubscribers[idRoute] = make(map[*MemoryPubSub]func(id string, lastUsed time.Time) error)
+	}
+
+	// same as redis - subscribing twice to the same channel is a no-op
+	if _, ok := p.broker.lastUsedSubscribers[idRoute][p]; !ok {
+		p.broker.lastUsedSubscribers[idRoute][p] = updateLastUsed
+	}
+}
+
+func (p *MemoryPubSub) PublishIsRunning(idRoute string, isRunning bool) {
+	p.broker.lock.RLock()
+	var subscribers []func(id string, isRunning bool) error
+	for _, updateIsRunning := range p.broker.isRunningSubscribers[idRoute] {
+		subscribers = append(subscribers, updateIsRunning)
+	}
+	p.broker.lock.RUnlock()
+
+	for _, updateIsRunning := range subscribers {
+		_ = updateIsRunning(idRoute, isRunning)
+	}
+}
+
+func (p *MemoryPubSub) SubscribeIsRunning(idRoute string, updateIsRunning func(id string, isRunning bool) error) {
+	p.broker.lock.Lock()
+	defer p.broker.lock.Unlock()
+
+	if _, ok := p.broker.isRunningSubscribers[idRoute]; !ok {
+		p.broker.isRunningSubscribers[idRoute] = make(map[*MemoryPubSub]func(id string, isRunning bool) error)
+	}
+
+	if _, ok := p.broker.isRunningSubscribers[idRoute][p]; !ok {
+		p.broker.isRunningSubscribers[idRoute][p] = updateIsRunning
+	}
+}
+
+func (p *MemoryPubSub) PublishPinLease(idRoute string, until time.Time) {
+	p.broker.lock.RLock()
+	var subscribers []func(id string, until time.Time) error
+	for _, updatePinLease := range p.broker.pinLeaseSubscribers[idRoute] {
+		subscribers = append(subscribers, updatePinLease)
+	}
+	p.broker.lock.RUnlock()
+
+	for _, updatePinLease := range subscribers {
+		_ = updatePinLease(idRoute, until)
+	}
+}
+
+func (p *MemoryPubSub) SubscribePinLease(idRoute string, updatePinLease func(id string, until time.Time) error) {
+	p.broker.lock.Lock()
+	defer p.broker.lock.Unlock()
+
+	if _, ok := p.broker.pinLeaseSubscribers[idRoute]; !ok {
+		p.broker.pinLeaseSubscribers[idRoute] = make(map[*MemoryPubSub]func(id string, until time.Time) error)
+	}
+
+	if _, ok := p.broker.pinLeaseSubscribers[idRoute][p]; !ok {
+		p.broker.pinLeaseSubscribers[idRoute][p] = updatePinLease
+	}
+}
+
+func (p *MemoryPubSub) Unsubscribe(idRoute string) {
+	p.broker.lock.Lock()
+	defer p.broker.lock.Unlock()
+
+	delete(p.broker.lastUsedSubscribers[idRoute], p)
+	if len(p.broker.lastUsedSubscribers[idRoute]) == 0 {
+		delete(p.broker.lastUsedSubscribers, idRoute)
+	}
+
+	delete(p.broker.isRunningSubscribers[idRoute], p)
+	if len(p.broker.isRunningSubscribers[idRoute]) == 0 {
+		delete(p.broker.isRunningSubscribers, idRoute)
+	}
+
+	delete(p.broker.pinLeaseSubscribers[idRoute], p)
+	if len(p.broker.pinLeaseSubscribers[idRoute]) == 0 {
+		delete(p.broker.pinLeaseSubscribers, idRoute)
+	}
+}
+
+// the broker is in the same process - always reachable
+func (p *MemoryPubSub) Ping() error {
+	return nil
+}
+
+// the other clients of the broker are not impacted
+func (p *MemoryPubSub) Close() {
+	p.broker.lock.RLock()
+	var ids []string
+	for id := range p.broker.lastUsedSubscribers {
+		ids = append(ids, id)
+	}
+	for id := range p.broker.isRunningSubscribers {
+		ids = append(ids, id)
+	}
+	for id := range p.broker.pinLeaseSubscribers {
+		ids = append(ids, id)
+	}
+	p.broker.lock.RUnlock()
+
+	for _, id := range ids {
+		p.Unsubscribe(id)
+	}
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryPubSub_LastUsed(t *testing.T) {
+	broker := NewBroker()
+	ps1 := NewMemoryPubSub(broker)
+	ps2 := NewMemoryPubSub(broker)
+
+	received := map[string]time.Time{}
+	ps1.SubscribeLastUsed("id", func(id string, lastUsed time.Time) error {
+		received["ps1"] = lastUsed
+		return nil
+	})
+	ps2.SubscribeLastUsed("id", func(id string, lastUsed time.Time) error {
+		received["ps2"] = lastUsed
+		return nil
+	})
+	// subscribing twice must not override the first subscription
+	ps2.SubscribeLastUsed("id", func(id string, lastUsed time.Time) error {
+		received["ps2-twice"] = lastUsed
+		return nil
+	})
+
+	// no subscriber for this route
+	ps1.PublishLastUsed("other-id", time.Now())
+	assert.Len(t, received, 0)
+
+	now := time.Now()
+	ps1.PublishLastUsed("id", now)
+	assert.Equal(t, map[string]time.Time{"ps1": now, "ps2": now}, received)
+
+	ps2.Unsubscribe("id")
+	later := now.Add(time.Second)
+	ps1.PublishLastUsed("id", later)
+	assert.Equal(t, map[string]time.Time{"ps1": later, "ps2": now}, received)
+}
+
+func TestMemoryPubSub_IsRunning(t *testing.T) {
+	broker := NewBroker()
+	ps1 := NewMemoryPubSub(broker)
+	ps2 := NewMemoryPubSub(broker)
+
+	received := map[string]bool{}
+	ps1.SubscribeIsRunning("id", func(id string, isRunning bool) error {
+		received["ps1"] = isRunning
+		return nil
+	})
+	ps2.SubscribeIsRunning("id", func(id string, isRunning bool) error {
+		received["ps2"] = isRunning
+		return nil
+	})
+
+	ps2.PublishIsRunning("id", true)
+	assert.Equal(t, map[string]bool{"ps1": true, "ps2": true}, received)
+
+	ps1.Unsubscribe("id")
+	ps2.PublishIsRunning("id", false)
+	assert.Equal(t, map[string]bool{"ps1": true, "ps2": false}, received)
+
+	ps2.Unsubscribe("id")
+	assert.Len(t, broker.isRunningSubscribers, 0)
+	assert.Len(t, broker.lastUsedSubscribers, 0)
+}
+
+func TestMemoryPubSub_PinLease(t *testing.T) {
+	broker := NewBroker()
+	ps1 := NewMemoryPubSub(broker)
+	ps2 := NewMemoryPubSub(broker)
+
+	received := map[string]time.Time{}
+	ps1.SubscribePinLease("id", func(id string, until time.Time) error {
+		received["ps1"] = until
+		return nil
+	})
+	ps2.SubscribePinLease("id", func(id string, until time.Time) error {
+		received["ps2"] = until
+		return nil
+	})
+
+	until := time.Now().Add(time.Hour)
+	ps1.PublishPinLease("id", until)
+	assert.Equal(t, map[string]time.Time{"ps1": until, "ps2": until}, received)
+
+	// unpin
+	ps2.PublishPinLease("id", time.Time{})
+	assert.Equal(t, map[string]time.Time{"ps1": {}, "ps2": {}}, received)
+
+	ps1.Unsubscribe("id")
+	ps2.Unsubscribe("id")
+	assert.Len(t, broker.pinLeaseSubscribers, 0)
+}
+
+func TestMemoryPubSub_Close(t *testing.T) {
+	broker := NewBroker()
+	ps1 := NewMemoryPubSub(broker)
+	ps2 := NewMemoryPubSub(broker)
+
+	received := map[string]bool{}
+	for _, id := range []string{"id1", "id2"} {
+		ps1.SubscribeLastUsed(id, func(id string, lastUsed time.Time) error { return nil })
+		ps1.SubscribeIsRunning(id, func(id string, isRunning bool) error {
+			received["ps1"] = isRunning
+			return nil
+		})
+		ps1.SubscribePinLease(id, func(id string, until time.Time) error { return nil })
+	}
+	ps2.SubscribeIsRunning("id1", func(id string, isRunning bool) error {
+		received["ps2"] = isRunning
+		return nil
+	})
+
+	ps1.Close()
+	assert.Len(t, broker.lastUsedSubscribers, 0)
+	assert.Len(t, broker.pinLeaseSubscribers, 0)
+
+	// the other clients still receive the messages
+	ps2.PublishIsRunning("id1", true)
+	assert.Equal(t, map[string]bool{"ps2": true}, received)
+}
@@ -9,5 +9,12 @@ type Interface interface {
 	SubscribeLastUsed(idRoute string, updateLastUsed func(id string, lastUsed time.Time) error)
 	PublishIsRunning(idRoute string, isRunning bool)
 	SubscribeIsRunning(idRoute string, updateIsRunning func(id string, isRunning bool) error)
+	// a zero time unpins the route
+	PublishPinLease(idRoute string, until time.Time)
+	SubscribePinLease(idRoute string, updatePinLease func(id string, until time.Time) error)
 	Unsubscribe(idRoute string)
+	// nil if the broker is reachable - used by the readiness of proxless
+	Ping() error
+	// close all the subscriptions - called once when proxless shuts down
+	Close()
 }
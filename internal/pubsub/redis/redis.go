@@ -6,12 +6,15 @@ import (
 	"kube-proxless/internal/logger"
 	"kube-proxless/internal/pubsub"
 	"strconv"
+	"sync"
 	"time"
 )
 
 type RedisClient struct {
 	client *redis.Client
 	m      map[string]*redis.PubSub
+	// the engines subscribe concurrently
+	lock sync.Mutex
 }
 
 func NewRedisPubSub(redisURL string) pubsub.Interface {
@@ -33,6 +36,7 @@ func NewRedisPubSub(redisURL string) pubsub.Interface {
 	return &RedisClient{
 		client: client,
 		m:      make(map[string]*redis.PubSub),
+		lock:   sync.Mutex{},
 	}
 }
 
@@ -46,12 +50,10 @@ func (r *RedisClient) PublishLastUsed(idRoute string, lastUsed time.Time) {
 
 func (r *RedisClient) SubscribeLastUsed(idRoute string, updateLastUsed func(id string, lastUsed time.Time) error) {
 	idChannel := genLastUsedChannelName(idRoute)
-	if _, ok := r.m[idChannel]; !ok {
-		r.m[idChannel] = r.client.Subscribe(idChannel)
-
+	if ps, ok := subscribe(r, idChannel); ok {
 		go func() {
 			for {
-				msg, ok := <-r.m[idChannel].Channel()
+				msg, ok := <-ps.Channel()
 
 				if !ok {
 					logger.Debugf("Could not receive message from channel %s - might have been closed", idChannel)
@@ -84,12 +86,10 @@ func (r *RedisClient) PublishIsRunning(idRoute string, isRunning bool) {
 
 func (r *RedisClient) SubscribeIsRunning(idRoute string, updateIsRunning func(id string, isRunning bool) error) {
 	idChannel := genIsRunningChannelName(idRoute)
-	if _, ok := r.m[idChannel]; !ok {
-		r.m[idChannel] = r.client.Subscribe(idChannel)
-
+	if ps, ok := subscribe(r, idChannel); ok {
 		go func() {
 			for {
-				msg, ok := <-r.m[idChannel].Channel()
+				msg, ok := <-ps.Channel()
 
 				if !ok {
 					logger.Debugf("Could not receive message from channel %s - might have been closed", idChannel)
@@ -112,6 +112,54 @@ func (r *RedisClient) SubscribeIsRunning(idRoute string, updateIsRunning func(id
 	}
 }
 
+// the lease is sent as a unix timestamp - 0 to unpin the route
+func (r *RedisClient) PublishPinLease(idRoute string, until time.Time) {
+	idChannel := genPinLeaseChannelName(idRoute)
+
+	var timestampInSec int64
+	if !until.IsZero() {
+		timestampInSec = until.Unix()
+	}
+
+	err := r.client.Publish(idChannel, timestampInSec).Err()
+	if err != nil {
+		logger.Errorf(err, "Cannot PUBLISH message to Redis channel %s", idChannel)
+	}
+}
+
+func (r *RedisClient) SubscribePinLease(idRoute string, updatePinLease func(id string, until time.Time) error) {
+	idChannel := genPinLeaseChannelName(idRoute)
+	if ps, ok := subscribe(r, idChannel); ok {
+		go func() {
+			for {
+				msg, ok := <-ps.Channel()
+
+				if !ok {
+					logger.Debugf("Could not receive message from channel %s - might have been closed", idChannel)
+					return
+				}
+
+				timestampInSec, err := strconv.ParseInt(msg.Payload, 10, 64)
+
+				if err != nil {
+					logger.Errorf(err, "Could not unmarshal payload %s from channel %s", msg.Payload, idChannel)
+					continue
+				}
+
+				until := time.Time{}
+				if timestampInSec > 0 {
+					until = time.Unix(timestampInSec, 0)
+				}
+
+				err = updatePinLease(idRoute, until)
+				if err != nil {
+					logger.Errorf(err, "Could not update the pin lease in route id %s", idChannel)
+				}
+			}
+		}()
+	}
+}
+
 func (r *RedisClient) Unsubscribe(idRoute string) {
 	// TODO commenting this because of the `nil` exception due to the redis library
 	// can repro remotely but not locally - To Be Fixed later
@@ -131,6 +179,40 @@ func (r *RedisClient) Unsubscribe(idRoute string) {
 	// }
 }
 
+// return the new subscription - false if the channel is already subscribed
+func subscribe(r *RedisClient, idChannel string) (*redis.PubSub, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.m[idChannel]; ok {
+		return nil, false
+	}
+
+	ps := r.client.Subscribe(idChannel)
+	r.m[idChannel] = ps
+
+	return ps, true
+}
+
+func (r *RedisClient) Ping() error {
+	return r.client.Ping().Err()
+}
+
+func (r *RedisClient) Close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for idChannel, ps := range r.m {
+		if err := ps.Close(); err != nil {
+			logger.Errorf(err, "Could not close the subscription to channel %s", idChannel)
+		}
+	}
+
+	if err := r.client.Close(); err != nil {
+		logger.Errorf(err, "Could not close the Redis client")
+	}
+}
+
 func genLastUsedChannelName(id string) string {
 	return fmt.Sprintf("last_used_%s", id)
 }
@@ -138,3 +220,7 @@ func genLastUsedChannelName(id string) string {
 func genIsRunningChannelName(id string) string {
 	return fmt.Sprintf("is_running_%s", id)
 }
+
+func genPinLeaseChannelName(id string) string {
+	return fmt.Sprintf("pin_lease_%s", id)
+}
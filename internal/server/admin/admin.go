@@ -0,0 +1,184 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kube-proxless/internal/config"
+	"kube-proxless/internal/controller"
+	"kube-proxless/internal/logger"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	dryRunPath  = "/dry-run"
+	savingsPath = "/savings"
+	metricsPath = "/metrics"
+	pinsPath    = "/pins"
+	livePath    = "/healthz"
+	readyPath   = "/readyz"
+)
+
+// the admin endpoints must not be exposed through the ingress - they are served on a different port than the proxy
+type adminServer struct {
+	controller controller.Interface
+	host       string
+	config     *config.Provider
+	// the readiness checks that the proxy is listening
+	proxyHost string
+}
+
+func NewAdminServer(controller controller.Interface, cfg *config.Provider) *adminServer {
+	return &adminServer{
+		controller: controller,
+		host:       fmt.Sprintf(":%s", cfg.Get().AdminPort),
+		config:     cfg,
+		proxyHost:  net.JoinHostPort("localhost", cfg.Get().Port),
+	}
+}
+
+func (s *adminServer) Run() {
+	logger.Infof("Starting Admin Server on %s...", s.host)
+	logger.Fatalf(http.ListenAndServe(s.host, s.newServeMux()), "Error starting the admin server")
+}
+
+func (s *adminServer) newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc(dryRunPath, s.dryRunHandler)
+	mux.HandleFunc(savingsPath, s.savingsHandler)
+	mux.HandleFunc(metricsPath, s.metricsHandler)
+	mux.HandleFunc(pinsPath, s.pinsHandler)
+	mux.HandleFunc(livePath, s.liveHandler)
+	mux.HandleFunc(readyPath, s.readyHandler)
+
+	return mux
+}
+
+// projected scale downs/ups and idle hours of each route
+func (s *adminServer) dryRunHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Get().DryRun {
+		http.Error(w, "dry-run mode is disabled", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, s.controller.GetDryRunReport())
+}
+
+// cpu/memory hours saved per route and per namespace
+func (s *adminServer) savingsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.controller.GetSavingsReport())
+}
+
+func (s *adminServer) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	writeMetrics(w, genSavingsMetrics(s.controller.GetSavingsReport()))
+	writeMetrics(w, genPreWarmMetrics(s.controller.GetPreWarmReport()))
+
+	if s.config.Get().DryRun {
+		writeMetrics(w, genDryRunMetrics(s.controller.GetDryRunActions()))
+	}
+}
+
+// the process is alive and the DownScaler and the services engine are not wedged
+func (s *adminServer) liveHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, s.controller.CheckLiveness())
+}
+
+// the routes are in memory, pubsub is connected and the proxy is listening
+// fail first when proxless shuts down so the replica is removed from the endpoints before the proxy stops
+func (s *adminServer) readyHandler(w http.ResponseWriter, r *http.Request) {
+	errs := s.controller.CheckReadiness()
+
+	if conn, err := net.DialTimeout("tcp", s.proxyHost, time.Second); err != nil {
+		errs = append(errs, errors.New(fmt.Sprintf("proxy not listening on %s", s.proxyHost)))
+	} else {
+		_ = conn.Close()
+	}
+
+	writeHealth(w, errs)
+}
+
+// GET - list the pinned routes
+// POST ?route=<id>&duration=<duration> or ?route=<id>&until=<RFC3339> - pin a route
+// DELETE ?route=<id> - remove the pin lease of a route
+func (s *adminServer) pinsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.controller.GetPinnedRoutes())
+	case http.MethodPost:
+		until, err := parsePinExpiration(r.URL.Query().Get("duration"), r.URL.Query().Get("until"), time.Now())
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.pinRoute(w, r.URL.Query().Get("route"), until)
+	case http.MethodDelete:
+		s.pinRoute(w, r.URL.Query().Get("route"), time.Time{})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *adminServer) pinRoute(w http.ResponseWriter, id string, until time.Time) {
+	if id == "" {
+		http.Error(w, "route must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.controller.PinRoute(id, until); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"id": id, "pinnedUntil": until})
+}
+
+// the pin always expires - either after `duration` or at `until`
+func parsePinExpiration(duration, until string, now time.Time) (time.Time, error) {
+	if duration != "" {
+		d, err := time.ParseDuration(duration)
+
+		if err != nil || d <= 0 {
+			return time.Time{}, errors.New(fmt.Sprintf("duration must be a positive duration (e.g. 2h) - got %q", duration))
+		}
+
+		return now.Add(d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, until)
+
+	if err != nil || !t.After(now) {
+		return time.Time{}, errors.New(fmt.Sprintf("until must be a RFC3339 date in the future - got %q", until))
+	}
+
+	return t, nil
+}
+
+// one failed check per line
+func writeHealth(w http.ResponseWriter, errs []error) {
+	if len(errs) == 0 {
+		_, _ = w.Write([]byte("ok"))
+		return
+	}
+
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	http.Error(w, strings.Join(msgs, "\n"), http.StatusServiceUnavailable)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Errorf(err, "Could not encode the response")
+	}
+}
@@ -0,0 +1,144 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"kube-proxless/internal/cluster/fake"
+	"kube-proxless/internal/config"
+	"kube-proxless/internal/controller"
+	"kube-proxless/internal/memory"
+	"kube-proxless/internal/model"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewAdminServer(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	server := NewAdminServer(nil, cfg)
+
+	assert.Equal(t, fmt.Sprintf(":%s", cfg.Get().AdminPort), server.host)
+}
+
+func Test_dryRunHandler(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	server := NewAdminServer(controller.NewController(memory.NewMemoryMap(cfg), fake.NewCluster(), nil, nil, cfg), cfg)
+
+	testCases := []struct {
+		dryRun bool
+		status int
+	}{
+		{false, http.StatusNotFound},
+		{true, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		cfg.Get().DryRun = tc.dryRun
+
+		w := httptest.NewRecorder()
+		server.newServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, dryRunPath, nil))
+
+		assert.Equal(t, tc.status, w.Code)
+
+		if tc.status == http.StatusOK {
+			var reports []controller.DryRunRouteReport
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &reports))
+			assert.Empty(t, reports)
+		}
+	}
+}
+
+func Test_savingsHandler(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	server := NewAdminServer(controller.NewController(memory.NewMemoryMap(cfg), fake.NewCluster(), nil, nil, cfg), cfg)
+
+	w := httptest.NewRecorder()
+	server.newServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, savingsPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report controller.SavingsReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Empty(t, report.Routes)
+
+	w = httptest.NewRecorder()
+	server.newServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "# TYPE proxless_route_saved_cpu_hours_total counter")
+}
+
+func Test_healthHandlers(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	c := controller.NewController(memory.NewMemoryMap(cfg), fake.NewCluster(), nil, nil, cfg)
+	server := NewAdminServer(c, cfg)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	assertStatus := func(path string, want int) {
+		w := httptest.NewRecorder()
+		server.newServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, w.Code, path)
+	}
+
+	// the routes are not in memory and the proxy is not listening
+	server.proxyHost = "127.0.0.1:1"
+	assertStatus(readyPath, http.StatusServiceUnavailable)
+
+	// the fake services engine syncs and returns immediately
+	c.RunServicesEngine(context.TODO())
+	assertStatus(readyPath, http.StatusServiceUnavailable)
+	assertStatus(livePath, http.StatusServiceUnavailable)
+
+	server.proxyHost = ln.Addr().String()
+	assertStatus(readyPath, http.StatusOK)
+
+	// the readiness fails first and the engines are expected to stop
+	c.Drain()
+	assertStatus(readyPath, http.StatusServiceUnavailable)
+	assertStatus(livePath, http.StatusOK)
+}
+
+func Test_pinsHandler(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	mem := memory.NewMemoryMap(cfg)
+	route, _ := model.NewRoute("mock-id", "mock-svc", "", "mock-deploy", "mock-ns", []string{"mock.io"}, true, nil, nil)
+	assert.NoError(t, mem.UpsertMemoryMap(route))
+	server := NewAdminServer(controller.NewController(mem, fake.NewCluster(), nil, nil, cfg), cfg)
+
+	testCases := []struct {
+		method string
+		query  string
+		status int
+		pinned bool
+	}{
+		{http.MethodPost, "?route=mock-id", http.StatusBadRequest, false},
+		{http.MethodPost, "?route=mock-id&duration=abc", http.StatusBadRequest, false},
+		{http.MethodPost, "?route=mock-id&duration=-1h", http.StatusBadRequest, false},
+		{http.MethodPost, "?route=mock-id&until=2020-06-01T18:00:00Z", http.StatusBadRequest, false},
+		{http.MethodPost, "?duration=2h", http.StatusBadRequest, false},
+		{http.MethodPost, "?route=unknown&duration=2h", http.StatusNotFound, false},
+		{http.MethodPost, "?route=mock-id&duration=2h", http.StatusOK, true},
+		{http.MethodDelete, "?route=mock-id", http.StatusOK, false},
+		{http.MethodPost, "?route=mock-id&until=" + time.Now().Add(time.Hour).Format(time.RFC3339), http.StatusOK, true},
+		{http.MethodPut, "?route=mock-id", http.StatusMethodNotAllowed, true},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		server.newServeMux().ServeHTTP(w, httptest.NewRequest(tc.method, pinsPath+tc.query, nil))
+		assert.Equal(t, tc.status, w.Code, tc.method+tc.query)
+
+		w = httptest.NewRecorder()
+		server.newServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, pinsPath, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reports []controller.PinnedRouteReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &reports))
+		assert.Equal(t, tc.pinned, len(reports) == 1, tc.method+tc.query)
+	}
+}
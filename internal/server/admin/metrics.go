@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"fmt"
+	"io"
+	"kube-proxless/internal/cluster"
+	"kube-proxless/internal/controller"
+	"strings"
+)
+
+// the metrics are written in the prometheus text format by hand - there are too few of them to pull the client library
+type metric struct {
+	name, help, metricType string
+	samples                []sample
+}
+
+type sample struct {
+	labels [][2]string // ordered
+	value  float64
+}
+
+func writeMetrics(w io.Writer, metrics []metric) {
+	for _, m := range metrics {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.metricType)
+
+		for _, s := range m.samples {
+			_, _ = fmt.Fprintf(w, "%s%s %g\n", m.name, formatLabels(s.labels), s.value)
+		}
+	}
+}
+
+func formatLabels(labels [][2]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	var pairs []string
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", l[0], escapeLabelValue(l[1])))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ","))
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func genSavingsMetrics(report controller.SavingsReport) []metric {
+	routeLabels := func(r controller.SavingsRouteReport) [][2]string {
+		return [][2]string{{"route", r.Id}, {"namespace", r.Namespace}, {"deployment", r.Deployment}}
+	}
+	namespaceLabels := func(ns controller.SavingsNamespaceReport) [][2]string {
+		return [][2]string{{"namespace", ns.Namespace}}
+	}
+
+	routeMetrics := []metric{
+		{name: "proxless_route_asleep_seconds_total", help: "Time the deployment of the route has been scaled down"},
+		{name: "proxless_route_saved_cpu_hours_total", help: "CPU hours saved while the deployment of the route was scaled down"},
+		{name: "proxless_route_saved_memory_gb_hours_total", help: "Memory GB hours saved while the deployment of the route was scaled down"},
+		{name: "proxless_route_saved_cost_total", help: "Cost saved while the deployment of the route was scaled down"},
+	}
+	namespaceMetrics := []metric{
+		{name: "proxless_namespace_asleep_seconds_total", help: "Time the deployments of the namespace have been scaled down"},
+		{name: "proxless_namespace_saved_cpu_hours_total", help: "CPU hours saved while the deployments of the namespace were scaled down"},
+		{name: "proxless_namespace_saved_memory_gb_hours_total", help: "Memory GB hours saved while the deployments of the namespace were scaled down"},
+		{name: "proxless_namespace_saved_cost_total", help: "Cost saved while the deployments of the namespace were scaled down"},
+	}
+
+	for _, r := range report.Routes {
+		for i, v := range []float64{r.AsleepSeconds, r.CPUHours, r.MemoryGBHours, r.Cost} {
+			routeMetrics[i].samples = append(routeMetrics[i].samples, sample{routeLabels(r), v})
+		}
+	}
+
+	for _, ns := range report.Namespaces {
+		for i, v := range []float64{ns.AsleepSeconds, ns.CPUHours, ns.MemoryGBHours, ns.Cost} {
+			namespaceMetrics[i].samples = append(namespaceMetrics[i].samples, sample{namespaceLabels(ns), v})
+		}
+	}
+
+	metrics := append(routeMetrics, namespaceMetrics...)
+	for i := range metrics {
+		metrics[i].metricType = "counter"
+	}
+
+	return metrics
+}
+
+func genPreWarmMetrics(reports []controller.PreWarmRouteReport) []metric {
+	metrics := []metric{
+		{name: "proxless_prewarm_total", help: "Number of times the route has been pre-warmed"},
+		{name: "proxless_prewarm_hits_total", help: "Number of pre-warms followed by a request in the predicted hour"},
+		{name: "proxless_prewarm_misses_total", help: "Number of pre-warms not followed by a request in the predicted hour"},
+	}
+
+	for _, r := range reports {
+		labels := [][2]string{{"route", r.Id}, {"namespace", r.Namespace}, {"deployment", r.Deployment}}
+
+		for i, v := range []int{r.PreWarms, r.Hits, r.Misses} {
+			metrics[i].samples = append(metrics[i].samples, sample{labels, float64(v)})
+		}
+	}
+
+	for i := range metrics {
+		metrics[i].metricType = "counter"
+	}
+
+	return metrics
+}
+
+func genDryRunMetrics(reports []cluster.DryRunActionReport) []metric {
+	m := metric{
+		name:       "proxless_dry_run_actions_total",
+		help:       "Number of writes on the cluster skipped in dry-run mode",
+		metricType: "counter",
+	}
+
+	for _, r := range reports {
+		m.samples = append(m.samples, sample{[][2]string{{"resource", r.Resource}, {"action", r.Action}}, float64(r.Count)})
+	}
+
+	return []metric{m}
+}
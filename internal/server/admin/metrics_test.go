@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"kube-proxless/internal/cluster"
+	"kube-proxless/internal/controller"
+	"testing"
+)
+
+func Test_formatLabels(t *testing.T) {
+	testCases := []struct {
+		labels [][2]string
+		want   string
+	}{
+		{nil, ""},
+		{[][2]string{{"route", "svc.ns"}}, `{route="svc.ns"}`},
+		{[][2]string{{"a", "1"}, {"b", `"quoted" \ new` + "\n"}}, `{a="1",b="\"quoted\" \\ new\n"}`},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, formatLabels(tc.labels))
+	}
+}
+
+func Test_genSavingsMetrics(t *testing.T) {
+	var b bytes.Buffer
+
+	writeMetrics(&b, genSavingsMetrics(controller.SavingsReport{
+		Routes: []controller.SavingsRouteReport{
+			{Id: "svc.ns", Deployment: "deploy", Namespace: "ns", AsleepSeconds: 3600, CPUHours: 0.5, MemoryGBHours: 2, Cost: 1.25},
+		},
+		Namespaces: []controller.SavingsNamespaceReport{
+			{Namespace: "ns", AsleepSeconds: 3600, CPUHours: 0.5, MemoryGBHours: 2, Cost: 1.25},
+		},
+	}))
+
+	metrics := b.String()
+	assert.Contains(t, metrics, "# TYPE proxless_route_asleep_seconds_total counter\n")
+	assert.Contains(t, metrics, `proxless_route_asleep_seconds_total{route="svc.ns",namespace="ns",deployment="deploy"} 3600`+"\n")
+	assert.Contains(t, metrics, `proxless_route_saved_cpu_hours_total{route="svc.ns",namespace="ns",deployment="deploy"} 0.5`+"\n")
+	assert.Contains(t, metrics, `proxless_namespace_saved_memory_gb_hours_total{namespace="ns"} 2`+"\n")
+	assert.Contains(t, metrics, `proxless_namespace_saved_cost_total{namespace="ns"} 1.25`+"\n")
+}
+
+func Test_genPreWarmMetrics(t *testing.T) {
+	var b bytes.Buffer
+
+	writeMetrics(&b, genPreWarmMetrics([]controller.PreWarmRouteReport{
+		{Id: "svc.ns", Deployment: "deploy", Namespace: "ns", PreWarms: 3, Hits: 2, Misses: 1},
+	}))
+
+	metrics := b.String()
+	assert.Contains(t, metrics, "# TYPE proxless_prewarm_total counter\n")
+	assert.Contains(t, metrics, `proxless_prewarm_total{route="svc.ns",namespace="ns",deployment="deploy"} 3`+"\n")
+	assert.Contains(t, metrics, `proxless_prewarm_hits_total{route="svc.ns",namespace="ns",deployment="deploy"} 2`+"\n")
+	assert.Contains(t, metrics, `proxless_prewarm_misses_total{route="svc.ns",namespace="ns",deployment="deploy"} 1`+"\n")
+}
+
+func Test_genDryRunMetrics(t *testing.T) {
+	var b bytes.Buffer
+
+	writeMetrics(&b, genDryRunMetrics([]cluster.DryRunActionReport{
+		{Resource: "deployments", Action: "patch", Count: 3},
+		{Resource: "services", Action: "create", Count: 1},
+	}))
+
+	metrics := b.String()
+	assert.Contains(t, metrics, "# TYPE proxless_dry_run_actions_total counter\n")
+	assert.Contains(t, metrics, `proxless_dry_run_actions_total{resource="deployments",action="patch"} 3`+"\n")
+	assert.Contains(t, metrics, `proxless_dry_run_actions_total{resource="services",action="create"} 1`+"\n")
+}
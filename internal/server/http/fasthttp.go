@@ -3,31 +3,46 @@ package http
 import (
 	"github.com/valyala/fasthttp"
 	"kube-proxless/internal/logger"
+	"time"
 )
 
 type fastHTTP struct {
 	client fasthttp.Client
+	server *fasthttp.Server
 }
 
 type fastHTTPInterface interface {
 	listenAndServe(host string, requestHandler func(ctx *fasthttp.RequestCtx))
+	shutdown() error
 	do(req *fasthttp.Request, resp *fasthttp.Response) error
 }
 
-func newFastHTTP(maxConsPerHost int) *fastHTTP {
+// the idle keep-alive connections are closed after `idleTimeoutSeconds`
+// `shutdown` waits for every connection to be closed, including the idle ones
+func newFastHTTP(maxConsPerHost, idleTimeoutSeconds int) *fastHTTP {
 	return &fastHTTP{
 		client: fasthttp.Client{
 			MaxConnsPerHost: maxConsPerHost,
 		},
+		server: &fasthttp.Server{
+			Name:        "proxless-http",
+			IdleTimeout: time.Duration(idleTimeoutSeconds) * time.Second,
+		},
 	}
 }
 
-func (*fastHTTP) listenAndServe(host string, requestHandler func(ctx *fasthttp.RequestCtx)) {
-	server := fasthttp.Server{
-		Name:    "proxless-http",
-		Handler: requestHandler,
+// return once `shutdown` is called
+func (f *fastHTTP) listenAndServe(host string, requestHandler func(ctx *fasthttp.RequestCtx)) {
+	f.server.Handler = requestHandler
+
+	if err := f.server.ListenAndServe(host); err != nil {
+		logger.Fatalf(err, "Error starting the server")
 	}
-	logger.Fatalf(server.ListenAndServe(host), "Error starting the server")
+}
+
+// close the listener and wait for the open connections to be closed
+func (f *fastHTTP) shutdown() error {
+	return f.server.Shutdown()
 }
 
 func (f *fastHTTP) do(req *fasthttp.Request, resp *fasthttp.Response) error {
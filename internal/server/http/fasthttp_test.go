@@ -1,16 +1,75 @@
 package http
 
 import (
+	"bufio"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"net"
+	"net/http"
 	"testing"
+	"time"
 )
 
 func Test_newFastHTTP(t *testing.T) {
 	want := 1
 
-	fastHTTP := newFastHTTP(want)
+	fastHTTP := newFastHTTP(want, 1)
 
 	if fastHTTP.client.MaxConnsPerHost != want {
 		t.Errorf("newFastHTTP(%d); maxConnsPerHost == %d; want %d",
 			want, fastHTTP.client.MaxConnsPerHost, want)
 	}
+
+	if fastHTTP.server.IdleTimeout != time.Second {
+		t.Errorf("newFastHTTP(%d, 1); idleTimeout == %s; want %s", want, fastHTTP.server.IdleTimeout, time.Second)
+	}
+}
+
+// an idle keep-alive connection must not block the shutdown until the end of the grace period
+func Test_fastHTTP_shutdown_IdleConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := ln.Addr().String()
+	_ = ln.Close()
+
+	fastHTTP := newFastHTTP(1, 1)
+	go fastHTTP.listenAndServe(host, func(ctx *fasthttp.RequestCtx) {})
+
+	var conn net.Conn
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("tcp", host); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the connection is kept alive after the response
+	if _, err := fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\n\r\n", host); err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fastHTTP.shutdown()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("shutdown() == %s; want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("shutdown() blocked by the idle connection")
+	}
 }
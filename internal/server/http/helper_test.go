@@ -3,18 +3,61 @@ package http
 import (
 	"errors"
 	"github.com/valyala/fasthttp"
+	"kube-proxless/internal/cluster"
+	"sync"
 )
 
 type mockFastHTTP struct {
-	doMustFail bool
+	doMustFail   bool
+	failingHosts []string      // the requests to these hosts fail - e.g. a scaled down service
+	host         string        // host of the last request
+	shutdownCh   chan struct{} // shutdown blocks until it is closed - nil to return immediately
+	lock         sync.Mutex
 }
 
 func (*mockFastHTTP) listenAndServe(host string, requestHandler func(ctx *fasthttp.RequestCtx)) {}
 
+func (m *mockFastHTTP) shutdown() error {
+	if m.shutdownCh != nil {
+		<-m.shutdownCh
+	}
+
+	return nil
+}
+
 func (m *mockFastHTTP) do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.host = string(req.Host())
+
 	if m.doMustFail {
 		return errors.New("do must fail")
 	}
 
+	for _, host := range m.failingHosts {
+		if host == m.host {
+			return errors.New("host must fail")
+		}
+	}
+
 	return nil
 }
+
+func (m *mockFastHTTP) helper_getHost() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.host
+}
+
+// the endpoint slices informer updates the endpoints of the route while the deployment is scaled up
+type fakeScaleUpCluster struct {
+	cluster.Interface
+	onScaleUp func()
+}
+
+func (c *fakeScaleUpCluster) ScaleUpDeployment(deploymentName, serviceName, namespace string, timeout int) error {
+	c.onScaleUp()
+	return c.Interface.ScaleUpDeployment(deploymentName, serviceName, namespace, timeout)
+}
@@ -1,27 +1,39 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"github.com/valyala/fasthttp"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"kube-proxless/internal/config"
 	"kube-proxless/internal/controller"
 	"kube-proxless/internal/logger"
+	"kube-proxless/internal/model"
 	"kube-proxless/internal/server/utils"
+	"net"
 	"time"
 )
 
 type httpServer struct {
-	controller controller.Interface
-	client     fastHTTPInterface
-	host       string
+	controller   controller.Interface
+	client       fastHTTPInterface
+	host         string
+	loadBalancer loadBalancer // nil if the requests are forwarded to the services
+	config       *config.Provider
 }
 
-func NewHTTPServer(controller controller.Interface) *httpServer {
+func NewHTTPServer(controller controller.Interface, cfg *config.Provider) *httpServer {
+	var lb loadBalancer
+	if cfg.Get().ProxyToEndpoints {
+		lb = newLoadBalancer(cfg.Get().LoadBalancing)
+	}
+
 	return &httpServer{
-		controller: controller,
-		client:     newFastHTTP(config.MaxConsPerHost),
-		host:       fmt.Sprintf(":%s", config.Port),
+		controller:   controller,
+		client:       newFastHTTP(cfg.Get().MaxConsPerHost, cfg.Get().IdleTimeoutSeconds),
+		host:         fmt.Sprintf(":%s", cfg.Get().Port),
+		loadBalancer: lb,
+		config:       cfg,
 	}
 }
 
@@ -31,13 +43,37 @@ func (s *httpServer) Run() {
 	s.client.listenAndServe(s.host, s.requestHandler)
 }
 
+// stop accepting connections and wait for the in-flight requests, including the ones waiting for a scale up
+// the requests still in flight after the grace period are dropped
+func (s *httpServer) Shutdown(gracePeriodSeconds int) error {
+	logger.Infof("Draining the in-flight requests...")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.client.shutdown()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Duration(gracePeriodSeconds) * time.Second):
+		return errors.New(fmt.Sprintf("in-flight requests not drained after %d seconds", gracePeriodSeconds))
+	}
+}
+
 func (s *httpServer) requestHandler(ctx *fasthttp.RequestCtx) {
+	// the keep-alive connections are closed so the clients reconnect to another replica
+	// deferred - the headers of the response are overridden by the ones of the backend
+	if s.controller.IsDraining() {
+		defer ctx.SetConnectionClose()
+	}
+
 	req := fasthttp.AcquireRequest()
 	res := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseRequest(req)
 	defer fasthttp.ReleaseResponse(res)
 
-	req.Header = ctx.Request.Header
+	ctx.Request.Header.CopyTo(&req.Header)
 	req.SetBody(ctx.Request.Body())
 
 	logger.Debugf("Received request %s", ctx.Host())
@@ -47,12 +83,24 @@ func (s *httpServer) requestHandler(ctx *fasthttp.RequestCtx) {
 	if err != nil {
 		forward404Error(ctx, err, host)
 	} else { // the route exists so we should have a deployment attached to the service
-		service := route.GetService()
 		namespace := route.GetNamespace()
-		port := route.GetPort()
+		port := route.GetPortByDomain(host)
+
+		release := s.setOrigin(req, route, port)
+		defer func() { release() }()
+
+		// the endpoints of the route are only known once the deployment is ready - e.g. after a scale up
+		repickOrigin := func() {
+			if s.loadBalancer == nil {
+				return
+			}
 
-		origin := fmt.Sprintf("%s.%s:%s", service, namespace, port)
-		req.SetHost(origin)
+			// the route is a copy - the endpoints updated by the endpoint slices informer are in memory
+			if r, err := s.controller.GetRouteByDomainFromMemory(host); err == nil {
+				release()
+				release = s.setOrigin(req, r, port)
+			}
+		}
 
 		// update before because it's gonna take some time to scale up the deployment
 		_ = s.controller.UpdateLastUsedInMemory(route.GetId())
@@ -60,7 +108,7 @@ func (s *httpServer) requestHandler(ctx *fasthttp.RequestCtx) {
 		err := s.client.do(req, res)
 
 		if err != nil { // First try, the deployment might be scaled down
-			readinessTimeoutSeconds := config.DeploymentReadinessTimeoutSeconds
+			readinessTimeoutSeconds := s.config.Get().DeploymentReadinessTimeoutSeconds
 			if route.GetReadinessTimeoutSeconds() != nil {
 				readinessTimeoutSeconds = *route.GetReadinessTimeoutSeconds()
 			}
@@ -68,7 +116,7 @@ func (s *httpServer) requestHandler(ctx *fasthttp.RequestCtx) {
 			if route.GetIsRunning() {
 				logger.Debugf("Error forwarding the request %s - deployment is already running, we just wait", ctx.Host())
 
-				err := waitForResponse(s, req, res, readinessTimeoutSeconds)
+				err := waitForResponse(s, req, res, readinessTimeoutSeconds, repickOrigin)
 
 				if err != nil {
 					forwardError(ctx, err)
@@ -79,11 +127,13 @@ func (s *httpServer) requestHandler(ctx *fasthttp.RequestCtx) {
 				// at the same time - otherwise that would overload the kubernetes api
 				_ = s.controller.UpdateIsRunningInMemory(route.GetId())
 
-				err := s.controller.ScaleUpDeployment(route.GetDeployment(), namespace, readinessTimeoutSeconds)
+				err := s.controller.ScaleUpDeployment(
+					route.GetDeployment(), route.GetService(), namespace, readinessTimeoutSeconds)
 
 				if err != nil {
 					forwardError(ctx, err)
 				} else { // Second try with the deployment scaled up
+					repickOrigin()
 					err := s.client.do(req, res)
 
 					if err != nil {
@@ -103,15 +153,34 @@ func (s *httpServer) requestHandler(ctx *fasthttp.RequestCtx) {
 	}
 }
 
+// pick the origin of the request - a ready endpoint of the service if any, the service otherwise
+// return the function releasing the endpoint picked
+func (s *httpServer) setOrigin(req *fasthttp.Request, route *model.Route, port string) func() {
+	// if the service has no ready endpoint (e.g. scaled down), we still go through the service
+	if endpoints := route.GetEndpoints(); s.loadBalancer != nil && len(endpoints) > 0 {
+		endpoint := s.loadBalancer.pick(route.GetId(), endpoints)
+		req.SetHost(net.JoinHostPort(endpoint, port))
+
+		return func() { s.loadBalancer.release(endpoint) }
+	}
+
+	req.SetHost(fmt.Sprintf("%s.%s:%s", route.GetService(), route.GetNamespace(), port))
+
+	return func() {}
+}
+
 // we call the backend regularly to see if the app is responding or not
+// the origin is picked again after each failure - the endpoint might be gone
 // TODO implement some sort of queuing system to make sure the request are being sent in order
-func waitForResponse(s *httpServer, req *fasthttp.Request, res *fasthttp.Response, readinessTimeoutSeconds int) error {
+func waitForResponse(
+	s *httpServer, req *fasthttp.Request, res *fasthttp.Response, readinessTimeoutSeconds int, repickOrigin func()) error {
 	err := wait.PollImmediate(1*time.Second, time.Duration(readinessTimeoutSeconds)*time.Second, func() (bool, error) {
 		err := s.client.do(req, res)
 
 		if err == nil {
 			return true, nil
 		} else {
+			repickOrigin()
 			return false, nil
 		}
 	})
@@ -127,8 +196,8 @@ func forward404Error(ctx *fasthttp.RequestCtx, err error, host string) {
 
 func forwardRequest(ctx *fasthttp.RequestCtx, res *fasthttp.Response) {
 	logger.Debugf("Request %s forwarded", ctx.Host())
+	res.Header.CopyTo(&ctx.Response.Header)
 	ctx.Response.SetBodyString(string(res.Body()))
-	ctx.Response.Header = res.Header
 	ctx.Response.SetStatusCode(res.StatusCode())
 }
 
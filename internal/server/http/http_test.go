@@ -9,29 +9,73 @@ import (
 	"kube-proxless/internal/controller"
 	"kube-proxless/internal/memory"
 	"kube-proxless/internal/model"
+	memorypubsub "kube-proxless/internal/pubsub/memory"
 	"testing"
+	"time"
 )
 
 func TestNewHTTPServer(t *testing.T) {
-	HTTPServer := NewHTTPServer(nil)
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	HTTPServer := NewHTTPServer(nil, cfg)
 
-	if HTTPServer.host != fmt.Sprintf(":%s", config.Port) {
-		t.Errorf("NewHTTPServer(nil); host == %s but must be %s",
-			HTTPServer.host, fmt.Sprintf(":%s", config.Port))
+	if HTTPServer.host != fmt.Sprintf(":%s", cfg.Get().Port) {
+		t.Errorf("NewHTTPServer(nil, cfg); host == %s but must be %s",
+			HTTPServer.host, fmt.Sprintf(":%s", cfg.Get().Port))
 	}
 }
 
 func TestHTTPServer_Run(t *testing.T) {
-	server := NewHTTPServer(controller.NewController(memory.NewMemoryMap(), fake.NewCluster(), nil))
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	server := NewHTTPServer(controller.NewController(memory.NewMemoryMap(cfg), fake.NewCluster(), nil, nil, cfg), cfg)
 	server.client = &mockFastHTTP{}
 
 	// make sure it does not panic
 	server.Run()
 }
 
+func TestHTTPServer_Shutdown(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	server := NewHTTPServer(nil, cfg)
+
+	server.client = &mockFastHTTP{}
+	assert.NoError(t, server.Shutdown(1))
+
+	// the connections are never idle
+	server.client = &mockFastHTTP{shutdownCh: make(chan struct{})}
+	assert.Error(t, server.Shutdown(1))
+}
+
+// the in-flight requests are still served but the keep-alive connections are closed
+func TestHTTPServer_requestHandler_Draining(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	mem := memory.NewMemoryMap(cfg)
+	c := controller.NewController(mem, fake.NewCluster(), nil, nil, cfg)
+	server := NewHTTPServer(c, cfg)
+	server.client = &mockFastHTTP{}
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "", "mock-deploy", "mock-ns", []string{"mock.io"}, true, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, mem.UpsertMemoryMap(route))
+
+	for _, draining := range []bool{false, true} {
+		if draining {
+			c.Drain()
+		}
+
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetHost("mock.io")
+		server.requestHandler(ctx)
+
+		assert.Equal(t, 200, ctx.Response.StatusCode())
+		assert.Equal(t, draining, ctx.Response.ConnectionClose())
+	}
+}
+
 func TestHTTPServer_requestHandler(t *testing.T) {
-	mem := memory.NewMemoryMap()
-	server := NewHTTPServer(controller.NewController(mem, fake.NewCluster(), nil))
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	mem := memory.NewMemoryMap(cfg)
+	server := NewHTTPServer(controller.NewController(mem, fake.NewCluster(), nil, nil, cfg), cfg)
 
 	testCases := []struct {
 		host       string
@@ -56,7 +100,8 @@ func TestHTTPServer_requestHandler(t *testing.T) {
 		req := fasthttp.AcquireRequest()
 		req.SetHost(tc.host)
 
-		ctx := &fasthttp.RequestCtx{Request: *req}
+		ctx := &fasthttp.RequestCtx{}
+		req.CopyTo(&ctx.Request)
 
 		server.client = &mockFastHTTP{doMustFail: tc.doMustFail}
 		server.requestHandler(ctx)
@@ -67,6 +112,130 @@ func TestHTTPServer_requestHandler(t *testing.T) {
 	}
 }
 
+// a request served by one replica must update the lastUsed of the other replicas
+func TestHTTPServer_requestHandler_PubSub(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	broker := memorypubsub.NewBroker()
+	mem1 := memory.NewMemoryMap(cfg)
+	mem2 := memory.NewMemoryMap(cfg)
+	server := NewHTTPServer(controller.NewController(mem1, fake.NewCluster(), memorypubsub.NewMemoryPubSub(broker), nil, cfg), cfg)
+	server.client = &mockFastHTTP{}
+	ps2 := memorypubsub.NewMemoryPubSub(broker)
+
+	for _, mem := range []*memory.MemoryMap{mem1, mem2} {
+		route, err := model.NewRoute(
+			"mock-id", "mock-svc", "", "mock-deploy", "mock-ns", []string{"mock.io"}, true, nil, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, mem.UpsertMemoryMap(route))
+	}
+	ps2.SubscribeLastUsed("mock-id", mem2.UpdateLastUsed)
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetHost("mock.io")
+	ctx := &fasthttp.RequestCtx{}
+	req.CopyTo(&ctx.Request)
+
+	server.requestHandler(ctx)
+	assert.Equal(t, 200, ctx.Response.StatusCode())
+
+	route1, _ := mem1.GetRouteByDomain("mock.io")
+	route2, _ := mem2.GetRouteByDomain("mock.io")
+	assert.Equal(t, route1.GetLastUsed(), route2.GetLastUsed())
+}
+
+func TestHTTPServer_requestHandler_ProxyToEndpoints(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	mem := memory.NewMemoryMap(cfg)
+	server := NewHTTPServer(controller.NewController(mem, fake.NewCluster(), nil, nil, cfg), cfg)
+	client := &mockFastHTTP{}
+	server.client = client
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "8080", "mock-deploy", "mock-ns", []string{"mock.io"}, true, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, mem.UpsertMemoryMap(route))
+
+	testCases := []struct {
+		loadBalancer loadBalancer
+		endpoints    []string
+		want         string
+	}{
+		{nil, []string{"10.0.0.1"}, "mock-svc.mock-ns:8080"},
+		{newRoundRobin(), []string{}, "mock-svc.mock-ns:8080"},
+		{newRoundRobin(), []string{"10.0.0.1"}, "10.0.0.1:8080"},
+		{newLeastConnections(), []string{"fd00::1"}, "[fd00::1]:8080"},
+	}
+
+	for _, tc := range testCases {
+		server.loadBalancer = tc.loadBalancer
+		assert.NoError(t, mem.UpdateEndpoints("mock-id", tc.endpoints))
+
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetHost("mock.io")
+		server.requestHandler(ctx)
+
+		assert.Equal(t, 200, ctx.Response.StatusCode())
+		assert.Equal(t, tc.want, client.helper_getHost())
+	}
+}
+
+// the deployment is asleep - the service has no endpoint until it is scaled up
+func TestHTTPServer_requestHandler_ProxyToEndpoints_ColdStart(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	mem := memory.NewMemoryMap(cfg)
+	cl := &fakeScaleUpCluster{Interface: fake.NewCluster(), onScaleUp: func() {
+		_ = mem.UpdateEndpoints("mock-id", []string{"10.0.0.1"})
+	}}
+	server := NewHTTPServer(controller.NewController(mem, cl, nil, nil, cfg), cfg)
+	server.loadBalancer = newRoundRobin()
+	client := &mockFastHTTP{failingHosts: []string{"mock-svc.mock-ns:8080"}}
+	server.client = client
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "8080", "mock-deploy", "mock-ns", []string{"mock.io"}, false, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, mem.UpsertMemoryMap(route))
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetHost("mock.io")
+	server.requestHandler(ctx)
+
+	// the second try goes to the endpoint of the deployment scaled up
+	assert.Equal(t, 200, ctx.Response.StatusCode())
+	assert.Equal(t, "10.0.0.1:8080", client.helper_getHost())
+}
+
+// the endpoint in memory is dead - the request must go to the new one as soon as it is known
+func TestHTTPServer_requestHandler_ProxyToEndpoints_StaleEndpoint(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	mem := memory.NewMemoryMap(cfg)
+	server := NewHTTPServer(controller.NewController(mem, fake.NewCluster(), nil, nil, cfg), cfg)
+	server.loadBalancer = newLeastConnections()
+	client := &mockFastHTTP{failingHosts: []string{"10.0.0.1:8080"}}
+	server.client = client
+
+	route, err := model.NewRoute(
+		"mock-id", "mock-svc", "8080", "mock-deploy", "mock-ns", []string{"mock.io"}, true, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, mem.UpsertMemoryMap(route))
+	assert.NoError(t, mem.UpdateEndpoints("mock-id", []string{"10.0.0.1"}))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = mem.UpdateEndpoints("mock-id", []string{"10.0.0.2"})
+	}()
+
+	now := time.Now()
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetHost("mock.io")
+	server.requestHandler(ctx)
+
+	assert.Equal(t, 200, ctx.Response.StatusCode())
+	assert.Equal(t, "10.0.0.2:8080", client.helper_getHost())
+	assert.True(t, time.Since(now) < 3*time.Second)
+}
+
 func TestHTTPServer_forward404Error(t *testing.T) {
 	ctx := &fasthttp.RequestCtx{
 		Response: fasthttp.Response{},
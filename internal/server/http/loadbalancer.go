@@ -0,0 +1,86 @@
+package http
+
+import (
+	"sync"
+)
+
+const (
+	loadBalancingRoundRobin       = "round-robin"
+	loadBalancingLeastConnections = "least-connections"
+)
+
+// pick the endpoint a request is forwarded to
+// `release` must be called once the request is done
+type loadBalancer interface {
+	pick(id string, endpoints []string) string
+	release(endpoint string)
+}
+
+func newLoadBalancer(loadBalancing string) loadBalancer {
+	if loadBalancing == loadBalancingLeastConnections {
+		return newLeastConnections()
+	}
+
+	return newRoundRobin()
+}
+
+type roundRobin struct {
+	counters map[string]int
+	lock     sync.Mutex
+}
+
+func newRoundRobin() *roundRobin {
+	return &roundRobin{
+		counters: make(map[string]int),
+		lock:     sync.Mutex{},
+	}
+}
+
+func (r *roundRobin) pick(id string, endpoints []string) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	i := r.counters[id] % len(endpoints)
+	r.counters[id] = i + 1
+
+	return endpoints[i]
+}
+
+func (*roundRobin) release(endpoint string) {}
+
+type leastConnections struct {
+	connections map[string]int
+	lock        sync.Mutex
+}
+
+func newLeastConnections() *leastConnections {
+	return &leastConnections{
+		connections: make(map[string]int),
+		lock:        sync.Mutex{},
+	}
+}
+
+func (l *leastConnections) pick(id string, endpoints []string) string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	endpoint := endpoints[0]
+	for _, e := range endpoints[1:] {
+		if l.connections[e] < l.connections[endpoint] {
+			endpoint = e
+		}
+	}
+	l.connections[endpoint]++
+
+	return endpoint
+}
+
+func (l *leastConnections) release(endpoint string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.connections[endpoint]--
+	if l.connections[endpoint] <= 0 {
+		delete(l.connections, endpoint)
+	}
+}
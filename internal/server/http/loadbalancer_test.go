@@ -0,0 +1,48 @@
+package http
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_newLoadBalancer(t *testing.T) {
+	assert.IsType(t, &roundRobin{}, newLoadBalancer(loadBalancingRoundRobin))
+	assert.IsType(t, &leastConnections{}, newLoadBalancer(loadBalancingLeastConnections))
+	assert.IsType(t, &roundRobin{}, newLoadBalancer("unknown"))
+}
+
+func TestRoundRobin_pick(t *testing.T) {
+	lb := newRoundRobin()
+	endpoints := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		endpoint := lb.pick("id", endpoints)
+		lb.release(endpoint)
+		got = append(got, endpoint)
+	}
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.1"}, got)
+
+	// each route has its own counter
+	assert.Equal(t, "10.0.0.1", lb.pick("other-id", endpoints))
+
+	// the endpoints changed in between
+	assert.Equal(t, "10.0.0.1", lb.pick("id", []string{"10.0.0.1"}))
+}
+
+func TestLeastConnections_pick(t *testing.T) {
+	lb := newLeastConnections()
+	endpoints := []string{"10.0.0.1", "10.0.0.2"}
+
+	first := lb.pick("id", endpoints)
+	second := lb.pick("id", endpoints)
+	assert.NotEqual(t, first, second)
+
+	// the first one is done - it must be picked again
+	lb.release(first)
+	assert.Equal(t, first, lb.pick("id", endpoints))
+
+	lb.release(first)
+	lb.release(second)
+	assert.Len(t, lb.connections, 0)
+}
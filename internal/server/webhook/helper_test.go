@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"kube-proxless/internal/cluster/fake"
+	"kube-proxless/internal/config"
+	"kube-proxless/internal/controller"
+	"kube-proxless/internal/memory"
+	"kube-proxless/internal/model"
+	"testing"
+)
+
+func helper_newController(t *testing.T) controller.Interface {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	mem := memory.NewMemoryMap(cfg)
+
+	route, err := model.NewRoute(
+		"mock-svc.mock-ns", "mock-svc", "", "mock-deploy", "mock-ns", []string{"mock.io"}, true, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, mem.UpsertMemoryMap(route))
+
+	return controller.NewController(mem, fake.NewCluster(), nil, nil, cfg)
+}
+
+func helper_newAdmissionRequest(t *testing.T, name string, annotations map[string]string) *admissionv1.AdmissionRequest {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "mock-ns", Annotations: annotations},
+	}
+	raw, err := json.Marshal(svc)
+	assert.NoError(t, err)
+
+	return &admissionv1.AdmissionRequest{
+		UID:       types.UID("mock-uid"),
+		Operation: admissionv1.Create,
+		Namespace: "mock-ns",
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
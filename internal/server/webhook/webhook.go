@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/config"
+	"kube-proxless/internal/controller"
+	"kube-proxless/internal/logger"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const validatePath = "/validate"
+
+type webhookServer struct {
+	controller controller.Interface
+	host       string
+	certFile   string
+	keyFile    string
+	// the domains are generated like the services engine does
+	namespaceScoped bool
+}
+
+func NewWebhookServer(controller controller.Interface, cfg *config.Provider) *webhookServer {
+	return &webhookServer{
+		controller:      controller,
+		host:            fmt.Sprintf(":%s", cfg.Get().WebhookPort),
+		certFile:        cfg.Get().WebhookCertFile,
+		keyFile:         cfg.Get().WebhookKeyFile,
+		namespaceScoped: cfg.Get().NamespaceScope != "",
+	}
+}
+
+func (s *webhookServer) Run() {
+	mux := http.NewServeMux()
+	mux.HandleFunc(validatePath, s.validateHandler)
+
+	logger.Infof("Starting Webhook Server on %s...", s.host)
+	logger.Fatalf(http.ListenAndServeTLS(s.host, s.certFile, s.keyFile, mux), "Error starting the webhook server")
+}
+
+func (s *webhookServer) validateHandler(w http.ResponseWriter, r *http.Request) {
+	review := admissionv1.AdmissionReview{}
+
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+		logger.Errorf(err, "Could not decode the admission review")
+		http.Error(w, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = validateAdmissionRequest(s.controller, review.Request, s.namespaceScoped)
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		logger.Errorf(err, "Could not encode the admission review")
+	}
+}
+
+// only the services with the proxless annotations are validated - the others are always allowed
+func validateAdmissionRequest(
+	controller controller.Interface, req *admissionv1.AdmissionRequest, namespaceScoped bool) *admissionv1.AdmissionResponse {
+	res := &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+
+	if req.Operation == admissionv1.Delete {
+		return res
+	}
+
+	svc := &corev1.Service{}
+	if err := json.Unmarshal(req.Object.Raw, svc); err != nil {
+		logger.Errorf(err, "Could not decode the object of the admission request")
+		return res
+	}
+
+	if svc.Namespace == "" { // not set on creation if the namespace comes from the request
+		svc.Namespace = req.Namespace
+	}
+
+	// nothing to validate if the annotations of the users did not change - e.g. `proxless/status` patched by proxless
+	// otherwise an invalid route would block proxless and every unrelated update of the service
+	if req.Operation == admissionv1.Update {
+		oldSvc := &corev1.Service{}
+		if err := json.Unmarshal(req.OldObject.Raw, oldSvc); err != nil {
+			logger.Errorf(err, "Could not decode the old object of the admission request")
+		} else if reflect.DeepEqual(getProxlessAnnotations(oldSvc), getProxlessAnnotations(svc)) {
+			return res
+		}
+	}
+
+	if errs := validateService(controller, svc, namespaceScoped); len(errs) > 0 {
+		var messages []string
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+
+		res.Allowed = false
+		res.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: strings.Join(messages, " - "),
+		}
+	}
+
+	return res
+}
+
+// the annotations set by the users - `proxless/status` is written by proxless
+func getProxlessAnnotations(svc *corev1.Service) map[string]string {
+	annotations := map[string]string{}
+
+	for k, v := range svc.Annotations {
+		if strings.HasPrefix(k, clusterutils.AnnotationPrefix) && k != clusterutils.AnnotationServiceStatus {
+			annotations[k] = v
+		}
+	}
+
+	return annotations
+}
+
+func validateService(controller controller.Interface, svc *corev1.Service, namespaceScoped bool) []error {
+	if !clusterutils.IsAnnotationsProxlessCompatible(svc.ObjectMeta) {
+		return nil
+	}
+
+	errs := clusterutils.ValidateServiceAnnotations(svc.Annotations)
+
+	// `proxless/deployment` can list multiple deployments - e.g. with `proxless/wake-all-deployments`
+	deployNames := clusterutils.SplitDeployments(svc.Annotations[clusterutils.AnnotationServiceDeployKey])
+	if len(deployNames) == 0 {
+		return errs
+	}
+
+	// the route is identified by the service targeted by `proxless/service` if any
+	serviceName := svc.Name
+	if name, ok := svc.Annotations[clusterutils.AnnotationServiceServiceName]; ok {
+		if name == "" {
+			errs = append(errs, errors.New(fmt.Sprintf("%s must not be empty", clusterutils.AnnotationServiceServiceName)))
+		}
+		serviceName = name
+	}
+
+	domainsAnnotation, _ := clusterutils.ParseDomainsPorts(svc.Annotations[clusterutils.AnnotationServiceDomainKey])
+	domains := clusterutils.GenDomains(domainsAnnotation, svc.Name, svc.Namespace, namespaceScoped)
+
+	return append(
+		errs,
+		controller.ValidateRoute(clusterutils.GenRouteId(serviceName, svc.Namespace), deployNames, svc.Namespace, domains)...)
+}
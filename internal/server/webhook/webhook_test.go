@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterutils "kube-proxless/internal/cluster/utils"
+	"kube-proxless/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewWebhookServer(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	server := NewWebhookServer(nil, cfg)
+
+	assert.Equal(t, fmt.Sprintf(":%s", cfg.Get().WebhookPort), server.host)
+}
+
+func Test_validateAdmissionRequest(t *testing.T) {
+	c := helper_newController(t)
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		allowed     bool
+	}{
+		{"random-svc", nil, true}, // not a proxless service
+		{"mock-svc", map[string]string{ // updating the existing route
+			clusterutils.AnnotationServiceDeployKey: "mock-deploy",
+			clusterutils.AnnotationServiceDomainKey: "mock.io",
+		}, true},
+		{"other-svc", map[string]string{ // domain owned by mock-svc
+			clusterutils.AnnotationServiceDeployKey: "mock-deploy",
+			clusterutils.AnnotationServiceDomainKey: "mock.io",
+		}, false},
+		{"mock-svc", map[string]string{ // deployment not found
+			clusterutils.AnnotationServiceDeployKey: "unknown-deploy",
+		}, false},
+		{"mock-svc", map[string]string{ // one of the deployments not found
+			clusterutils.AnnotationServiceDeployKey: "mock-deploy,unknown-deploy",
+		}, false},
+		{"mock-svc", map[string]string{
+			clusterutils.AnnotationServiceDeployKey:  "mock-deploy",
+			clusterutils.AnnotationServiceTTLSeconds: "abc",
+		}, false},
+		{"mock-svc", map[string]string{
+			clusterutils.AnnotationServiceDeployKey: "mock-deploy",
+			clusterutils.AnnotationServiceDomainKey: "not_a_domain",
+		}, false},
+	}
+
+	for _, tc := range testCases {
+		res := validateAdmissionRequest(c, helper_newAdmissionRequest(t, tc.name, tc.annotations), false)
+
+		assert.Equal(t, types.UID("mock-uid"), res.UID)
+		assert.Equal(t, tc.allowed, res.Allowed, tc.annotations)
+		if !tc.allowed {
+			assert.NotEmpty(t, res.Result.Message)
+		}
+	}
+
+	// always allow deletion
+	req := helper_newAdmissionRequest(t, "other-svc", map[string]string{
+		clusterutils.AnnotationServiceDeployKey: "unknown-deploy",
+	})
+	req.Operation = admissionv1.Delete
+	assert.True(t, validateAdmissionRequest(c, req, false).Allowed)
+}
+
+func Test_validateAdmissionRequest_Update(t *testing.T) {
+	c := helper_newController(t)
+
+	// invalid - domain owned by mock-svc
+	annotations := map[string]string{
+		clusterutils.AnnotationServiceDeployKey: "mock-deploy",
+		clusterutils.AnnotationServiceDomainKey: "mock.io",
+	}
+	oldSvc := helper_newAdmissionRequest(t, "other-svc", annotations).Object
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		allowed     bool
+	}{
+		{"proxless status patched", map[string]string{
+			clusterutils.AnnotationServiceDeployKey: "mock-deploy",
+			clusterutils.AnnotationServiceDomainKey: "mock.io",
+			clusterutils.AnnotationServiceStatus:    "running",
+		}, true},
+		{"other annotation changed", map[string]string{
+			clusterutils.AnnotationServiceDeployKey: "mock-deploy",
+			clusterutils.AnnotationServiceDomainKey: "mock.io",
+			"app.io/owner":                          "team",
+		}, true},
+		{"proxless annotation changed", map[string]string{
+			clusterutils.AnnotationServiceDeployKey:  "mock-deploy",
+			clusterutils.AnnotationServiceDomainKey:  "mock.io",
+			clusterutils.AnnotationServiceTTLSeconds: "60",
+		}, false},
+	}
+
+	for _, tc := range testCases {
+		req := helper_newAdmissionRequest(t, "other-svc", tc.annotations)
+		req.Operation = admissionv1.Update
+		req.OldObject = oldSvc
+
+		assert.Equal(t, tc.allowed, validateAdmissionRequest(c, req, false).Allowed, tc.name)
+	}
+}
+
+func TestWebhookServer_validateHandler(t *testing.T) {
+	cfg := config.NewProvider(config.NewDefaultConfig())
+	server := NewWebhookServer(helper_newController(t), cfg)
+
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: helper_newAdmissionRequest(t, "other-svc", map[string]string{
+			clusterutils.AnnotationServiceDeployKey: "unknown-deploy",
+		}),
+	}
+	body, err := json.Marshal(review)
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	server.validateHandler(rec, httptest.NewRequest(http.MethodPost, validatePath, bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	got := admissionv1.AdmissionReview{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, "AdmissionReview", got.Kind)
+	assert.Nil(t, got.Request)
+	assert.False(t, got.Response.Allowed)
+
+	// invalid body
+	rec = httptest.NewRecorder()
+	server.validateHandler(rec, httptest.NewRequest(http.MethodPost, validatePath, bytes.NewReader([]byte("{"))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
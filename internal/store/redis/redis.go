@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"kube-proxless/internal/logger"
+	"kube-proxless/internal/store"
+	"time"
+)
+
+// only override the value if it is more recent than the one already stored
+// this way, a replica with an old `lastUsed` cannot move it backward
+var setIfNewerScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]))
+if current == nil or current < tonumber(ARGV[1]) then
+	redis.call("SET", KEYS[1], ARGV[1], "EX", ARGV[2])
+	return 1
+end
+redis.call("EXPIRE", KEYS[1], ARGV[2])
+return 0
+`)
+
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisStore(redisURL string, ttlSeconds int) store.Interface {
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisURL,
+		Password: "", // no password set - the data there don't need to be protected
+		DB:       0,  // use default DB
+	})
+
+	_, err := client.Ping().Result()
+	if err != nil {
+		// we don't return error - the proxy must still work even if the store is not working
+		// the state will just not be shared across restarts
+		logger.Errorf(err, "Cannot PING Redis - please check if further errors and fix Redis connection if needed")
+	} else {
+		logger.Infof("Proxless state store connected to Redis on %s", redisURL)
+	}
+
+	return &RedisStore{
+		client: client,
+		ttl:    time.Duration(ttlSeconds) * time.Second,
+	}
+}
+
+func (r *RedisStore) SetLastUsed(idRoute string, lastUsed time.Time) {
+	key := genLastUsedKeyName(idRoute)
+	err := setIfNewerScript.Run(r.client, []string{key}, lastUsed.Unix(), int64(r.ttl/time.Second)).Err()
+	if err != nil {
+		logger.Errorf(err, "Cannot SET key %s in Redis", key)
+	}
+}
+
+func (r *RedisStore) GetLastUsed(idRoute string) (time.Time, error) {
+	key := genLastUsedKeyName(idRoute)
+	timestampInSec, err := r.client.Get(key).Int64()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(timestampInSec, 0), nil
+}
+
+// the key expires with the lease - unpinning deletes it
+func (r *RedisStore) SetPinLease(idRoute string, until time.Time) {
+	key := genPinLeaseKeyName(idRoute)
+
+	var err error
+	if ttl := time.Until(until); ttl > 0 {
+		err = r.client.Set(key, until.Unix(), ttl).Err()
+	} else {
+		err = r.client.Del(key).Err()
+	}
+
+	if err != nil {
+		logger.Errorf(err, "Cannot SET key %s in Redis", key)
+	}
+}
+
+func (r *RedisStore) GetPinLease(idRoute string) (time.Time, error) {
+	key := genPinLeaseKeyName(idRoute)
+	timestampInSec, err := r.client.Get(key).Int64()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(timestampInSec, 0), nil
+}
+
+func genLastUsedKeyName(id string) string {
+	return fmt.Sprintf("state_last_used_%s", id)
+}
+
+func genPinLeaseKeyName(id string) string {
+	return fmt.Sprintf("state_pin_lease_%s", id)
+}
+
+func (r *RedisStore) Close() {
+	if err := r.client.Close(); err != nil {
+		logger.Errorf(err, "Could not close the Redis client")
+	}
+}
@@ -0,0 +1,16 @@
+package store
+
+import (
+	"time"
+)
+
+// The store persists the state of the routes so that it survives proxless restarts
+// and replicas that missed pubsub messages can catch up
+type Interface interface {
+	SetLastUsed(idRoute string, lastUsed time.Time)
+	GetLastUsed(idRoute string) (time.Time, error)
+	SetPinLease(idRoute string, until time.Time)
+	GetPinLease(idRoute string) (time.Time, error)
+	// close the connection - called once when proxless shuts down
+	Close()
+}